@@ -3,13 +3,15 @@ package accesstoken
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"otc-auth/common"
-	"otc-auth/common/endpoints"
 	"otc-auth/config"
+	"otc-auth/iam"
 
 	"github.com/golang/glog"
 	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
@@ -18,9 +20,9 @@ import (
 	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/tokens"
 )
 
-func CreateAccessToken(tokenDescription string, printAkSk bool) {
+func CreateAccessToken(tokenDescription string, selector UserSelector, output OutputOptions) {
 	glog.V(common.InfoLogLevel).Infof("info: creating access token file with GTC...\n")
-	resp, err := getAccessTokenFromServiceProvider(tokenDescription)
+	resp, user, err := getAccessTokenFromServiceProvider(tokenDescription, selector)
 	if err != nil {
 		// A 404 error is thrown when trying to create a permanent AK/SK when logged in with OIDC or SAML
 		var notFound golangsdk.ErrDefault404
@@ -32,75 +34,99 @@ func CreateAccessToken(tokenDescription string, printAkSk bool) {
 		}
 		common.ThrowError(err)
 	}
-	makeAccessFile(resp, nil, printAkSk)
+	makeAccessFile(resp, user, output)
 }
 
-func makeAccessFile(resp *credentials.Credential, tempResp *credentials.TemporaryCredential, printAkSk bool) {
-	if resp == nil && tempResp == nil {
-		common.ThrowError(errors.New("fatal: no temporary or permanent access keys to write"))
-	}
-	var accessKeyFileContent string
-	if resp != nil {
-		accessKeyFileContent = fmt.Sprintf(
-			"export OS_ACCESS_KEY=%s\n"+
-				"export AWS_ACCESS_KEY_ID=%s\n"+
-				"export OS_SECRET_KEY=%s\n"+
-				"export AWS_SECRET_ACCESS_KEY=%s",
-			resp.AccessKey,
-			resp.AccessKey,
-			resp.SecretKey,
-			resp.SecretKey)
-	} else {
-		accessKeyFileContent = fmt.Sprintf(
-			"export OS_ACCESS_KEY=%s\n"+
-				"export AWS_ACCESS_KEY_ID=%s\n"+
-				"export OS_SECRET_KEY=%s\n"+
-				"export AWS_SECRET_ACCESS_KEY=%s\n"+
-				"export AWS_SESSION_TOKEN=%s",
-			tempResp.AccessKey,
-			tempResp.AccessKey,
-			tempResp.SecretKey,
-			tempResp.SecretKey,
-			tempResp.SecurityToken)
-	}
-
-	if printAkSk {
-		_, err := os.Stdout.Write(append([]byte(accessKeyFileContent), '\n'))
-		if err != nil {
-			common.ThrowError(err)
-		}
-	} else {
-		common.WriteStringToFile("./ak-sk-env.sh", accessKeyFileContent)
-		glog.V(common.InfoLogLevel).Info("info: access token file created successfully")
-		glog.V(common.InfoLogLevel).Info("info: please source the ak-sk-env.sh file in the current directory manually")
+func makeAccessFile(resp *credentials.Credential, user *tokens.User, output OutputOptions) {
+	if resp == nil {
+		common.ThrowError(errors.New("fatal: no permanent access keys to write"))
 	}
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		common.ThrowError(err)
+	}
+	credential := Credential{
+		AccessKey:  resp.AccessKey,
+		SecretKey:  resp.SecretKey,
+		DomainName: activeCloud.Domain.Name,
+		Region:     activeCloud.Region,
+		UserName:   user.Name,
+		UserID:     user.ID,
+	}
+
+	if err = WriteCredential(credential, output, os.Stdout); err != nil {
+		common.ThrowError(err)
+	}
+	// stdout may hold the printed key, the label must not end up in it
+	fmt.Fprintf(os.Stderr, "created access key %s for user %s\n", resp.AccessKey, userLabel(user))
 }
 
-func CreateTemporaryAccessToken(durationSeconds int, printAkSk bool) error {
+// CreateTemporaryAccessToken writes a temporary AK/SK, a cached one is reused
+// while it stays valid for a few more minutes.
+func CreateTemporaryAccessToken(params TemporaryParams, output OutputOptions) error {
 	glog.V(common.InfoLogLevel).Info("info: creating temporary access token file with GTC...")
-	resp, err := getTempAccessTokenFromServiceProvider(durationSeconds)
+	credential, err := getTemporaryCredential(params, temporaryCredentialMinValidity)
 	if err != nil {
 		return err
 	}
+	return WriteCredential(*credential, output, os.Stdout)
+}
 
-	makeAccessFile(nil, resp, printAkSk)
-	return nil
+// getTemporaryCredential returns the cached temporary AK/SK of the login,
+// project and lifetime, or a new one if it expires within renewBefore.
+func getTemporaryCredential(params TemporaryParams, renewBefore time.Duration) (*Credential, error) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := temporaryCredentialCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	key := temporaryCredentialCacheKey{
+		DomainName:      activeCloud.Domain.Name,
+		Username:        activeCloud.Username,
+		ProjectName:     params.ProjectName,
+		DurationSeconds: params.DurationSeconds,
+		Variant:         temporaryCredentialVariant(params),
+	}
+	return loadOrFetchTemporaryCredential(key.path(cacheDir), time.Now(), renewBefore, params.ForceNew,
+		func() (*Credential, error) {
+			resp, errFetch := getTempAccessTokenFromServiceProvider(params)
+			if errFetch != nil {
+				return nil, errFetch
+			}
+			return &Credential{
+				AccessKey:     resp.AccessKey,
+				SecretKey:     resp.SecretKey,
+				SecurityToken: resp.SecurityToken,
+				ExpiresAt:     resp.ExpiresAt,
+				DomainName:    activeCloud.Domain.Name,
+				Region:        activeCloud.Region,
+			}, nil
+		})
 }
 
-func ListAccessToken() ([]credentials.Credential, error) {
+// ListAccessToken returns the permanent keys of the selected user and the
+// user itself.
+func ListAccessToken(selector UserSelector) ([]credentials.Credential, *tokens.User, error) {
 	client, err := getIdentityServiceClient()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	activeCloud, err := config.GetActiveCloudConfig()
 	if err != nil {
 		common.ThrowError(err)
 	}
-	user, err := tokens.Get(client, activeCloud.UnscopedToken.Secret).ExtractUser()
+	user, err := resolveUser(client, activeCloud, selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	accessTokens, err := listCredentials(client, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't get user: %w", err)
+		return nil, nil, err
 	}
-	return listCredentials(client, user.ID)
+	return accessTokens, user, nil
 }
 
 // Upstream regression: the new URL builder passes &opts (pointer to the
@@ -128,14 +154,47 @@ func listCredentials(client *golangsdk.ServiceClient, userID string) ([]credenti
 	return body.Credentials, nil
 }
 
-func getTempAccessTokenFromServiceProvider(durationSeconds int) (*credentials.TemporaryCredential, error) {
-	client, err := getIdentityServiceClient()
+// getTempAccessTokenFromServiceProvider creates a temporary AK/SK with the
+// permissions of the unscoped token, or of the project's scoped token, or of
+// the agency. A session policy restricts them further.
+func getTempAccessTokenFromServiceProvider(params TemporaryParams) (*credentials.TemporaryCredential, error) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return nil, err
+	}
+	if activeCloud.AccessKey != nil {
+		return nil, errors.New("fatal: temporary access keys are issued for tokens only.\n\n" +
+			"Please log in with a password, SAML or OIDC to create them")
+	}
+	var project *config.Project
+	if params.ProjectName != "" {
+		if _, err = iam.ScopedToken(params.ProjectName); err != nil {
+			return nil, err
+		}
+		// the scoped token may have been renewed
+		activeCloud, err = config.GetActiveCloudConfig()
+		if err != nil {
+			return nil, err
+		}
+		project, err = activeCloud.Projects.GetProjectByName(params.ProjectName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	client, err := getIdentityServiceClientFor(activeCloud, project)
 	if err != nil {
 		return nil, err
 	}
-	tempCreds, err := credentials.CreateTemporary(client, credentials.CreateTemporaryOpts{
-		Methods:  []string{"token"},
-		Duration: durationSeconds,
+	agencyDomainName := params.AgencyDomainName
+	if agencyDomainName == "" {
+		agencyDomainName = activeCloud.Domain.Name
+	}
+	tempCreds, err := credentials.CreateTemporary(client, temporaryCredentialOpts{
+		Method:           params.Method,
+		DurationSeconds:  params.DurationSeconds,
+		AgencyName:       params.AgencyName,
+		AgencyDomainName: agencyDomainName,
+		Policy:           params.Policy,
 	}).Extract()
 	if err != nil {
 		return nil, err
@@ -144,18 +203,19 @@ func getTempAccessTokenFromServiceProvider(durationSeconds int) (*credentials.Te
 	return tempCreds, err
 }
 
-func getAccessTokenFromServiceProvider(tokenDescription string) (*credentials.Credential, error) {
+func getAccessTokenFromServiceProvider(tokenDescription string, selector UserSelector,
+) (*credentials.Credential, *tokens.User, error) {
 	client, err := getIdentityServiceClient()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	activeCloud, err := config.GetActiveCloudConfig()
 	if err != nil {
 		common.ThrowError(err)
 	}
-	user, err := tokens.Get(client, activeCloud.UnscopedToken.Secret).ExtractUser()
+	user, err := resolveUser(client, activeCloud, selector)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't get user: %w", err)
+		return nil, nil, err
 	}
 	credResp := credentials.Create(client, credentials.CreateOpts{
 		UserID:      user.ID,
@@ -165,7 +225,7 @@ func getAccessTokenFromServiceProvider(tokenDescription string) (*credentials.Cr
 	if err != nil {
 		credential, err = handlePotentialLimitError(err, user, client, tokenDescription)
 	}
-	return credential, err
+	return credential, user, err
 }
 
 func handlePotentialLimitError(err error,
@@ -175,7 +235,7 @@ func handlePotentialLimitError(err error,
 ) (*credentials.Credential, error) {
 	var badRequest golangsdk.ErrDefault400
 	if errors.As(err, &badRequest) {
-		accessTokens, listErr := ListAccessToken()
+		accessTokens, listErr := listCredentials(client, user.ID)
 		if listErr != nil {
 			return nil, listErr
 		}
@@ -196,7 +256,7 @@ func conditionallyReplaceAccessTokens(user *tokens.User, client *golangsdk.Servi
 ) (*credentials.Credential, error) {
 	changed := false
 	for _, token := range accessTokens {
-		if token.Description == "Token by otc-auth" {
+		if token.Description == DefaultTokenDescription {
 			err := DeleteAccessToken(token.AccessKey)
 			if err != nil {
 				return nil, err
@@ -223,16 +283,65 @@ func DeleteAccessToken(token string) error {
 	return credentials.Delete(client, token).ExtractErr()
 }
 
+// DeleteUserAccessToken deletes a permanent key and writes whose it was. A
+// selected user has to own the key, without one any key the login may manage
+// is deleted.
+func DeleteUserAccessToken(token string, selector UserSelector, out io.Writer) error {
+	client, err := getIdentityServiceClient()
+	if err != nil {
+		return err
+	}
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return err
+	}
+	user, err := resolveUser(client, activeCloud, selector)
+	if err != nil {
+		return err
+	}
+	credential, err := credentials.Get(client, token).Extract()
+	if err != nil {
+		return fmt.Errorf("fatal: couldn't find access key %s\ntrace: %w", token, err)
+	}
+	if credential.UserID != user.ID {
+		if !selector.IsEmpty() {
+			return fmt.Errorf("fatal: access key %s doesn't belong to user %s", token, userLabel(user))
+		}
+		user = &tokens.User{ID: credential.UserID}
+	}
+	if err = credentials.Delete(client, token).ExtractErr(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "deleted access key %s of user %s\n", token, userLabel(user))
+	return nil
+}
+
+// getCurrentUser returns the user the active cloud is logged in as. Clouds
+// logged in to with an access key have no token to ask, the key knows its user.
+func getCurrentUser(client *golangsdk.ServiceClient, activeCloud *config.Cloud) (*tokens.User, error) {
+	if activeCloud.AccessKey != nil {
+		credential, err := credentials.Get(client, activeCloud.AccessKey.AccessKey).Extract()
+		if err != nil {
+			return nil, err
+		}
+		return &tokens.User{ID: credential.UserID, Name: activeCloud.Username}, nil
+	}
+	return tokens.Get(client, activeCloud.UnscopedToken.Secret).ExtractUser()
+}
+
 func getIdentityServiceClient() (*golangsdk.ServiceClient, error) {
 	activeCloud, err := config.GetActiveCloudConfig()
 	if err != nil {
 		common.ThrowError(err)
 	}
-	provider, err := openstack.AuthenticatedClient(golangsdk.AuthOptions{
-		IdentityEndpoint: endpoints.BaseURLIam(activeCloud.Region),
-		DomainID:         activeCloud.Domain.ID,
-		TokenID:          activeCloud.UnscopedToken.Secret,
-	})
+	return getIdentityServiceClientFor(activeCloud, nil)
+}
+
+// getIdentityServiceClientFor authenticates with the project's scoped token,
+// or the unscoped one if project is nil.
+func getIdentityServiceClientFor(activeCloud *config.Cloud, project *config.Project,
+) (*golangsdk.ServiceClient, error) {
+	provider, err := openstack.AuthenticatedClient(activeCloud.AuthOptions(project))
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get provider: %w", err)
 	}
@@ -0,0 +1,198 @@
+package accesstoken
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"otc-auth/common"
+
+	"github.com/golang/glog"
+)
+
+const (
+	credentialCacheFileAccess = 0o600
+	credentialCacheDirAccess  = 0o700
+	// temporaryCredentialMinValidity is how long a cached temporary AK/SK has
+	// to stay valid to be handed out by temp-access-token create again
+	temporaryCredentialMinValidity = 5 * time.Minute
+	// unscopedCacheName stands in for the project of AK/SKs created with the
+	// unscoped token
+	unscopedCacheName = "_unscoped"
+)
+
+// TemporaryParams configures how a temporary AK/SK is obtained.
+type TemporaryParams struct {
+	DurationSeconds int
+	// ProjectName scopes the AK/SK to a project, it is valid for the whole
+	// domain if empty
+	ProjectName string
+	// ForceNew creates a new AK/SK even if a cached one is still valid
+	ForceNew bool
+	// Method is one of TemporaryMethods, token if empty
+	Method     string
+	AgencyName string
+	// AgencyDomainName is the domain which created the agency, the logged in
+	// domain if empty
+	AgencyDomainName string
+	// Policy is a session policy restricting the AK/SK further, see
+	// ReadSessionPolicy
+	Policy []byte
+}
+
+// temporaryCredentialCacheKey identifies the cached AK/SK. AK/SKs are only
+// reused for the same login, project, lifetime and variant, see
+// temporaryCredentialVariant.
+type temporaryCredentialCacheKey struct {
+	DomainName      string
+	Username        string
+	ProjectName     string
+	DurationSeconds int
+	Variant         string
+}
+
+// temporaryCredentialCacheDir is where otc-auth caches temporary AK/SKs, in
+// a directory per domain.
+func temporaryCredentialCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("fatal: couldn't find the cache directory\ntrace: %w", err)
+	}
+	return filepath.Join(cacheDir, "otc-auth", "temp-ak-sk"), nil
+}
+
+func (key temporaryCredentialCacheKey) path(cacheDir string) string {
+	projectName := key.ProjectName
+	if projectName == "" {
+		projectName = unscopedCacheName
+	}
+	name := cachePathSegment(projectName) + "-" + strconv.Itoa(key.DurationSeconds) + "s"
+	if key.Variant != "" {
+		name += "-" + key.Variant
+	}
+	return filepath.Join(cacheDir, cachePathSegment(key.DomainName), cachePathSegment(key.Username), name+".json")
+}
+
+func cachePathSegment(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(name)
+}
+
+// loadOrFetchTemporaryCredential returns the cached AK/SK unless it expires
+// within renewBefore or forceNew is set, otherwise it fetches and caches a new
+// one.
+func loadOrFetchTemporaryCredential(cachePath string, now time.Time, renewBefore time.Duration, forceNew bool,
+	fetch func() (*Credential, error),
+) (*Credential, error) {
+	if !forceNew {
+		cached, err := readCredentialCache(cachePath)
+		if err != nil {
+			glog.V(common.InfoLogLevel).Infof("info: ignoring access key cache: %s", err)
+		} else if cached != nil {
+			expiresAt, errExpiry := credentialExpiry(*cached)
+			if errExpiry == nil && now.Add(renewBefore).Before(expiresAt) {
+				glog.V(common.InfoLogLevel).Infof("info: reusing the cached access key valid until %s (UTC)",
+					expiresAt.UTC().Format(common.PrintTimeFormat))
+				return cached, nil
+			}
+		}
+	}
+
+	credential, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if err = writeCredentialCache(cachePath, credential); err != nil {
+		// the caller still gets its AK/SK, it is just created again next time
+		glog.Warningf("warning: couldn't cache access key: %s", err)
+	}
+	return credential, nil
+}
+
+func readCredentialCache(cachePath string) (*Credential, error) {
+	content, err := os.ReadFile(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil //nolint:nilnil // a missing cache is not an error
+	}
+	if err != nil {
+		return nil, err
+	}
+	var credential Credential
+	if err = json.Unmarshal(content, &credential); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %w", cachePath, err)
+	}
+	return &credential, nil
+}
+
+func writeCredentialCache(cachePath string, credential *Credential) error {
+	content, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(cachePath), credentialCacheDirAccess); err != nil {
+		return err
+	}
+	// write and rename, so tools asking concurrently never read half a file
+	tmp, err := os.CreateTemp(filepath.Dir(cachePath), filepath.Base(cachePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = tmp.Chmod(credentialCacheFileAccess); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath)
+}
+
+// PurgeTemporaryCredentialCache removes the cached temporary AK/SKs of the
+// domain, or of all domains if it is empty, and returns how many there were.
+// The AK/SKs stay valid on the OTC until they expire.
+func PurgeTemporaryCredentialCache(domainName string) (int, error) {
+	cacheDir, err := temporaryCredentialCacheDir()
+	if err != nil {
+		return 0, err
+	}
+	if domainName != "" {
+		cacheDir = filepath.Join(cacheDir, cachePathSegment(domainName))
+	}
+	return purgeCredentialCacheDir(cacheDir)
+}
+
+func purgeCredentialCacheDir(cacheDir string) (int, error) {
+	purged := 0
+	err := filepath.WalkDir(cacheDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && filepath.Ext(path) == ".json" {
+			purged++
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("fatal: couldn't read the access key cache %s\ntrace: %w", cacheDir, err)
+	}
+	if err = os.RemoveAll(cacheDir); err != nil {
+		return 0, fmt.Errorf("fatal: couldn't remove the access key cache %s\ntrace: %w", cacheDir, err)
+	}
+	return purged, nil
+}
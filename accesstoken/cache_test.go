@@ -0,0 +1,146 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadOrFetchTemporaryCredential(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	credentialUntil := func(accessKey string, expiresAt time.Time) *Credential {
+		return &Credential{
+			AccessKey: accessKey, SecretKey: "SK", SecurityToken: "TOKEN",
+			ExpiresAt: expiresAt.Format("2006-01-02T15:04:05.000000Z"),
+		}
+	}
+	tests := []struct {
+		name        string
+		cached      *Credential
+		corrupt     bool
+		renewBefore time.Duration
+		forceNew    bool
+		wantKey     string
+	}{
+		{name: "no cache", wantKey: "NEW"},
+		{name: "valid cache", cached: credentialUntil("CACHED", now.Add(time.Hour)), wantKey: "CACHED"},
+		{
+			name:        "cache expiring within the margin",
+			cached:      credentialUntil("CACHED", now.Add(10*time.Minute)),
+			renewBefore: temporaryCredentialRenewBefore,
+			wantKey:     "NEW",
+		},
+		{
+			name:        "cache valid for the smaller margin",
+			cached:      credentialUntil("CACHED", now.Add(10*time.Minute)),
+			renewBefore: temporaryCredentialMinValidity,
+			wantKey:     "CACHED",
+		},
+		{
+			name:        "fresh credential of the shortest lifetime",
+			cached:      credentialUntil("CACHED", now.Add(900*time.Second-time.Minute)),
+			renewBefore: temporaryCredentialRenewWindow(900),
+			wantKey:     "CACHED",
+		},
+		{name: "forced", cached: credentialUntil("CACHED", now.Add(time.Hour)), forceNew: true, wantKey: "NEW"},
+		{name: "corrupt cache", corrupt: true, wantKey: "NEW"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cachePath := filepath.Join(t.TempDir(), "temp-ak-sk", "d", "me", "_unscoped-900s.json")
+			if tt.cached != nil {
+				if err := writeCredentialCache(cachePath, tt.cached); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.corrupt {
+				if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(cachePath, []byte("{"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fetched := false
+			got, err := loadOrFetchTemporaryCredential(cachePath, now, tt.renewBefore, tt.forceNew,
+				func() (*Credential, error) {
+					fetched = true
+					return credentialUntil("NEW", now.Add(time.Hour)), nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AccessKey != tt.wantKey || fetched != (tt.wantKey == "NEW") {
+				t.Errorf("got %s, fetched %v, want %s", got.AccessKey, fetched, tt.wantKey)
+			}
+
+			cached, err := readCredentialCache(cachePath)
+			if err != nil || cached == nil || cached.AccessKey != tt.wantKey {
+				t.Errorf("cache holds %+v (%v), want %s", cached, err, tt.wantKey)
+			}
+			info, err := os.Stat(cachePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("cache mode = %v, want 0600", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestTemporaryCredentialCacheKeyPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		key  temporaryCredentialCacheKey
+		want string
+	}{
+		{
+			key:  temporaryCredentialCacheKey{DomainName: "d", Username: "me", DurationSeconds: 900},
+			want: filepath.Join("cache", "d", "me", "_unscoped-900s.json"),
+		},
+		{
+			key: temporaryCredentialCacheKey{
+				DomainName: "d", Username: "idp/me", ProjectName: "eu-de_p", DurationSeconds: 3600,
+			},
+			want: filepath.Join("cache", "d", "idp_me", "eu-de_p-3600s.json"),
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.key.path("cache"); got != tt.want {
+			t.Errorf("path() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestPurgeCredentialCacheDir(t *testing.T) {
+	t.Parallel()
+	cacheDir := filepath.Join(t.TempDir(), "temp-ak-sk")
+	credential := &Credential{AccessKey: "AK"}
+	for _, key := range []temporaryCredentialCacheKey{
+		{DomainName: "d", Username: "me", DurationSeconds: 900},
+		{DomainName: "d", Username: "me", ProjectName: "p", DurationSeconds: 900},
+		{DomainName: "other", Username: "me", DurationSeconds: 900},
+	} {
+		if err := writeCredentialCache(key.path(cacheDir), credential); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purged, err := purgeCredentialCacheDir(filepath.Join(cacheDir, "d"))
+	if err != nil || purged != 2 {
+		t.Fatalf("purgeCredentialCacheDir() = %d, %v, want 2", purged, err)
+	}
+	if _, err = os.Stat(filepath.Join(cacheDir, "other")); err != nil {
+		t.Errorf("the cache of the other domain is gone: %v", err)
+	}
+	purged, err = purgeCredentialCacheDir(filepath.Join(cacheDir, "d"))
+	if err != nil || purged != 0 {
+		t.Errorf("purgeCredentialCacheDir() of a missing cache = %d, %v, want 0", purged, err)
+	}
+}
@@ -0,0 +1,94 @@
+package accesstoken
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// credentialProcessVersion is the only version of the credential_process
+	// output the AWS SDKs know
+	credentialProcessVersion = 1
+	// temporaryCredentialRenewBefore is how long before expiry a cached
+	// temporary AK/SK is replaced. The AWS SDKs ask again 15 minutes before
+	// the expiration, a credential closer to it would be requested every time.
+	temporaryCredentialRenewBefore = 15 * time.Minute
+	// temporaryCredentialRenewFraction of shorter lifetimes is the renew
+	// window instead
+	temporaryCredentialRenewFraction = 4
+)
+
+// credentialProcessOutput is the JSON the AWS SDKs expect from a
+// credential_process, see
+// https://docs.aws.amazon.com/sdkref/latest/guide/feature-process-credentials.html
+type credentialProcessOutput struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+// PrintCredentialProcess writes a temporary AK/SK for the credential_process
+// setting of the AWS CLI and SDKs. The AK/SK is cached and only created again
+// shortly before it expires.
+func PrintCredentialProcess(params TemporaryParams, out io.Writer) error {
+	credential, err := getTemporaryCredential(params, temporaryCredentialRenewWindow(params.DurationSeconds))
+	if err != nil {
+		return err
+	}
+	output, err := newCredentialProcessOutput(*credential)
+	if err != nil {
+		return err
+	}
+	// the SDKs read the credential from stdout, everything else goes to stderr
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("fatal: couldn't marshal credential\ntrace: %w", err)
+	}
+	if _, err = fmt.Fprintln(out, string(encoded)); err != nil {
+		return fmt.Errorf("fatal: couldn't write credential\ntrace: %w", err)
+	}
+	return nil
+}
+
+// temporaryCredentialRenewWindow is temporaryCredentialRenewBefore, or a
+// quarter of lifetimes shorter than an hour. An AK/SK of 15 minutes would be
+// inside the window as soon as it's issued and never be reused.
+func temporaryCredentialRenewWindow(durationSeconds int) time.Duration {
+	window := time.Duration(durationSeconds) * time.Second / temporaryCredentialRenewFraction
+	if window > 0 && window < temporaryCredentialRenewBefore {
+		return window
+	}
+	return temporaryCredentialRenewBefore
+}
+
+func newCredentialProcessOutput(credential Credential) (credentialProcessOutput, error) {
+	output := credentialProcessOutput{
+		Version:         credentialProcessVersion,
+		AccessKeyID:     credential.AccessKey,
+		SecretAccessKey: credential.SecretKey,
+		SessionToken:    credential.SecurityToken,
+	}
+	if credential.ExpiresAt != "" {
+		expiresAt, err := credentialExpiry(credential)
+		if err != nil {
+			return output, err
+		}
+		output.Expiration = expiresAt.UTC().Format(time.RFC3339)
+	}
+	return output, nil
+}
+
+// credentialExpiry parses the expiry of a temporary AK/SK, the IAM returns it
+// with microseconds.
+func credentialExpiry(credential Credential) (time.Time, error) {
+	expiresAt, err := time.Parse(time.RFC3339Nano, credential.ExpiresAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fatal: couldn't parse expiry %s of the access key\ntrace: %w",
+			credential.ExpiresAt, err)
+	}
+	return expiresAt, nil
+}
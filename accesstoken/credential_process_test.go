@@ -0,0 +1,50 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewCredentialProcessOutput(t *testing.T) {
+	t.Parallel()
+	output, err := newCredentialProcessOutput(Credential{
+		AccessKey: "AK", SecretKey: "SK", SecurityToken: "TOKEN", ExpiresAt: "2025-01-01T12:00:00.123456Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Version":1,"AccessKeyId":"AK","SecretAccessKey":"SK","SessionToken":"TOKEN",` +
+		`"Expiration":"2025-01-01T12:00:00Z"}`
+	if string(got) != want {
+		t.Errorf("output = %s, want %s", got, want)
+	}
+
+	if _, err = newCredentialProcessOutput(Credential{AccessKey: "AK", ExpiresAt: "tomorrow"}); err == nil {
+		t.Error("newCredentialProcessOutput() with an invalid expiry succeeded")
+	}
+}
+
+func TestTemporaryCredentialRenewWindow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		durationSeconds int
+		want            time.Duration
+	}{
+		{durationSeconds: 900, want: 225 * time.Second},
+		{durationSeconds: 1800, want: 450 * time.Second},
+		{durationSeconds: 3600, want: temporaryCredentialRenewBefore},
+		{durationSeconds: 86400, want: temporaryCredentialRenewBefore},
+		{durationSeconds: 0, want: temporaryCredentialRenewBefore},
+	}
+	for _, tt := range tests {
+		if got := temporaryCredentialRenewWindow(tt.durationSeconds); got != tt.want {
+			t.Errorf("temporaryCredentialRenewWindow(%d) = %v, want %v", tt.durationSeconds, got, tt.want)
+		}
+	}
+}
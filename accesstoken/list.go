@@ -0,0 +1,204 @@
+package accesstoken
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"otc-auth/config"
+
+	"github.com/golang/glog"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/credentials"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of access-token list.
+const (
+	ListFormatTable = "table"
+	ListFormatJSON  = "json"
+	ListFormatYAML  = "yaml"
+)
+
+// ListFormats returns all formats WriteAccessTokenInfos understands.
+func ListFormats() []string {
+	return []string{ListFormatTable, ListFormatJSON, ListFormatYAML}
+}
+
+// Status of a permanent key.
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+// Statuses returns all statuses a permanent key can have.
+func Statuses() []string {
+	return []string{StatusActive, StatusInactive}
+}
+
+// AccessTokenInfo describes a permanent key, its secret is never returned
+// after the creation.
+type AccessTokenInfo struct {
+	AccessKey        string     `json:"accessKey"            yaml:"accessKey"`
+	Description      string     `json:"description"          yaml:"description"`
+	Status           string     `json:"status"               yaml:"status"`
+	CreatedAt        *time.Time `json:"createdAt,omitempty"  yaml:"createdAt,omitempty"`
+	LastUsedAt       *time.Time `json:"lastUsedAt,omitempty" yaml:"lastUsedAt,omitempty"`
+	UserID           string     `json:"userId"               yaml:"userId"`
+	UserName         string     `json:"userName,omitempty"   yaml:"userName,omitempty"`
+	CreatedByOtcAuth bool       `json:"createdByOtcAuth"     yaml:"createdByOtcAuth"`
+}
+
+// ListFilter selects the keys access-token list shows, empty fields match
+// every key.
+type ListFilter struct {
+	Status string
+	// Description is a shell pattern, e.g. 'ci-*'
+	Description string
+	// UnusedFor matches keys not used within this duration, or never
+	UnusedFor time.Duration
+}
+
+// ListAccessTokenInfos returns the permanent keys of the selected user
+// matching the filter, the oldest first.
+func ListAccessTokenInfos(selector UserSelector, filter ListFilter) ([]AccessTokenInfo, error) {
+	accessTokens, user, err := ListAccessToken(selector)
+	if err != nil {
+		return nil, err
+	}
+	return filterAccessTokenInfos(accessTokenInfos(accessTokens, user.Name), filter, time.Now())
+}
+
+func accessTokenInfos(accessTokens []credentials.Credential, userName string) []AccessTokenInfo {
+	infos := make([]AccessTokenInfo, 0, len(accessTokens))
+	for _, accessToken := range accessTokens {
+		infos = append(infos, AccessTokenInfo{
+			AccessKey:        accessToken.AccessKey,
+			Description:      accessToken.Description,
+			Status:           string(accessToken.Status),
+			CreatedAt:        parseCredentialTime(accessToken.CreateTime),
+			LastUsedAt:       parseCredentialTime(accessToken.LastUseTime),
+			UserID:           accessToken.UserID,
+			UserName:         userName,
+			CreatedByOtcAuth: accessToken.Description == DefaultTokenDescription,
+		})
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].CreatedAt == nil || infos[j].CreatedAt == nil {
+			return infos[j].CreatedAt == nil && infos[i].CreatedAt != nil
+		}
+		return infos[i].CreatedAt.Before(*infos[j].CreatedAt)
+	})
+	return infos
+}
+
+// parseCredentialTime parses the times of the credentials API, e.g.
+// 2020-01-08T02:26:19.000000Z. Keys never used have no last use time.
+func parseCredentialTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		glog.Warningf("warning: couldn't parse time %s of an access key: %s", value, err)
+		return nil
+	}
+	parsed = parsed.UTC()
+	return &parsed
+}
+
+func filterAccessTokenInfos(infos []AccessTokenInfo, filter ListFilter, now time.Time) ([]AccessTokenInfo, error) {
+	filtered := make([]AccessTokenInfo, 0, len(infos))
+	for _, info := range infos {
+		if filter.Status != "" && info.Status != filter.Status {
+			continue
+		}
+		if filter.Description != "" {
+			matches, err := path.Match(filter.Description, info.Description)
+			if err != nil {
+				return nil, fmt.Errorf("fatal: invalid description pattern %s\ntrace: %w", filter.Description, err)
+			}
+			if !matches {
+				continue
+			}
+		}
+		if filter.UnusedFor > 0 && info.LastUsedAt != nil && info.LastUsedAt.After(now.Add(-filter.UnusedFor)) {
+			continue
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered, nil
+}
+
+// WriteAccessTokenInfos writes the keys in one of ListFormats.
+func WriteAccessTokenInfos(w io.Writer, infos []AccessTokenInfo, format string) error {
+	switch format {
+	case ListFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	case ListFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2) //nolint:mnd // the usual yaml indentation
+		if err := encoder.Encode(infos); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case ListFormatTable:
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // padding between columns
+		fmt.Fprintln(table, "USER\tACCESS KEY\tSTATUS\tCREATED\tLAST USED\tDESCRIPTION")
+		for _, info := range infos {
+			user := info.UserName
+			if user == "" {
+				user = info.UserID
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", user, info.AccessKey, info.Status,
+				timeText(info.CreatedAt, "-"), timeText(info.LastUsedAt, "never"), info.Description)
+		}
+		return table.Flush()
+	default:
+		return fmt.Errorf("fatal: unknown output format %s, use one of %s",
+			format, strings.Join(ListFormats(), ", "))
+	}
+}
+
+func timeText(value *time.Time, missing string) string {
+	if value == nil {
+		return missing
+	}
+	return value.Format(time.DateTime)
+}
+
+// SetAccessTokenStatus activates or deactivates a permanent key. The OTC
+// rejects requests signed with an inactive key, but it can be activated
+// again.
+func SetAccessTokenStatus(accessKey string, status string) error {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return err
+	}
+	if status == StatusInactive && activeCloud.AccessKey != nil && activeCloud.AccessKey.AccessKey == accessKey {
+		glog.Warningf("warning: otc-auth is logged in with %s, log in again with another method after "+
+			"deactivating it", accessKey)
+	}
+	return updateAccessToken(accessKey, credentials.UpdateOpts{Status: status})
+}
+
+// SetAccessTokenDescription changes the description of a permanent key.
+func SetAccessTokenDescription(accessKey string, description string) error {
+	return updateAccessToken(accessKey, credentials.UpdateOpts{Description: description})
+}
+
+// updateAccessToken changes the status or description of a permanent key,
+// empty fields are left as they are.
+func updateAccessToken(accessKey string, opts credentials.UpdateOpts) error {
+	client, err := getIdentityServiceClient()
+	if err != nil {
+		return err
+	}
+	return credentials.Update(client, accessKey, opts).Err
+}
@@ -0,0 +1,98 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/credentials"
+)
+
+func TestFilterAccessTokenInfos(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	infos := accessTokenInfos([]credentials.Credential{
+		{
+			AccessKey: "RECENT", Description: "ci-deploy", Status: "active",
+			CreateTime: "2025-05-01T00:00:00.000000Z", LastUseTime: "2025-05-31T00:00:00.000000Z",
+		},
+		{
+			AccessKey: "STALE", Description: DefaultTokenDescription, Status: "inactive",
+			CreateTime: "2024-01-01T00:00:00.000000Z", LastUseTime: "2024-02-01T00:00:00.000000Z",
+		},
+		{AccessKey: "UNUSED", Description: "ci-test", Status: "active", CreateTime: "2024-06-01T00:00:00.000000Z"},
+	}, "ci-bot")
+	tests := []struct {
+		name    string
+		filter  ListFilter
+		want    []string
+		wantErr bool
+	}{
+		{name: "no filter, oldest first", want: []string{"STALE", "UNUSED", "RECENT"}},
+		{name: "status", filter: ListFilter{Status: StatusActive}, want: []string{"UNUSED", "RECENT"}},
+		{name: "description", filter: ListFilter{Description: "ci-*"}, want: []string{"UNUSED", "RECENT"}},
+		{name: "unused for", filter: ListFilter{UnusedFor: 30 * 24 * time.Hour}, want: []string{"STALE", "UNUSED"}},
+		{name: "invalid pattern", filter: ListFilter{Description: "["}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := filterAccessTokenInfos(infos, tt.filter, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("filterAccessTokenInfos() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, info := range got {
+				keys = append(keys, info.AccessKey)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("filterAccessTokenInfos() = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+	if !infos[0].CreatedByOtcAuth || infos[1].CreatedByOtcAuth {
+		t.Error("only the key with the default description should be marked as created by otc-auth")
+	}
+}
+
+func TestWriteAccessTokenInfos(t *testing.T) {
+	t.Parallel()
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	infos := []AccessTokenInfo{
+		{AccessKey: "AK", Description: "ci", Status: StatusActive, CreatedAt: &created, UserID: "u1", UserName: "ci-bot"},
+		{AccessKey: "AK2", Description: "deploy", Status: StatusInactive, UserID: "u2"},
+	}
+
+	var table bytes.Buffer
+	if err := WriteAccessTokenInfos(&table, infos, ListFormatTable); err != nil {
+		t.Fatal(err)
+	}
+	want := "USER    ACCESS KEY  STATUS    CREATED              LAST USED  DESCRIPTION\n" +
+		"ci-bot  AK          active    2025-01-02 03:04:05  never      ci\n" +
+		"u2      AK2         inactive  -                    never      deploy\n"
+	if table.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", table.String(), want)
+	}
+
+	var encoded bytes.Buffer
+	if err := WriteAccessTokenInfos(&encoded, infos, ListFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(encoded.String(), `"createdAt": "2025-01-02T03:04:05Z"`) ||
+		!strings.Contains(encoded.String(), `"userName": "ci-bot"`) ||
+		strings.Contains(encoded.String(), "lastUsedAt") {
+		t.Errorf("json = %s, want the creation time, the user and no last use", encoded.String())
+	}
+
+	if err := WriteAccessTokenInfos(&encoded, infos, "name"); err == nil {
+		t.Error("WriteAccessTokenInfos() with an unknown format succeeded")
+	}
+}
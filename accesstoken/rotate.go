@@ -0,0 +1,228 @@
+package accesstoken
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"otc-auth/common"
+	"otc-auth/config"
+
+	"github.com/golang/glog"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/credentials"
+)
+
+// DefaultTokenDescription is the description of the keys otc-auth creates.
+// Only keys described like this are replaced without being forced to.
+const DefaultTokenDescription = "Token by otc-auth"
+
+// RetiredTokenDescription is the description of the keys a rotation
+// deactivated. The next rotation deletes such a key to make room.
+const RetiredTokenDescription = "Retired token by otc-auth"
+
+// maxAccessTokens is how many permanent keys a user may have on the OTC.
+const maxAccessTokens = 2
+
+// What happens to the old key after a rotation.
+const (
+	RetireDelete     = "delete"
+	RetireDeactivate = "deactivate"
+)
+
+// RetireActions returns all ways to retire the old key.
+func RetireActions() []string {
+	return []string{RetireDelete, RetireDeactivate}
+}
+
+// RotateParams configures RotateAccessToken.
+type RotateParams struct {
+	// AccessKey is the key to replace, empty picks the only key created by
+	// otc-auth
+	AccessKey   string
+	Description string
+	Outputs     []OutputOptions
+	// VerifyCommand is run by the shell with the new key in the environment,
+	// the old key is only retired if it succeeds
+	VerifyCommand string
+	Retire        string
+	// Force allows replacing keys otc-auth didn't create
+	Force bool
+	// User owns the key, the logged in user if empty
+	User UserSelector
+}
+
+// RotateAccessToken replaces a permanent key by a new one. The new key is
+// written to the outputs and verified before the old key is retired, so a
+// failed rotation leaves the old key working.
+func RotateAccessToken(params RotateParams, out io.Writer) error {
+	client, err := getIdentityServiceClient()
+	if err != nil {
+		return err
+	}
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return err
+	}
+	user, err := resolveUser(client, activeCloud, params.User)
+	if err != nil {
+		return err
+	}
+	accessTokens, err := listCredentials(client, user.ID)
+	if err != nil {
+		return err
+	}
+	oldToken, retiredToken, err := selectRotatedToken(accessTokens, params.AccessKey, params.Force)
+	if err != nil {
+		return err
+	}
+	if retiredToken != nil {
+		if err = DeleteAccessToken(retiredToken.AccessKey); err != nil {
+			return fmt.Errorf("fatal: couldn't delete the retired access key %s\ntrace: %w",
+				retiredToken.AccessKey, err)
+		}
+		fmt.Fprintf(out, "deleted retired access key %s of user %s\n", retiredToken.AccessKey, userLabel(user))
+	}
+
+	newToken, err := credentials.Create(client, credentials.CreateOpts{
+		UserID:      user.ID,
+		Description: params.Description,
+	}).Extract()
+	if err != nil {
+		return fmt.Errorf("fatal: couldn't create the new access key\ntrace: %w", err)
+	}
+	fmt.Fprintf(out, "created access key %s (%s) for user %s\n", newToken.AccessKey, params.Description,
+		userLabel(user))
+
+	credential := Credential{
+		AccessKey:  newToken.AccessKey,
+		SecretKey:  newToken.SecretKey,
+		DomainName: activeCloud.Domain.Name,
+		Region:     activeCloud.Region,
+		UserName:   user.Name,
+		UserID:     user.ID,
+	}
+	for _, output := range params.Outputs {
+		if err = WriteCredential(credential, output, out); err != nil {
+			return fmt.Errorf("%w\nthe new access key %s exists, the old key %s was kept",
+				err, newToken.AccessKey, oldToken.AccessKey)
+		}
+		fmt.Fprintf(out, "wrote access key %s as %s\n", newToken.AccessKey, output.Format)
+	}
+	if params.VerifyCommand != "" {
+		if err = runVerifyCommand(params.VerifyCommand, credential); err != nil {
+			return fmt.Errorf("fatal: verifying the new access key %s failed, the old key %s was kept\ntrace: %w",
+				newToken.AccessKey, oldToken.AccessKey, err)
+		}
+		fmt.Fprintf(out, "verified access key %s with %q\n", newToken.AccessKey, params.VerifyCommand)
+	}
+	if activeCloud.AccessKey != nil && activeCloud.AccessKey.AccessKey == oldToken.AccessKey {
+		activeCloud.AccessKey = &config.AccessKeyPair{AccessKey: newToken.AccessKey, SecretKey: newToken.SecretKey}
+		config.UpdateCloudConfig(*activeCloud)
+		fmt.Fprintf(out, "logged in with access key %s\n", newToken.AccessKey)
+	}
+
+	if err = retireAccessToken(params.Retire, oldToken.AccessKey); err != nil {
+		return fmt.Errorf("fatal: couldn't %s the old access key %s\ntrace: %w",
+			params.Retire, oldToken.AccessKey, err)
+	}
+	fmt.Fprintf(out, "%s old access key %s (%s) of user %s\n", retiredVerb(params.Retire), oldToken.AccessKey,
+		oldToken.Description, userLabel(user))
+	return nil
+}
+
+// selectRotatedToken picks the key to replace and checks there is room for
+// the new one next to it. A key retired by an earlier rotation that takes up
+// the room is returned as well, it has to be deleted first.
+func selectRotatedToken(accessTokens []credentials.Credential, accessKey string, force bool,
+) (*credentials.Credential, *credentials.Credential, error) {
+	var selected *credentials.Credential
+	if accessKey != "" {
+		for index := range accessTokens {
+			if accessTokens[index].AccessKey == accessKey {
+				selected = &accessTokens[index]
+			}
+		}
+		if selected == nil {
+			return nil, nil, fmt.Errorf("fatal: access key %s doesn't belong to the user", accessKey)
+		}
+		if selected.Description != DefaultTokenDescription && !force {
+			return nil, nil, fmt.Errorf("fatal: access key %s (%s) wasn't created by otc-auth, "+
+				"pass --force to replace it anyway", selected.AccessKey, selected.Description)
+		}
+	} else {
+		var created []string
+		for index := range accessTokens {
+			if accessTokens[index].Description == DefaultTokenDescription &&
+				accessTokens[index].Status != StatusInactive {
+				selected = &accessTokens[index]
+				created = append(created, selected.AccessKey)
+			}
+		}
+		switch len(created) {
+		case 0:
+			return nil, nil, errors.New("fatal: found no access key created by otc-auth, " +
+				"pass the one to replace with --token")
+		case 1:
+		default:
+			return nil, nil, fmt.Errorf("fatal: found several access keys created by otc-auth (%s), "+
+				"pass the one to replace with --token", strings.Join(created, ", "))
+		}
+	}
+
+	if len(accessTokens) >= maxAccessTokens {
+		for index, accessToken := range accessTokens {
+			if accessToken.AccessKey == selected.AccessKey {
+				continue
+			}
+			if accessToken.Description == RetiredTokenDescription && accessToken.Status == StatusInactive {
+				return selected, &accessTokens[index], nil
+			}
+			return nil, nil, fmt.Errorf("fatal: the user already has %d access keys, the most the OTC allows. "+
+				"Delete %s (%s) to make room for the new key", maxAccessTokens, accessToken.AccessKey,
+				accessToken.Description)
+		}
+	}
+	return selected, nil, nil
+}
+
+// runVerifyCommand runs the command with the shell. The new key is exported
+// like the shell format writes it, the command's output goes to stderr.
+func runVerifyCommand(command string, credential Credential) error {
+	glog.V(common.InfoLogLevel).Infof("info: verifying the new access key with %s", command)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = os.Environ()
+	for _, variable := range credentialEnv(credential) {
+		if variable.value != "" {
+			cmd.Env = append(cmd.Env, variable.name+"="+variable.value)
+		}
+	}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func retireAccessToken(action string, accessKey string) error {
+	if action == RetireDeactivate {
+		return updateAccessToken(accessKey, credentials.UpdateOpts{
+			Status:      StatusInactive,
+			Description: RetiredTokenDescription,
+		})
+	}
+	return DeleteAccessToken(accessKey)
+}
+
+func retiredVerb(action string) string {
+	if action == RetireDeactivate {
+		return "deactivated"
+	}
+	return "deleted"
+}
@@ -0,0 +1,136 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/credentials"
+)
+
+func TestSelectRotatedToken(t *testing.T) {
+	t.Parallel()
+	created := credentials.Credential{AccessKey: "CREATED", Description: DefaultTokenDescription}
+	other := credentials.Credential{AccessKey: "OTHER", Description: "ci pipeline"}
+	tests := []struct {
+		name         string
+		accessTokens []credentials.Credential
+		accessKey    string
+		force        bool
+		want         string
+		wantErr      string
+	}{
+		{
+			name:         "only key created by otc-auth",
+			accessTokens: []credentials.Credential{created},
+			want:         "CREATED",
+		},
+		{
+			name:         "no key created by otc-auth",
+			accessTokens: []credentials.Credential{other},
+			wantErr:      "found no access key created by otc-auth",
+		},
+		{
+			name: "several keys created by otc-auth",
+			accessTokens: []credentials.Credential{
+				created, {AccessKey: "CREATED2", Description: DefaultTokenDescription},
+			},
+			wantErr: "CREATED, CREATED2",
+		},
+		{
+			name:         "foreign key",
+			accessTokens: []credentials.Credential{other},
+			accessKey:    "OTHER",
+			wantErr:      "pass --force",
+		},
+		{
+			name:         "forced foreign key",
+			accessTokens: []credentials.Credential{other},
+			accessKey:    "OTHER",
+			force:        true,
+			want:         "OTHER",
+		},
+		{
+			name:         "unknown key",
+			accessTokens: []credentials.Credential{created},
+			accessKey:    "UNKNOWN",
+			wantErr:      "doesn't belong to the user",
+		},
+		{
+			name:         "no room for the new key",
+			accessTokens: []credentials.Credential{created, other},
+			wantErr:      "Delete OTHER (ci pipeline)",
+		},
+		{
+			name: "inactive key created by otc-auth",
+			accessTokens: []credentials.Credential{
+				created, {AccessKey: "INACTIVE", Description: DefaultTokenDescription, Status: StatusInactive},
+			},
+			wantErr: "Delete INACTIVE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, _, err := selectRotatedToken(tt.accessTokens, tt.accessKey, tt.force)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("selectRotatedToken() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AccessKey != tt.want {
+				t.Errorf("selectRotatedToken() = %s, want %s", got.AccessKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectRotatedTokenDeactivatedTwice(t *testing.T) {
+	t.Parallel()
+	accessTokens := []credentials.Credential{
+		{AccessKey: "FIRST", Description: DefaultTokenDescription, Status: StatusActive},
+	}
+	for _, newKey := range []string{"SECOND", "THIRD"} {
+		oldToken, retiredToken, err := selectRotatedToken(accessTokens, "", false)
+		if err != nil {
+			t.Fatalf("rotating to %s: %v", newKey, err)
+		}
+		var remaining []credentials.Credential
+		for _, accessToken := range accessTokens {
+			if retiredToken != nil && accessToken.AccessKey == retiredToken.AccessKey {
+				continue
+			}
+			if accessToken.AccessKey == oldToken.AccessKey {
+				accessToken.Status = StatusInactive
+				accessToken.Description = RetiredTokenDescription
+			}
+			remaining = append(remaining, accessToken)
+		}
+		accessTokens = append(remaining, credentials.Credential{
+			AccessKey: newKey, Description: DefaultTokenDescription, Status: StatusActive,
+		})
+	}
+	if len(accessTokens) != maxAccessTokens || accessTokens[0].AccessKey != "SECOND" ||
+		accessTokens[1].AccessKey != "THIRD" {
+		t.Errorf("keys after two rotations = %v, want SECOND retired and THIRD", accessTokens)
+	}
+}
+
+func TestRunVerifyCommand(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are written for sh")
+	}
+	credential := Credential{AccessKey: "NEW", SecretKey: "SECRET"}
+	if err := runVerifyCommand(`test "$AWS_ACCESS_KEY_ID" = NEW -a "$OS_SECRET_KEY" = SECRET`, credential); err != nil {
+		t.Errorf("runVerifyCommand() didn't export the new key: %v", err)
+	}
+	if err := runVerifyCommand("exit 1", credential); err == nil {
+		t.Error("runVerifyCommand() of a failing command succeeded")
+	}
+}
@@ -0,0 +1,319 @@
+package accesstoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"otc-auth/common"
+	"otc-auth/common/xheaders"
+	"otc-auth/config"
+	"otc-auth/iam"
+
+	"github.com/go-http-utils/headers"
+	"github.com/golang/glog"
+)
+
+const (
+	// DefaultServeAddress is where serve-credentials listens unless told
+	// otherwise, a free port on the IPv4 loopback
+	DefaultServeAddress = "127.0.0.1:0"
+
+	// SecurityKeyPath serves temporary AK/SKs like the ECS metadata service
+	SecurityKeyPath = "/openstack/latest/securitykey"
+	// ContainerCredentialsPath serves temporary AK/SKs like the AWS container
+	// credentials endpoint
+	ContainerCredentialsPath = "/credentials"
+	// TokenPath serves the IAM token
+	TokenPath = "/token"
+
+	serveAuthTokenBytes = 32
+	// serveRenewInterval is how often the server checks whether the cached
+	// AK/SK has to be renewed
+	serveRenewInterval = 1 * time.Minute
+	serveRWTimeout     = 1 * time.Minute
+	serveIdleTimeout   = 2 * time.Minute
+	serveShutdownGrace = 5 * time.Second
+)
+
+// ServeParams configures the local credential endpoint.
+type ServeParams struct {
+	// Address is the loopback host and port to listen on
+	Address string
+	// AuthToken has to be sent in the Authorization header of every request,
+	// a random one is generated if empty
+	AuthToken string
+	Temporary TemporaryParams
+}
+
+// securityKeyOutput is what the ECS metadata service returns for
+// /openstack/latest/securitykey.
+type securityKeyOutput struct {
+	Credential struct {
+		Access        string `json:"access"`
+		Secret        string `json:"secret"`
+		SecurityToken string `json:"securitytoken"`
+		ExpiresAt     string `json:"expires_at"`
+	} `json:"credential"`
+}
+
+// containerCredentialsOutput is what the AWS SDKs expect from
+// AWS_CONTAINER_CREDENTIALS_FULL_URI, see
+// https://docs.aws.amazon.com/sdkref/latest/guide/feature-container-credentials.html
+type containerCredentialsOutput struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+	Expiration      string `json:"Expiration"`
+}
+
+type tokenOutput struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// credentialServer hands out the cached temporary AK/SK and the IAM token.
+// The config and the cache are files, so requests are served one at a time.
+type credentialServer struct {
+	authToken  string
+	credential func() (*Credential, error)
+	token      func() (config.Token, error)
+	mutex      sync.Mutex
+}
+
+// ServeCredentials serves temporary AK/SKs and the IAM token on a loopback
+// address until ctx is done. It prints the environment the AWS SDKs need to
+// out. The AK/SK is cached and renewed in the background before it expires.
+func ServeCredentials(ctx context.Context, params ServeParams, out io.Writer) error {
+	if err := validateLoopbackAddress(params.Address); err != nil {
+		return err
+	}
+	authToken := params.AuthToken
+	if authToken == "" {
+		var err error
+		authToken, err = newServeAuthToken()
+		if err != nil {
+			return err
+		}
+	}
+	server := &credentialServer{
+		authToken: authToken,
+		credential: func() (*Credential, error) {
+			return getTemporaryCredential(params.Temporary,
+				temporaryCredentialRenewWindow(params.Temporary.DurationSeconds))
+		},
+		token: func() (config.Token, error) {
+			return currentToken(params.Temporary.ProjectName)
+		},
+	}
+	// fail early instead of on the first request
+	if _, err := server.currentCredential(); err != nil {
+		return err
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", params.Address)
+	if err != nil {
+		return fmt.Errorf("fatal: can't listen on %s, something might already be using this port\ntrace: %w",
+			params.Address, err)
+	}
+	baseURL := "http://" + listener.Addr().String()
+	if _, err = fmt.Fprintf(out, "export AWS_CONTAINER_CREDENTIALS_FULL_URI=%s%s\n"+
+		"export AWS_CONTAINER_AUTHORIZATION_TOKEN=%s\n"+
+		"# ECS metadata format: %s%s\n"+
+		"# IAM token: %s%s\n",
+		baseURL, ContainerCredentialsPath, authToken, baseURL, SecurityKeyPath, baseURL, TokenPath); err != nil {
+		listener.Close()
+		return fmt.Errorf("fatal: couldn't write the endpoint\ntrace: %w", err)
+	}
+
+	httpServer := &http.Server{
+		Handler:      server.handler(),
+		ReadTimeout:  serveRWTimeout,
+		WriteTimeout: serveRWTimeout,
+		IdleTimeout:  serveIdleTimeout,
+		BaseContext: func(listener net.Listener) context.Context {
+			return ctx
+		},
+	}
+	go server.renew(ctx, serveRenewInterval)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownGrace)
+		defer cancel()
+		if errShutdown := httpServer.Shutdown(shutdownCtx); errShutdown != nil {
+			glog.Warningf("warning: couldn't shut down the credential endpoint: %s", errShutdown)
+		}
+	}()
+	glog.V(common.InfoLogLevel).Infof("info: serving credentials on %s", baseURL)
+	if err = httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("fatal: the credential endpoint failed\ntrace: %w", err)
+	}
+	return nil
+}
+
+// validateLoopbackAddress makes sure credentials are never served beyond the
+// local machine.
+func validateLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("fatal: invalid address %s, use host:port\ntrace: %w", address, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("fatal: credentials are only served on loopback addresses like 127.0.0.1, not %s", host)
+	}
+	return nil
+}
+
+func newServeAuthToken() (string, error) {
+	random := make([]byte, serveAuthTokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("fatal: couldn't generate the auth token\ntrace: %w", err)
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// currentToken returns the scoped token of the project, or the unscoped token
+// of the login if there is none.
+func currentToken(projectName string) (config.Token, error) {
+	if projectName != "" {
+		return iam.ScopedToken(projectName)
+	}
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return config.Token{}, err
+	}
+	if activeCloud.AccessKey != nil {
+		return config.Token{}, errors.New("fatal: logged in with an access key, there is no token to serve")
+	}
+	if !activeCloud.UnscopedToken.IsTokenValid() {
+		return config.Token{}, errors.New("fatal: the unscoped token expired, please log in again")
+	}
+	return activeCloud.UnscopedToken, nil
+}
+
+func (s *credentialServer) currentCredential() (*Credential, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.credential()
+}
+
+func (s *credentialServer) currentToken() (config.Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.token()
+}
+
+// renew keeps the cached AK/SK fresh, so requests rarely wait for the IAM.
+func (s *credentialServer) renew(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.currentCredential(); err != nil {
+				glog.Warningf("warning: couldn't renew the access key: %s", err)
+			}
+		}
+	}
+}
+
+func (s *credentialServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(SecurityKeyPath, s.authorized(s.handleSecurityKey))
+	mux.HandleFunc(ContainerCredentialsPath, s.authorized(s.handleContainerCredentials))
+	mux.HandleFunc(TokenPath, s.authorized(s.handleToken))
+	return mux
+}
+
+// authorized rejects requests without the auth token, it accepts the token
+// as is, like the AWS SDKs send it, or as a bearer token.
+func (s *credentialServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		sent := strings.TrimPrefix(r.Header.Get(headers.Authorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(s.authToken)) != 1 {
+			http.Error(w, "missing or wrong auth token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *credentialServer) handleSecurityKey(w http.ResponseWriter, _ *http.Request) {
+	credential, err := s.currentCredential()
+	if err != nil {
+		serveUnavailable(w, err)
+		return
+	}
+	var output securityKeyOutput
+	output.Credential.Access = credential.AccessKey
+	output.Credential.Secret = credential.SecretKey
+	output.Credential.SecurityToken = credential.SecurityToken
+	output.Credential.ExpiresAt = credential.ExpiresAt
+	writeJSON(w, output)
+}
+
+func (s *credentialServer) handleContainerCredentials(w http.ResponseWriter, _ *http.Request) {
+	credential, err := s.currentCredential()
+	if err != nil {
+		serveUnavailable(w, err)
+		return
+	}
+	expiresAt, err := credentialExpiry(*credential)
+	if err != nil {
+		serveUnavailable(w, err)
+		return
+	}
+	writeJSON(w, containerCredentialsOutput{
+		AccessKeyID:     credential.AccessKey,
+		SecretAccessKey: credential.SecretKey,
+		Token:           credential.SecurityToken,
+		Expiration:      expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *credentialServer) handleToken(w http.ResponseWriter, _ *http.Request) {
+	token, err := s.currentToken()
+	if err != nil {
+		serveUnavailable(w, err)
+		return
+	}
+	// like the IAM itself, so clients can take the token from the header
+	w.Header().Set(xheaders.XSubjectToken, token.Secret)
+	writeJSON(w, tokenOutput{Token: token.Secret, ExpiresAt: token.ExpiresAt})
+}
+
+func serveUnavailable(w http.ResponseWriter, err error) {
+	glog.Warningf("warning: couldn't serve credentials: %s", err)
+	http.Error(w, err.Error(), http.StatusServiceUnavailable)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		serveUnavailable(w, err)
+		return
+	}
+	w.Header().Set(headers.ContentType, "application/json")
+	if _, err = w.Write(encoded); err != nil {
+		glog.Warningf("warning: couldn't write the response: %s", err)
+	}
+}
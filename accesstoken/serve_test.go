@@ -0,0 +1,128 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otc-auth/common/xheaders"
+	"otc-auth/config"
+
+	"github.com/go-http-utils/headers"
+)
+
+func TestCredentialServer(t *testing.T) {
+	t.Parallel()
+	server := &credentialServer{
+		authToken: "secret",
+		credential: func() (*Credential, error) {
+			return &Credential{
+				AccessKey: "AK", SecretKey: "SK", SecurityToken: "STS", ExpiresAt: "2025-01-01T12:00:00.123456Z",
+			}, nil
+		},
+		token: func() (config.Token, error) {
+			return config.Token{Secret: "IAM", ExpiresAt: "2025-01-01T12:00:00Z"}, nil
+		},
+	}
+	failing := &credentialServer{
+		authToken:  "secret",
+		credential: func() (*Credential, error) { return nil, errors.New("login expired") },
+		token:      func() (config.Token, error) { return config.Token{}, errors.New("login expired") },
+	}
+	tests := []struct {
+		name          string
+		server        *credentialServer
+		method        string
+		path          string
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{
+			name: "container credentials", server: server, path: ContainerCredentialsPath, authorization: "secret",
+			wantStatus: http.StatusOK,
+			wantBody: `{"AccessKeyId":"AK","SecretAccessKey":"SK","Token":"STS",` +
+				`"Expiration":"2025-01-01T12:00:00Z"}`,
+		},
+		{
+			name: "security key", server: server, path: SecurityKeyPath, authorization: "Bearer secret",
+			wantStatus: http.StatusOK,
+			wantBody: `{"credential":{"access":"AK","secret":"SK","securitytoken":"STS",` +
+				`"expires_at":"2025-01-01T12:00:00.123456Z"}}`,
+		},
+		{
+			name: "token", server: server, path: TokenPath, authorization: "secret",
+			wantStatus: http.StatusOK, wantBody: `{"token":"IAM","expires_at":"2025-01-01T12:00:00Z"}`,
+		},
+		{name: "no auth token", server: server, path: ContainerCredentialsPath, wantStatus: http.StatusUnauthorized},
+		{
+			name: "wrong auth token", server: server, path: TokenPath, authorization: "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "POST", server: server, method: http.MethodPost, path: TokenPath, authorization: "secret",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{name: "unknown path", server: server, path: "/latest/meta-data", wantStatus: http.StatusNotFound},
+		{
+			name: "credential unavailable", server: failing, path: ContainerCredentialsPath, authorization: "secret",
+			wantStatus: http.StatusServiceUnavailable, wantBody: "login expired",
+		},
+		{
+			name: "token unavailable", server: failing, path: TokenPath, authorization: "secret",
+			wantStatus: http.StatusServiceUnavailable, wantBody: "login expired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			request := httptest.NewRequest(method, tt.path, nil)
+			if tt.authorization != "" {
+				request.Header.Set(headers.Authorization, tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			tt.server.handler().ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(recorder.Body.String()); tt.wantBody != "" && got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+			if tt.path == TokenPath && tt.wantStatus == http.StatusOK &&
+				recorder.Header().Get(xheaders.XSubjectToken) != "IAM" {
+				t.Errorf("%s header = %q, want IAM", xheaders.XSubjectToken, recorder.Header().Get(xheaders.XSubjectToken))
+			}
+		})
+	}
+}
+
+func TestValidateLoopbackAddress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: DefaultServeAddress},
+		{address: "localhost:0"},
+		{address: "[::1]:8089"},
+		{address: "0.0.0.0:8089", wantErr: true},
+		{address: "192.168.1.10:8089", wantErr: true},
+		{address: ":8089", wantErr: true},
+		{address: "127.0.0.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			t.Parallel()
+			if err := validateLoopbackAddress(tt.address); (err != nil) != tt.wantErr {
+				t.Errorf("validateLoopbackAddress(%s) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
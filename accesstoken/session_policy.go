@@ -0,0 +1,126 @@
+package accesstoken
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// How temporary AK/SKs are issued.
+const (
+	// TemporaryMethodToken issues the AK/SK for the logged in user, it has the
+	// permissions of the token
+	TemporaryMethodToken = "token"
+	// TemporaryMethodAgency issues the AK/SK by assuming an agency, it has the
+	// permissions the agency grants
+	TemporaryMethodAgency = "agency"
+)
+
+// TemporaryMethods returns all ways to issue temporary AK/SKs.
+func TemporaryMethods() []string {
+	return []string{TemporaryMethodToken, TemporaryMethodAgency}
+}
+
+// ReadSessionPolicy returns the IAM policy document given inline or in a
+// file, compacted, or nil if neither is given. The document has to look like
+// a policy, the IAM checks the rest when issuing the AK/SK.
+func ReadSessionPolicy(inline string, file string) ([]byte, error) {
+	if inline != "" && file != "" {
+		return nil, errors.New("fatal: pass the session policy either inline or as a file, not both")
+	}
+	document := []byte(inline)
+	if file != "" {
+		var err error
+		document, err = os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: couldn't read the session policy %s\ntrace: %w", file, err)
+		}
+	}
+	if len(bytes.TrimSpace(document)) == 0 {
+		return nil, nil
+	}
+
+	var policy struct {
+		Version   string            `json:"Version"`
+		Statement []json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal(document, &policy); err != nil {
+		return nil, fmt.Errorf("fatal: the session policy isn't a JSON policy document\ntrace: %w", err)
+	}
+	if policy.Version == "" || len(policy.Statement) == 0 {
+		return nil, errors.New("fatal: the session policy needs a Version and at least one Statement")
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, document); err != nil {
+		return nil, fmt.Errorf("fatal: couldn't compact the session policy\ntrace: %w", err)
+	}
+	return compacted.Bytes(), nil
+}
+
+// temporaryCredentialOpts builds the request of the security token API. The
+// SDK's options know neither session policies nor the duration of agency
+// AK/SKs together with the delegating domain.
+type temporaryCredentialOpts struct {
+	Method          string
+	DurationSeconds int
+	AgencyName      string
+	// AgencyDomainName is the domain which created the agency
+	AgencyDomainName string
+	Policy           json.RawMessage
+}
+
+func (opts temporaryCredentialOpts) ToTempCredentialCreateMap() (map[string]any, error) {
+	identity := map[string]any{}
+	switch opts.Method {
+	case TemporaryMethodToken, "":
+		// the token itself is sent in the X-Auth-Token header
+		token := map[string]any{}
+		if opts.DurationSeconds != 0 {
+			token["duration_seconds"] = opts.DurationSeconds
+		}
+		identity["methods"] = []string{"token"}
+		identity["token"] = token
+	case TemporaryMethodAgency:
+		if opts.AgencyName == "" || opts.AgencyDomainName == "" {
+			return nil, errors.New("fatal: issuing an AK/SK by an agency needs the agency and its domain")
+		}
+		assumeRole := map[string]any{
+			"agency_name": opts.AgencyName,
+			"domain_name": opts.AgencyDomainName,
+		}
+		if opts.DurationSeconds != 0 {
+			assumeRole["duration_seconds"] = opts.DurationSeconds
+		}
+		identity["methods"] = []string{"assume_role"}
+		identity["assume_role"] = assumeRole
+	default:
+		return nil, fmt.Errorf("fatal: unknown method %s, use one of %s",
+			opts.Method, strings.Join(TemporaryMethods(), ", "))
+	}
+	if len(opts.Policy) > 0 {
+		identity["policy"] = opts.Policy
+	}
+	return map[string]any{"auth": map[string]any{"identity": identity}}, nil
+}
+
+// temporaryCredentialVariant tells AK/SKs of the same login and lifetime
+// apart which were issued by an agency or with a session policy. It is empty
+// for plain token AK/SKs.
+func temporaryCredentialVariant(params TemporaryParams) string {
+	if (params.Method == TemporaryMethodToken || params.Method == "") && len(params.Policy) == 0 {
+		return ""
+	}
+	hash := sha256.New()
+	for _, part := range [][]byte{
+		[]byte(params.Method), []byte(params.AgencyName), []byte(params.AgencyDomainName), params.Policy,
+	} {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
@@ -0,0 +1,131 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const uploadPolicy = `{
+  "Version": "1.1",
+  "Statement": [
+    {"Effect": "Allow", "Action": ["obs:object:PutObject"], "Resource": ["OBS:*:*:object:artifacts/*"]}
+  ]
+}`
+
+func TestReadSessionPolicy(t *testing.T) {
+	t.Parallel()
+	policyFile := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(policyFile, []byte(uploadPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	compacted := `{"Version":"1.1","Statement":[{"Effect":"Allow","Action":["obs:object:PutObject"],` +
+		`"Resource":["OBS:*:*:object:artifacts/*"]}]}`
+	tests := []struct {
+		name    string
+		inline  string
+		file    string
+		want    string
+		wantErr string
+	}{
+		{name: "none"},
+		{name: "inline", inline: uploadPolicy, want: compacted},
+		{name: "file", file: policyFile, want: compacted},
+		{name: "both", inline: uploadPolicy, file: policyFile, wantErr: "not both"},
+		{name: "missing file", file: policyFile + ".missing", wantErr: "couldn't read"},
+		{name: "no JSON", inline: "obs:*", wantErr: "isn't a JSON policy document"},
+		{name: "no statement", inline: `{"Version": "1.1"}`, wantErr: "at least one Statement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ReadSessionPolicy(tt.inline, tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadSessionPolicy() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ReadSessionPolicy() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemporaryCredentialOpts(t *testing.T) {
+	t.Parallel()
+	policy, err := ReadSessionPolicy(uploadPolicy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		opts    temporaryCredentialOpts
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "token",
+			opts: temporaryCredentialOpts{DurationSeconds: 900},
+			want: `{"auth":{"identity":{"methods":["token"],"token":{"duration_seconds":900}}}}`,
+		},
+		{
+			name: "token with policy",
+			opts: temporaryCredentialOpts{Method: TemporaryMethodToken, Policy: policy},
+			want: `{"auth":{"identity":{"methods":["token"],"policy":` + string(policy) + `,"token":{}}}}`,
+		},
+		{
+			name: "agency",
+			opts: temporaryCredentialOpts{
+				Method: TemporaryMethodAgency, DurationSeconds: 3600, AgencyName: "ci", AgencyDomainName: "d",
+			},
+			want: `{"auth":{"identity":{"assume_role":{"agency_name":"ci","domain_name":"d","duration_seconds":3600},` +
+				`"methods":["assume_role"]}}}`,
+		},
+		{name: "agency without name", opts: temporaryCredentialOpts{Method: TemporaryMethodAgency}, wantErr: true},
+		{name: "unknown method", opts: temporaryCredentialOpts{Method: "password"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			body, err := tt.opts.ToTempCredentialCreateMap()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ToTempCredentialCreateMap() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToTempCredentialCreateMap() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemporaryCredentialVariant(t *testing.T) {
+	t.Parallel()
+	plain := temporaryCredentialVariant(TemporaryParams{DurationSeconds: 900})
+	if plain != "" {
+		t.Errorf("variant of a token AK/SK = %s, want none", plain)
+	}
+	restricted := temporaryCredentialVariant(TemporaryParams{Policy: []byte(`{"Version":"1.1"}`)})
+	agency := temporaryCredentialVariant(TemporaryParams{Method: TemporaryMethodAgency, AgencyName: "ci"})
+	otherAgency := temporaryCredentialVariant(TemporaryParams{Method: TemporaryMethodAgency, AgencyName: "cd"})
+	if restricted == "" || agency == "" || restricted == agency || agency == otherAgency {
+		t.Errorf("variants %q, %q and %q should differ", restricted, agency, otherAgency)
+	}
+}
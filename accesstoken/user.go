@@ -0,0 +1,85 @@
+package accesstoken
+
+import (
+	"fmt"
+
+	"otc-auth/config"
+
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/tokens"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/users"
+)
+
+// UserSelector picks the IAM user whose permanent keys are managed, the
+// logged in user if it is empty. Managing the keys of other users needs the
+// permissions of a domain admin.
+type UserSelector struct {
+	Name string
+	ID   string
+}
+
+// IsEmpty tells whether the logged in user is meant.
+func (selector UserSelector) IsEmpty() bool {
+	return selector.Name == "" && selector.ID == ""
+}
+
+// resolveUser looks up the selected user, by ID or by name within the
+// domain of the login.
+func resolveUser(client *golangsdk.ServiceClient, activeCloud *config.Cloud, selector UserSelector,
+) (*tokens.User, error) {
+	switch {
+	case selector.ID != "":
+		user, err := users.Get(client, selector.ID).Extract()
+		if err != nil {
+			return nil, fmt.Errorf("fatal: couldn't find the user with ID %s\ntrace: %w", selector.ID, err)
+		}
+		return &tokens.User{ID: user.ID, Name: user.Name}, nil
+	case selector.Name != "":
+		pages, err := users.List(client, users.ListOpts{
+			DomainID: activeCloud.Domain.ID,
+			Name:     selector.Name,
+		}).AllPages()
+		if err != nil {
+			return nil, fmt.Errorf("fatal: couldn't look up the user %s\ntrace: %w", selector.Name, err)
+		}
+		found, err := users.ExtractUsers(pages)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: couldn't look up the user %s\ntrace: %w", selector.Name, err)
+		}
+		return selectUserByName(found, selector.Name)
+	default:
+		user, err := getCurrentUser(client, activeCloud)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get user: %w", err)
+		}
+		return user, nil
+	}
+}
+
+// selectUserByName picks the user with exactly the name, user names are
+// unique within a domain.
+func selectUserByName(found []users.User, name string) (*tokens.User, error) {
+	var selected *tokens.User
+	for _, user := range found {
+		if user.Name != name {
+			continue
+		}
+		if selected != nil {
+			return nil, fmt.Errorf("fatal: found several users named %s, pass the one meant by its ID", name)
+		}
+		selected = &tokens.User{ID: user.ID, Name: user.Name}
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("fatal: found no user named %s in the domain", name)
+	}
+	return selected, nil
+}
+
+// userLabel names the user in output, scripts can rely on the ID in
+// parentheses.
+func userLabel(user *tokens.User) string {
+	if user.Name == "" {
+		return user.ID
+	}
+	return fmt.Sprintf("%s (%s)", user.Name, user.ID)
+}
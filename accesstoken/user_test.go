@@ -0,0 +1,78 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otc-auth/config"
+
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/tokens"
+)
+
+func TestResolveUser(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v3/users/u1":
+			_, _ = w.Write([]byte(`{"user":{"id":"u1","name":"ci-bot","domain_id":"d1"}}`))
+		case r.URL.Path == "/v3/users" && r.URL.Query().Get("name") == "ci-bot" &&
+			r.URL.Query().Get("domain_id") == "d1":
+			_, _ = w.Write([]byte(`{"users":[{"id":"u1","name":"ci-bot","domain_id":"d1"}],"links":{}}`))
+		case r.URL.Path == "/v3/users":
+			_, _ = w.Write([]byte(`{"users":[],"links":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := &golangsdk.ServiceClient{
+		ProviderClient: &golangsdk.ProviderClient{},
+		Endpoint:       server.URL + "/v3/",
+	}
+	activeCloud := &config.Cloud{Domain: config.NameAndIDResource{Name: "domain", ID: "d1"}}
+
+	tests := []struct {
+		name     string
+		selector UserSelector
+		want     string
+		wantErr  string
+	}{
+		{name: "by ID", selector: UserSelector{ID: "u1"}, want: "ci-bot (u1)"},
+		{name: "by name", selector: UserSelector{Name: "ci-bot"}, want: "ci-bot (u1)"},
+		{name: "unknown name", selector: UserSelector{Name: "nobody"}, wantErr: "found no user named nobody"},
+		{name: "unknown ID", selector: UserSelector{ID: "u2"}, wantErr: "couldn't find the user with ID u2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			user, err := resolveUser(client, activeCloud, tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveUser() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := userLabel(user); got != tt.want {
+				t.Errorf("resolveUser() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserLabel(t *testing.T) {
+	t.Parallel()
+	if got := userLabel(&tokens.User{ID: "u1"}); got != "u1" {
+		t.Errorf("userLabel() without a name = %s, want u1", got)
+	}
+	if got := userLabel(&tokens.User{ID: "u1", Name: "ci-bot"}); got != "ci-bot (u1)" {
+		t.Errorf("userLabel() = %s, want ci-bot (u1)", got)
+	}
+}
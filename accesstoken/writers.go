@@ -0,0 +1,467 @@
+package accesstoken
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"otc-auth/common"
+	"otc-auth/common/endpoints"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/util/homedir"
+)
+
+// Formats the AK/SK can be written in.
+const (
+	FormatShell      = "shell"
+	FormatFish       = "fish"
+	FormatPowerShell = "powershell"
+	FormatDotenv     = "dotenv"
+	FormatJSON       = "json"
+	FormatAWS        = "aws"
+	FormatS3cmd      = "s3cmd"
+	FormatRclone     = "rclone"
+	FormatTerraform  = "terraform"
+)
+
+// Formats returns all formats WriteCredential understands, in the order of the
+// help text.
+func Formats() []string {
+	return []string{
+		FormatShell, FormatFish, FormatPowerShell, FormatDotenv, FormatJSON,
+		FormatAWS, FormatS3cmd, FormatRclone, FormatTerraform,
+	}
+}
+
+// DefaultProfile names the AWS profile and the rclone remote.
+const DefaultProfile = "otc"
+
+// OutputOptions selects how and where the AK/SK is written.
+type OutputOptions struct {
+	// Format is one of Formats, shell if empty
+	Format string
+	// Path overrides the destination of the format
+	Path string
+	// Profile is the section of the aws and rclone formats, DefaultProfile
+	// if empty
+	Profile string
+	// Print writes to stdout instead of the destination
+	Print bool
+}
+
+// Credential is an AK/SK pair, temporary pairs come with a security token.
+// The domain and region are needed by the formats configuring an endpoint.
+type Credential struct {
+	AccessKey     string `json:"accessKey"`
+	SecretKey     string `json:"secretKey"`
+	SecurityToken string `json:"securityToken,omitempty"`
+	ExpiresAt     string `json:"expiresAt,omitempty"`
+	DomainName    string `json:"domainName,omitempty"`
+	Region        string `json:"region,omitempty"`
+	// UserName and UserID label permanent keys with their owner
+	UserName string `json:"userName,omitempty"`
+	UserID   string `json:"userId,omitempty"`
+}
+
+// credentialWriter renders the credential into the content of its
+// destination, merging with what the destination held before if the format
+// allows it.
+type credentialWriter struct {
+	defaultPath func() string
+	render      func(existing string, credential Credential, profile string) (string, error)
+	// usage tells how to use the written file, %s is its path
+	usage string
+}
+
+// credentialWriters returns the writer of every format.
+func credentialWriters() map[string]credentialWriter {
+	return map[string]credentialWriter{
+		FormatShell: {
+			defaultPath: func() string { return "./ak-sk-env.sh" },
+			render:      renderEnv(shellSyntax(), credentialEnv),
+			usage:       "source %s",
+		},
+		FormatFish: {
+			defaultPath: func() string { return "./ak-sk-env.fish" },
+			render:      renderEnv(fishSyntax(), credentialEnv),
+			usage:       "source %s",
+		},
+		FormatPowerShell: {
+			defaultPath: func() string { return "./ak-sk-env.ps1" },
+			render:      renderEnv(powerShellSyntax(), credentialEnv),
+			usage:       ". %s",
+		},
+		FormatDotenv: {
+			defaultPath: func() string { return "./.env" },
+			render:      renderEnv(dotenvSyntax(), credentialEnv),
+		},
+		FormatJSON: {
+			defaultPath: func() string { return "./ak-sk.json" },
+			render:      renderJSON,
+		},
+		FormatAWS: {
+			defaultPath: func() string {
+				return envOrDefault("AWS_SHARED_CREDENTIALS_FILE", filepath.Join("~", ".aws", "credentials"))
+			},
+			render: renderAWS,
+		},
+		FormatS3cmd: {
+			defaultPath: func() string { return filepath.Join("~", ".s3cfg") },
+			render:      renderS3cmd,
+		},
+		FormatRclone: {
+			defaultPath: func() string {
+				return envOrDefault("RCLONE_CONFIG", filepath.Join("~", ".config", "rclone", "rclone.conf"))
+			},
+			render: renderRclone,
+		},
+		FormatTerraform: {
+			defaultPath: func() string { return "./terraform-env.sh" },
+			render:      renderEnv(shellSyntax(), terraformEnv),
+			usage:       "source %s",
+		},
+	}
+}
+
+// WriteCredential writes the credential in the format to its destination,
+// or to stdout if printing. Files are only readable by the user.
+func WriteCredential(credential Credential, options OutputOptions, stdout io.Writer) error {
+	format := options.Format
+	if format == "" {
+		format = FormatShell
+	}
+	writer, ok := credentialWriters()[format]
+	if !ok {
+		return fmt.Errorf("fatal: unknown format %s, use one of %s", format, strings.Join(Formats(), ", "))
+	}
+	profile := options.Profile
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	if options.Print {
+		content, err := writer.render("", credential, profile)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, content)
+		return err
+	}
+
+	path := options.Path
+	if path == "" {
+		path = writer.defaultPath()
+	}
+	if strings.HasPrefix(path, "~") {
+		path = strings.Replace(path, "~", homedir.HomeDir(), 1)
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("fatal: couldn't read %s\ntrace: %w", path, err)
+	}
+	content, err := writer.render(string(existing), credential, profile)
+	if err != nil {
+		return err
+	}
+	if err = writeCredentialFile(path, content); err != nil {
+		return err
+	}
+
+	glog.V(common.InfoLogLevel).Infof("info: access key written to %s", path)
+	if writer.usage != "" {
+		glog.V(common.InfoLogLevel).Infof("info: please run `%s` manually", fmt.Sprintf(writer.usage, path))
+	}
+	return nil
+}
+
+func writeCredentialFile(path string, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil { //nolint:mnd // only the user reads the keys
+		return fmt.Errorf("fatal: couldn't create directory for %s\ntrace: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil { //nolint:mnd // only the user reads the keys
+		return fmt.Errorf("fatal: couldn't write %s\ntrace: %w", path, err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0o600); err != nil { //nolint:mnd // only the user reads the keys
+		return fmt.Errorf("fatal: couldn't restrict permissions of %s\ntrace: %w", path, err)
+	}
+	return nil
+}
+
+func envOrDefault(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// envVar is a variable of the env formats. An empty value removes the
+// variable, e.g. the session token of an earlier temporary AK/SK.
+type envVar struct {
+	name  string
+	value string
+}
+
+func credentialEnv(credential Credential) []envVar {
+	return []envVar{
+		{"OS_ACCESS_KEY", credential.AccessKey},
+		{"AWS_ACCESS_KEY_ID", credential.AccessKey},
+		{"OS_SECRET_KEY", credential.SecretKey},
+		{"AWS_SECRET_ACCESS_KEY", credential.SecretKey},
+		{"AWS_SESSION_TOKEN", credential.SecurityToken},
+	}
+}
+
+// terraformEnv configures the opentelekomcloud provider.
+func terraformEnv(credential Credential) []envVar {
+	authURL := ""
+	if credential.Region != "" {
+		authURL = endpoints.BaseURLIam(credential.Region)
+	}
+	return []envVar{
+		{"OS_AUTH_URL", authURL},
+		{"OS_DOMAIN_NAME", credential.DomainName},
+		{"OS_REGION_NAME", credential.Region},
+		{"OS_ACCESS_KEY", credential.AccessKey},
+		{"OS_SECRET_KEY", credential.SecretKey},
+		{"OS_SECURITY_TOKEN", credential.SecurityToken},
+	}
+}
+
+// envSyntax writes and recognizes the assignments of a shell.
+type envSyntax struct {
+	line func(name string, value string) string
+	// name returns the variable a line assigns, empty for other lines
+	name func(line string) string
+}
+
+func shellSyntax() envSyntax {
+	return envSyntax{
+		line: func(name string, value string) string { return fmt.Sprintf("export %s=%s", name, value) },
+		name: func(line string) string {
+			return assignedName(strings.TrimPrefix(strings.TrimSpace(line), "export "), "=")
+		},
+	}
+}
+
+func fishSyntax() envSyntax {
+	return envSyntax{
+		line: func(name string, value string) string { return fmt.Sprintf("set -gx %s %s", name, value) },
+		name: func(line string) string {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[0] != "set" { //nolint:mnd // set and the name
+				return ""
+			}
+			for _, field := range fields[1:] {
+				if !strings.HasPrefix(field, "-") {
+					return field
+				}
+			}
+			return ""
+		},
+	}
+}
+
+func powerShellSyntax() envSyntax {
+	return envSyntax{
+		line: func(name string, value string) string { return fmt.Sprintf(`$env:%s = "%s"`, name, value) },
+		name: func(line string) string {
+			rest, ok := strings.CutPrefix(strings.TrimSpace(line), "$env:")
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(assignedName(rest, "="))
+		},
+	}
+}
+
+func dotenvSyntax() envSyntax {
+	return envSyntax{
+		line: func(name string, value string) string { return fmt.Sprintf("%s=%s", name, value) },
+		name: shellSyntax().name,
+	}
+}
+
+func assignedName(line string, separator string) string {
+	name, _, found := strings.Cut(line, separator)
+	if !found || strings.HasPrefix(name, "#") || strings.HasPrefix(name, ";") {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+// renderEnv replaces the assignments of the variables in place and appends
+// the missing ones, everything else in the file is kept.
+func renderEnv(syntax envSyntax, variables func(Credential) []envVar,
+) func(string, Credential, string) (string, error) {
+	return func(existing string, credential Credential, _ string) (string, error) {
+		vars := variables(credential)
+		values := map[string]string{}
+		for _, variable := range vars {
+			values[variable.name] = variable.value
+		}
+		written := map[string]bool{}
+		var lines []string
+		for _, line := range splitLines(existing) {
+			name := syntax.name(line)
+			value, managed := values[name]
+			if !managed {
+				lines = append(lines, line)
+				continue
+			}
+			if value != "" && !written[name] {
+				lines = append(lines, syntax.line(name, value))
+				written[name] = true
+			}
+		}
+		for _, variable := range vars {
+			if variable.value != "" && !written[variable.name] {
+				lines = append(lines, syntax.line(variable.name, variable.value))
+			}
+		}
+		return strings.Join(lines, "\n") + "\n", nil
+	}
+}
+
+// renderJSON replaces the file, JSON has no room for other content.
+func renderJSON(_ string, credential Credential, _ string) (string, error) {
+	content, err := json.MarshalIndent(credential, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content) + "\n", nil
+}
+
+// iniValue is a key of an ini section, an empty value removes the key.
+type iniValue struct {
+	key   string
+	value string
+}
+
+func renderAWS(existing string, credential Credential, profile string) (string, error) {
+	return mergeINISection(existing, profile, []iniValue{
+		{"aws_access_key_id", credential.AccessKey},
+		{"aws_secret_access_key", credential.SecretKey},
+		{"aws_session_token", credential.SecurityToken},
+	}), nil
+}
+
+// renderS3cmd writes the default section, the only one s3cmd reads.
+func renderS3cmd(existing string, credential Credential, _ string) (string, error) {
+	if credential.Region == "" {
+		return "", errors.New("fatal: the s3cmd format needs the region of the login")
+	}
+	host := endpoints.OBS(credential.Region)
+	return mergeINISection(existing, "default", []iniValue{
+		{"access_key", credential.AccessKey},
+		{"secret_key", credential.SecretKey},
+		{"access_token", credential.SecurityToken},
+		{"host_base", host},
+		{"host_bucket", "%(bucket)s." + host},
+		{"bucket_location", credential.Region},
+	}), nil
+}
+
+func renderRclone(existing string, credential Credential, profile string) (string, error) {
+	if credential.Region == "" {
+		return "", errors.New("fatal: the rclone format needs the region of the login")
+	}
+	return mergeINISection(existing, profile, []iniValue{
+		{"type", "s3"},
+		{"provider", "Other"},
+		{"access_key_id", credential.AccessKey},
+		{"secret_access_key", credential.SecretKey},
+		{"session_token", credential.SecurityToken},
+		{"endpoint", "https://" + endpoints.OBS(credential.Region)},
+		{"region", credential.Region},
+	}), nil
+}
+
+// mergeINISection sets the keys in the section, adding the section if it is
+// missing. Other sections, keys and comments are kept.
+func mergeINISection(existing string, section string, values []iniValue) string {
+	lines := splitLines(existing)
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		name, isHeader := iniSectionName(line)
+		if !isHeader {
+			continue
+		}
+		if start >= 0 {
+			end = i
+			break
+		}
+		if name == section {
+			start = i
+		}
+	}
+
+	valueOf := map[string]string{}
+	for _, value := range values {
+		valueOf[value.key] = value.value
+	}
+	written := map[string]bool{}
+	var body []string
+	if start >= 0 {
+		for _, line := range lines[start+1 : end] {
+			key := assignedName(strings.TrimSpace(line), "=")
+			value, managed := valueOf[key]
+			if !managed {
+				body = append(body, line)
+				continue
+			}
+			if value != "" && !written[key] {
+				body = append(body, fmt.Sprintf("%s = %s", key, value))
+				written[key] = true
+			}
+		}
+	}
+	// new keys go before the blank lines separating the next section
+	trailing := len(body)
+	for trailing > 0 && strings.TrimSpace(body[trailing-1]) == "" {
+		trailing--
+	}
+	var added []string
+	for _, value := range values {
+		if value.value != "" && !written[value.key] {
+			added = append(added, fmt.Sprintf("%s = %s", value.key, value.value))
+		}
+	}
+	body = append(body[:trailing:trailing], append(added, body[trailing:]...)...)
+
+	var merged []string
+	if start >= 0 {
+		merged = append(merged, lines[:start+1]...)
+		merged = append(merged, body...)
+		merged = append(merged, lines[end:]...)
+	} else {
+		merged = append(merged, lines...)
+		if len(merged) > 0 && strings.TrimSpace(merged[len(merged)-1]) != "" {
+			merged = append(merged, "")
+		}
+		merged = append(merged, "["+section+"]")
+		merged = append(merged, body...)
+	}
+	return strings.Join(merged, "\n") + "\n"
+}
+
+func iniSectionName(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "[") || !strings.HasSuffix(trimmed, "]") {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[1 : len(trimmed)-1]), true
+}
+
+func splitLines(content string) []string {
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
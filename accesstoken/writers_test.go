@@ -0,0 +1,204 @@
+//nolint:testpackage // whitebox testing
+package accesstoken
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	permanent = Credential{AccessKey: "AK", SecretKey: "SK", DomainName: "d", Region: "eu-de"}
+	temporary = Credential{
+		AccessKey: "TAK", SecretKey: "TSK", SecurityToken: "TOKEN", ExpiresAt: "2025-01-01T00:00:00Z",
+		DomainName: "d", Region: "eu-de",
+	}
+)
+
+func TestRenderEnv(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		format     string
+		existing   string
+		credential Credential
+		want       string
+	}{
+		{
+			name:       "new shell file",
+			format:     FormatShell,
+			credential: permanent,
+			want: "export OS_ACCESS_KEY=AK\nexport AWS_ACCESS_KEY_ID=AK\nexport OS_SECRET_KEY=SK\n" +
+				"export AWS_SECRET_ACCESS_KEY=SK\n",
+		},
+		{
+			name:   "shell keeps other lines and drops the old session token",
+			format: FormatShell,
+			existing: "# my env\nexport OS_ACCESS_KEY=OLD\nexport EDITOR=vim\nexport AWS_SESSION_TOKEN=OLD\n" +
+				"export OS_ACCESS_KEY=DUPLICATE\n",
+			credential: permanent,
+			want: "# my env\nexport OS_ACCESS_KEY=AK\nexport EDITOR=vim\nexport AWS_ACCESS_KEY_ID=AK\n" +
+				"export OS_SECRET_KEY=SK\nexport AWS_SECRET_ACCESS_KEY=SK\n",
+		},
+		{
+			name:       "fish",
+			format:     FormatFish,
+			existing:   "set -gx AWS_SESSION_TOKEN OLD\nset -U fish_greeting\n",
+			credential: temporary,
+			want: "set -gx AWS_SESSION_TOKEN TOKEN\nset -U fish_greeting\nset -gx OS_ACCESS_KEY TAK\n" +
+				"set -gx AWS_ACCESS_KEY_ID TAK\nset -gx OS_SECRET_KEY TSK\nset -gx AWS_SECRET_ACCESS_KEY TSK\n",
+		},
+		{
+			name:       "powershell",
+			format:     FormatPowerShell,
+			existing:   "$env:OS_SECRET_KEY = \"OLD\"\n",
+			credential: permanent,
+			want: "$env:OS_SECRET_KEY = \"SK\"\n$env:OS_ACCESS_KEY = \"AK\"\n$env:AWS_ACCESS_KEY_ID = \"AK\"\n" +
+				"$env:AWS_SECRET_ACCESS_KEY = \"SK\"\n",
+		},
+		{
+			name:       "dotenv",
+			format:     FormatDotenv,
+			existing:   "DATABASE_URL=postgres://\nOS_ACCESS_KEY=OLD\n",
+			credential: permanent,
+			want: "DATABASE_URL=postgres://\nOS_ACCESS_KEY=AK\nAWS_ACCESS_KEY_ID=AK\nOS_SECRET_KEY=SK\n" +
+				"AWS_SECRET_ACCESS_KEY=SK\n",
+		},
+		{
+			name:       "terraform",
+			format:     FormatTerraform,
+			credential: temporary,
+			want: "export OS_AUTH_URL=https://iam.eu-de.otc.t-systems.com:443/v3\nexport OS_DOMAIN_NAME=d\n" +
+				"export OS_REGION_NAME=eu-de\nexport OS_ACCESS_KEY=TAK\nexport OS_SECRET_KEY=TSK\n" +
+				"export OS_SECURITY_TOKEN=TOKEN\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := credentialWriters()[tt.format].render(tt.existing, tt.credential, DefaultProfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeINISection(t *testing.T) {
+	t.Parallel()
+	existing := `[default]
+aws_access_key_id = AWS
+aws_secret_access_key = AWS
+
+; managed by otc-auth
+[otc]
+aws_access_key_id = OLD
+aws_session_token = OLD
+region = eu-de
+
+[other]
+aws_access_key_id = OTHER
+`
+	want := `[default]
+aws_access_key_id = AWS
+aws_secret_access_key = AWS
+
+; managed by otc-auth
+[otc]
+aws_access_key_id = AK
+region = eu-de
+aws_secret_access_key = SK
+
+[other]
+aws_access_key_id = OTHER
+`
+	got, err := renderAWS(existing, permanent, "otc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("renderAWS() =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = renderAWS("[default]\naws_access_key_id = AWS\n", temporary, "otc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "[default]\naws_access_key_id = AWS\n\n[otc]\naws_access_key_id = TAK\naws_secret_access_key = TSK\n" +
+		"aws_session_token = TOKEN\n"
+	if got != want {
+		t.Errorf("renderAWS() of a new profile =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderS3cmdAndRclone(t *testing.T) {
+	t.Parallel()
+	s3cmd, err := renderS3cmd("", temporary, DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"[default]", "access_token = TOKEN", "host_base = obs.eu-de.otc.t-systems.com",
+		"host_bucket = %(bucket)s.obs.eu-de.otc.t-systems.com"} {
+		if !strings.Contains(s3cmd, line+"\n") {
+			t.Errorf("renderS3cmd() = %s, want a line %s", s3cmd, line)
+		}
+	}
+	rclone, err := renderRclone("", permanent, "obs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"[obs]", "type = s3", "access_key_id = AK",
+		"endpoint = https://obs.eu-de.otc.t-systems.com"} {
+		if !strings.Contains(rclone, line+"\n") {
+			t.Errorf("renderRclone() = %s, want a line %s", rclone, line)
+		}
+	}
+	if strings.Contains(rclone, "session_token") {
+		t.Errorf("renderRclone() of a permanent AK/SK = %s, want no session token", rclone)
+	}
+	if _, err = renderS3cmd("", Credential{AccessKey: "AK"}, DefaultProfile); err == nil {
+		t.Error("renderS3cmd() without a region succeeded")
+	}
+}
+
+func TestWriteCredential(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "creds", "ak-sk.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil { //nolint:gosec // the test checks it is fixed
+		t.Fatal(err)
+	}
+
+	if err := WriteCredential(temporary, OutputOptions{Format: FormatJSON, Path: path}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Credential
+	if err = json.Unmarshal(content, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != temporary {
+		t.Errorf("written = %+v, want %+v", got, temporary)
+	}
+	if err = WriteCredential(permanent, OutputOptions{Format: "yaml"}, nil); err == nil {
+		t.Error("WriteCredential() with an unknown format succeeded")
+	}
+}
@@ -0,0 +1,314 @@
+package cce
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"sync"
+
+	"otc-auth/common"
+	"otc-auth/config"
+	"otc-auth/iam"
+
+	"github.com/golang/glog"
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/cce/v3/clusters"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// allClustersParallelism limits the concurrent requests against CCE.
+const allClustersParallelism = 4
+
+// ClusterFilter selects projects and clusters by shell patterns (see
+// path.Match). An empty list matches everything.
+type ClusterFilter struct {
+	Projects []string
+	Clusters []string
+}
+
+func (filter ClusterFilter) matchesProject(name string) bool {
+	return matchesAny(filter.Projects, name)
+}
+
+func (filter ClusterFilter) matchesCluster(name string) bool {
+	return matchesAny(filter.Clusters, name)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterResult is the outcome for a single cluster. Cluster is empty if the
+// clusters of the project couldn't be listed.
+type clusterResult struct {
+	Project    string
+	Cluster    string
+	Err        error
+	kubeConfig *api.Config
+}
+
+func (result clusterResult) name() string {
+	if result.Cluster == "" {
+		return result.Project
+	}
+	return fmt.Sprintf("%s/%s", result.Project, result.Cluster)
+}
+
+// GetAllKubeConfigs fetches the kube configs of every cluster in every
+// project of the active cloud which matches the filter, and merges them into
+// a single kube config. Contexts are named "project/cluster" as with
+// get-kube-config without an alias.
+func GetAllKubeConfigs(configParams KubeConfigParams, filter ClusterFilter, skipKubeTLS bool,
+	printKubeConfig bool, summary io.Writer,
+) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		common.ThrowError(err)
+	}
+
+	var projects config.Projects
+	for _, project := range activeCloud.Projects {
+		if filter.matchesProject(project.Name) {
+			projects = append(projects, project)
+		}
+	}
+	if len(projects) == 0 {
+		common.ThrowError(fmt.Errorf("fatal: no project matches %v.\n\nUse the projects list command to "+
+			"get a list of projects", filter.Projects))
+	}
+
+	clients := newProjectClients(activeCloud)
+	var listedMutex sync.Mutex
+	listed := map[string]config.Clusters{}
+	results := fetchAllKubeConfigs(projects, filter,
+		func(project config.Project) (config.Clusters, error) {
+			client, errClient := clients.get(project)
+			if errClient != nil {
+				return nil, errClient
+			}
+			clusterArr, errList := listClusters(client)
+			if errList == nil {
+				listedMutex.Lock()
+				listed[project.Name] = clusterArr
+				listedMutex.Unlock()
+			}
+			return clusterArr, errList
+		},
+		func(project config.Project, cluster config.Cluster) (*api.Config, error) {
+			client, errClient := clients.get(project)
+			if errClient != nil {
+				return nil, errClient
+			}
+			cert, errCert := getCert(client, cluster.ID, configParams.DaysValid)
+			if errCert != nil {
+				return nil, errCert
+			}
+			return clusterKubeConfig(cert, activeCloud, project.Name, cluster, configParams)
+		})
+
+	// the listings are fresh anyway, so they refresh the cluster caches too
+	for projectName, clusterArr := range listed {
+		config.UpdateClusterCache(projectName, clusterArr)
+	}
+
+	kubeConfig := api.NewConfig()
+	for _, result := range results {
+		if result.kubeConfig == nil {
+			continue
+		}
+		if err = merge(kubeConfig, *result.kubeConfig); err != nil {
+			common.ThrowError(fmt.Errorf("fatal: couldn't merge kube config of %s\ntrace: %w", result.name(), err))
+		}
+	}
+	// the current context of the target kube config stays as it is, there is
+	// no sensible choice among all clusters
+	kubeConfig.CurrentContext = ""
+	overrideClusters(kubeConfig, skipKubeTLS, "")
+
+	if len(results) == 0 {
+		glog.Warningf("warning: no cce cluster matches %v", filter.Clusters)
+	}
+	failed := writeSummary(summary, results)
+	if len(kubeConfig.Contexts) > 0 {
+		CheckAndWarnCertsValidity(*kubeConfig)
+		if err = outputKubeConfig(configParams, *kubeConfig, printKubeConfig); err != nil {
+			common.ThrowError(err)
+		}
+	}
+	if failed > 0 {
+		common.ThrowError(fmt.Errorf("fatal: couldn't fetch the kube config of %d of %d entries", failed, len(results)))
+	}
+}
+
+// fetchAllKubeConfigs lists the clusters of all projects and fetches their
+// kube configs concurrently. The results are sorted by project and cluster.
+func fetchAllKubeConfigs(projects config.Projects, filter ClusterFilter,
+	list func(project config.Project) (config.Clusters, error),
+	fetch func(project config.Project, cluster config.Cluster) (*api.Config, error),
+) []clusterResult {
+	type job struct {
+		project config.Project
+		cluster config.Cluster
+	}
+	listErrs := make([]error, len(projects))
+	listed := runLimited(len(projects), func(i int) []job {
+		clusterList, err := list(projects[i])
+		if err != nil {
+			listErrs[i] = fmt.Errorf("couldn't list clusters: %w", err)
+			return nil
+		}
+		var jobs []job
+		for _, cluster := range clusterList {
+			if filter.matchesCluster(cluster.Name) {
+				jobs = append(jobs, job{project: projects[i], cluster: cluster})
+			}
+		}
+		return jobs
+	})
+
+	var results []clusterResult
+	for i, err := range listErrs {
+		if err != nil {
+			results = append(results, clusterResult{Project: projects[i].Name, Err: err})
+		}
+	}
+	var jobs []job
+	for _, projectJobs := range listed {
+		jobs = append(jobs, projectJobs...)
+	}
+
+	results = append(results, runLimited(len(jobs), func(i int) clusterResult {
+		kubeConfig, err := fetch(jobs[i].project, jobs[i].cluster)
+		return clusterResult{
+			Project: jobs[i].project.Name, Cluster: jobs[i].cluster.Name, Err: err, kubeConfig: kubeConfig,
+		}
+	})...)
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].name() < results[j].name()
+	})
+	return results
+}
+
+// runLimited runs task for 0..n-1 with at most allClustersParallelism at a
+// time, the results are in the order of the indices.
+func runLimited[T any](n int, task func(i int) T) []T {
+	results := make([]T, n)
+	limit := make(chan struct{}, allClustersParallelism)
+	var waitGroup sync.WaitGroup
+	for i := range n {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			results[i] = task(i)
+		}()
+	}
+	waitGroup.Wait()
+	return results
+}
+
+// projectClients creates one CCE client per project and shares it between
+// the requests for that project. Scoped tokens expire long before the
+// unscoped one, so each client gets a scoped token that is still valid.
+type projectClients struct {
+	activeCloud *config.Cloud
+	mutex       sync.Mutex
+	clients     map[string]*golangsdk.ServiceClient
+	scopedToken func(projectName string) (config.Token, error)
+	newClient   func(activeCloud *config.Cloud, project *config.Project) (*golangsdk.ServiceClient, error)
+}
+
+func newProjectClients(activeCloud *config.Cloud) *projectClients {
+	return &projectClients{
+		activeCloud: activeCloud,
+		clients:     map[string]*golangsdk.ServiceClient{},
+		scopedToken: iam.ScopedToken,
+		newClient:   newCCEClient,
+	}
+}
+
+func (clients *projectClients) get(project config.Project) (*golangsdk.ServiceClient, error) {
+	clients.mutex.Lock()
+	defer clients.mutex.Unlock()
+	if client, ok := clients.clients[project.Name]; ok {
+		return client, nil
+	}
+	// AK/SK logins sign every request and have no tokens
+	if clients.activeCloud.AccessKey == nil {
+		token, err := clients.scopedToken(project.Name)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get a scoped token for project %s: %w", project.Name, err)
+		}
+		project.ScopedToken = token
+	}
+	client, err := clients.newClient(clients.activeCloud, &project)
+	if err != nil {
+		return nil, err
+	}
+	clients.clients[project.Name] = client
+	return client, nil
+}
+
+// clusterKubeConfig turns the certificate of a cluster into kube config
+// entries named like those of get-kube-config without an alias.
+func clusterKubeConfig(cert *clusters.Certificate, activeCloud *config.Cloud, projectName string,
+	cluster config.Cluster, configParams KubeConfigParams,
+) (*api.Config, error) {
+	rawConfig, err := certToKubeConfig(cert)
+	if err != nil {
+		return nil, err
+	}
+	tag := kubeConfigTag{
+		Domain:    activeCloud.Domain.Name,
+		Project:   projectName,
+		Cluster:   cluster.Name,
+		ClusterID: cluster.ID,
+	}
+	contextName, userName := kubeConfigEntryNames(configParams, tag, activeCloud.Username)
+	renameKubeconfigEntries(rawConfig, contextName, userName)
+	if err = applyKubeConfigOptions(rawConfig, configParams, contextName); err != nil {
+		return nil, err
+	}
+	err = tagKubeConfigEntries(rawConfig, tag)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't tag entries: %w", err)
+	}
+	if configParams.ExecCredential {
+		configParams.ProjectName = projectName
+		configParams.ClusterName = cluster.Name
+		useExecCredential(rawConfig, activeCloud.Domain.Name, configParams)
+	}
+	return rawConfig, nil
+}
+
+// writeSummary lists which clusters succeeded and which failed, it returns the
+// number of failures.
+func writeSummary(w io.Writer, results []clusterResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	succeeded := len(results) - failed
+	fmt.Fprintf(w, "fetched kube configs for %d cluster(s), %d failed\n", succeeded, failed)
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(w, "  failed  %s: %s\n", result.name(), result.Err)
+		} else {
+			fmt.Fprintf(w, "  ok      %s\n", result.name())
+		}
+	}
+	return failed
+}
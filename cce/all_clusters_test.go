@@ -0,0 +1,174 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"otc-auth/config"
+
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestClusterFilter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		patterns []string
+		value    string
+		want     bool
+	}{
+		{name: "no patterns", patterns: nil, value: "anything", want: true},
+		{name: "exact", patterns: []string{"eu-de_prod"}, value: "eu-de_prod", want: true},
+		{name: "glob", patterns: []string{"eu-de_*"}, value: "eu-de_prod", want: true},
+		{name: "second pattern", patterns: []string{"eu-nl_*", "eu-de_*"}, value: "eu-de_prod", want: true},
+		{name: "no match", patterns: []string{"eu-nl_*"}, value: "eu-de_prod", want: false},
+		{name: "invalid pattern", patterns: []string{"["}, value: "[", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			filter := ClusterFilter{Projects: tt.patterns, Clusters: tt.patterns}
+			if got := filter.matchesProject(tt.value); got != tt.want {
+				t.Errorf("matchesProject(%s) = %v, want %v", tt.value, got, tt.want)
+			}
+			if got := filter.matchesCluster(tt.value); got != tt.want {
+				t.Errorf("matchesCluster(%s) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fetchAllKubeConfigs(t *testing.T) {
+	t.Parallel()
+	projects := config.Projects{
+		{NameAndIDResource: config.NameAndIDResource{Name: "p1"}},
+		{NameAndIDResource: config.NameAndIDResource{Name: "p2"}},
+		{NameAndIDResource: config.NameAndIDResource{Name: "p3"}},
+	}
+	clustersByProject := map[string]config.Clusters{
+		"p1": {{Name: "prod-a", ID: "1"}, {Name: "dev-a", ID: "2"}},
+		"p2": {{Name: "prod-b", ID: "3"}, {Name: "prod-broken", ID: "4"}},
+	}
+	var fetches atomic.Int32
+
+	results := fetchAllKubeConfigs(projects, ClusterFilter{Clusters: []string{"prod-*"}},
+		func(project config.Project) (config.Clusters, error) {
+			clusterList, ok := clustersByProject[project.Name]
+			if !ok {
+				return nil, errors.New("forbidden")
+			}
+			return clusterList, nil
+		},
+		func(project config.Project, cluster config.Cluster) (*api.Config, error) {
+			fetches.Add(1)
+			if cluster.Name == "prod-broken" {
+				return nil, errors.New("cluster unavailable")
+			}
+			return &api.Config{Contexts: map[string]*api.Context{
+				project.Name + "/" + cluster.Name: {Cluster: cluster.ID},
+			}}, nil
+		})
+
+	var names []string
+	var failed []string
+	for _, result := range results {
+		names = append(names, result.name())
+		if result.Err != nil {
+			failed = append(failed, result.name())
+		} else if result.kubeConfig == nil {
+			t.Errorf("missing kube config for %s", result.name())
+		}
+	}
+	wantNames := []string{"p1/prod-a", "p2/prod-b", "p2/prod-broken", "p3"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("fetchAllKubeConfigs() results = %v, want %v", names, wantNames)
+	}
+	wantFailed := []string{"p2/prod-broken", "p3"}
+	if !reflect.DeepEqual(failed, wantFailed) {
+		t.Errorf("fetchAllKubeConfigs() failed = %v, want %v", failed, wantFailed)
+	}
+	if fetches.Load() != 3 {
+		t.Errorf("fetchAllKubeConfigs() fetched %d clusters, want 3", fetches.Load())
+	}
+}
+
+func Test_writeSummary(t *testing.T) {
+	t.Parallel()
+	var buffer bytes.Buffer
+	failed := writeSummary(&buffer, []clusterResult{
+		{Project: "p1", Cluster: "c1"},
+		{Project: "p2", Cluster: "c2", Err: errors.New("boom")},
+		{Project: "p3", Err: errors.New("forbidden")},
+	})
+	if failed != 2 {
+		t.Errorf("writeSummary() = %d, want 2", failed)
+	}
+	for _, want := range []string{
+		"fetched kube configs for 1 cluster(s), 2 failed",
+		"ok      p1/c1",
+		"failed  p2/c2: boom",
+		"failed  p3: forbidden",
+	} {
+		if !strings.Contains(buffer.String(), want) {
+			t.Errorf("writeSummary() output misses %q:\n%s", want, buffer.String())
+		}
+	}
+}
+
+func Test_projectClients_get(t *testing.T) {
+	t.Parallel()
+	project := config.Project{
+		NameAndIDResource: config.NameAndIDResource{Name: "eu-de_a", ID: "a"},
+		ScopedToken:       config.Token{Secret: "expired"},
+	}
+	tests := []struct {
+		name          string
+		cloud         *config.Cloud
+		tokenErr      error
+		wantToken     string
+		wantTokenGets int
+		wantErr       bool
+	}{
+		{name: "refreshed token", cloud: &config.Cloud{}, wantToken: "fresh", wantTokenGets: 1},
+		// failures aren't cached, the next get tries again
+		{name: "no token", cloud: &config.Cloud{}, tokenErr: errors.New("login expired"), wantTokenGets: 2, wantErr: true},
+		{
+			name:      "AK/SK login",
+			cloud:     &config.Cloud{AccessKey: &config.AccessKeyPair{AccessKey: "AK", SecretKey: "SK"}},
+			wantToken: "expired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tokenGets := 0
+			var usedToken string
+			clients := newProjectClients(tt.cloud)
+			clients.scopedToken = func(string) (config.Token, error) {
+				tokenGets++
+				return config.Token{Secret: "fresh"}, tt.tokenErr
+			}
+			clients.newClient = func(_ *config.Cloud, project *config.Project) (*golangsdk.ServiceClient, error) {
+				usedToken = project.ScopedToken.Secret
+				return &golangsdk.ServiceClient{}, nil
+			}
+			for range 2 {
+				if _, err := clients.get(project); (err != nil) != tt.wantErr {
+					t.Fatalf("get() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+			if tokenGets != tt.wantTokenGets {
+				t.Errorf("scoped token requested %d times, want %d", tokenGets, tt.wantTokenGets)
+			}
+			if usedToken != tt.wantToken {
+				t.Errorf("client built with token %q, want %q", usedToken, tt.wantToken)
+			}
+		})
+	}
+}
@@ -10,11 +10,9 @@ import (
 	"log"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"otc-auth/common"
-	"otc-auth/common/endpoints"
 	"otc-auth/config"
 
 	"github.com/golang/glog"
@@ -25,60 +23,71 @@ import (
 	"k8s.io/client-go/tools/clientcmd/api"
 )
 
-func GetClusterNames(projectName string) config.Clusters {
-	clustersArr, err := getClustersForProjectFromServiceProvider(projectName)
-	if err != nil {
-		common.ThrowError(err)
-	}
-
-	config.UpdateClusters(clustersArr)
-	glog.V(common.InfoLogLevel).Infof(
-		"info: CCE clusters for project %s:\n%s",
-		projectName, strings.Join(clustersArr.GetClusterNames(), ",\n"))
-
-	return clustersArr
-}
-
 func GetKubeConfig(configParams KubeConfigParams, skipKubeTLS bool, printKubeConfig bool, alias string) {
 	kubeConfig, err := getKubeConfig(configParams, alias)
 	if err != nil {
 		common.ThrowError(err)
 	}
 
-	if skipKubeTLS || configParams.Server != "" {
-		kubeConfigBkp := kubeConfig
-		for idx := range kubeConfigBkp.Clusters {
-			if skipKubeTLS {
-				kubeConfig.Clusters[idx].InsecureSkipTLSVerify = true
-			}
-			if configParams.Server != "" {
-				kubeConfig.Clusters[idx].Server = configParams.Server
-			}
+	overrideClusters(kubeConfig, skipKubeTLS, configParams.Server)
+
+	if configParams.ExecCredential {
+		activeCloud, errCloud := config.GetActiveCloudConfig()
+		if errCloud != nil {
+			common.ThrowError(errCloud)
 		}
+		useExecCredential(kubeConfig, activeCloud.Domain.Name, configParams)
 	}
 
 	CheckAndWarnCertsValidity(*kubeConfig)
 
+	if err = outputKubeConfig(configParams, *kubeConfig, printKubeConfig); err != nil {
+		common.ThrowError(err)
+	}
 	if printKubeConfig {
-		// Create a configuration file in kubectl-compatible format
-		configBytes, errMarshal := clientcmd.Write(*kubeConfig)
-		if errMarshal != nil {
-			common.ThrowError(errMarshal)
-		}
-		// Output the YAML data to STDOUT, since STDERR already contains log messages
-		_, err = os.Stdout.Write(configBytes)
-		if err != nil {
-			common.ThrowError(errors.New("error writing YAML to STDOUT"))
-		}
 		glog.V(common.InfoLogLevel).Info("info: successfully fetched kube config for cce cluster %s. \n",
 			configParams.ClusterName)
 	} else {
-		mergeKubeConfig(configParams, *kubeConfig)
 		glog.V(common.InfoLogLevel).Infof("info: successfully fetched and Merge kube config for cce cluster %s. \n",
 			configParams.ClusterName)
 	}
 }
 
+func overrideClusters(kubeConfig *api.Config, skipKubeTLS bool, server string) {
+	for idx := range kubeConfig.Clusters {
+		if skipKubeTLS {
+			kubeConfig.Clusters[idx].InsecureSkipTLSVerify = true
+		}
+		if server != "" {
+			kubeConfig.Clusters[idx].Server = server
+		}
+	}
+}
+
+// outputKubeConfig prints the kube config or merges it into the one at the
+// target location, or into one file per cluster with the split layout.
+func outputKubeConfig(configParams KubeConfigParams, kubeConfig api.Config, printKubeConfig bool) error {
+	if !printKubeConfig && configParams.Layout == KubeConfigLayoutSplit {
+		_, err := writeSplitKubeConfigs(configParams.PathTemplate, kubeConfig)
+		return err
+	}
+	if !printKubeConfig {
+		mergeKubeConfig(configParams, kubeConfig)
+		return nil
+	}
+	// Create a configuration file in kubectl-compatible format
+	configBytes, err := clientcmd.Write(kubeConfig)
+	if err != nil {
+		return err
+	}
+	// Output the YAML data to STDOUT, since STDERR already contains log messages
+	_, err = os.Stdout.Write(configBytes)
+	if err != nil {
+		return errors.New("error writing YAML to STDOUT")
+	}
+	return nil
+}
+
 func CheckAndWarnCertsValidity(kubeConfig api.Config) {
 	var certs []*x509.Certificate
 	issueFound := false
@@ -174,6 +183,22 @@ func sprintCertInfo(cert *x509.Certificate) string {
 }
 
 func listClusters(client *golangsdk.ServiceClient) (config.Clusters, error) {
+	items, err := listClusterItems(client)
+	if err != nil {
+		return nil, err
+	}
+
+	var clustersArr config.Clusters
+	for _, item := range items {
+		clustersArr = append(clustersArr, config.Cluster{
+			Name: item.Metadata.Name,
+			ID:   item.Metadata.UID,
+		})
+	}
+	return clustersArr, nil
+}
+
+func listClusterItems(client *golangsdk.ServiceClient) ([]cceClusterItem, error) {
 	// GET /api/v3/projects/{project_id}/clusters
 	raw, err := client.Get(client.ServiceURL("clusters"), nil, &golangsdk.RequestOpts{
 		OkCodes:     []int{200},
@@ -191,15 +216,7 @@ func listClusters(client *golangsdk.ServiceClient) (config.Clusters, error) {
 	if err != nil {
 		return nil, fmt.Errorf("couldn't decode cluster list: %w", err)
 	}
-
-	var clustersArr config.Clusters
-	for _, item := range res.Items {
-		clustersArr = append(clustersArr, config.Cluster{
-			Name: item.Metadata.Name,
-			ID:   item.Metadata.UID,
-		})
-	}
-	return clustersArr, nil
+	return res.Items, nil
 }
 
 func getClustersForProjectFromServiceProvider(projectName string) (config.Clusters, error) {
@@ -211,12 +228,7 @@ func getClustersForProjectFromServiceProvider(projectName string) (config.Cluste
 	if err != nil {
 		common.ThrowError(err)
 	}
-	provider, err := openstack.AuthenticatedClient(golangsdk.AuthOptions{
-		IdentityEndpoint: endpoints.BaseURLIam(activeCloud.Region),
-		DomainID:         activeCloud.Domain.ID,
-		TokenID:          project.ScopedToken.Secret,
-		TenantID:         project.ID,
-	})
+	provider, err := openstack.AuthenticatedClient(activeCloud.AuthOptions(project))
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get provider: %w", err)
 	}
@@ -227,9 +239,42 @@ func getClustersForProjectFromServiceProvider(projectName string) (config.Cluste
 	return listClusters(client)
 }
 
-func getKubeConfFromServiceProvider(kubeConfigParams KubeConfigParams,
+// kubeConfigFromCert turns the certificate of the cluster into kube config
+// entries, named and tagged for otc-auth.
+func kubeConfigFromCert(kubeConfigParams KubeConfigParams, cert *clusters.Certificate,
 	clusterID string, alias string,
 ) (*api.Config, error) {
+	rawConfig, err := certToKubeConfig(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get active cloud: %w", err)
+	}
+	tag := kubeConfigTag{
+		Domain:    activeCloud.Domain.Name,
+		Project:   kubeConfigParams.ProjectName,
+		Cluster:   kubeConfigParams.ClusterName,
+		ClusterID: clusterID,
+	}
+	if alias != "" {
+		kubeConfigParams.ContextTemplate = alias
+	}
+	contextName, userName := kubeConfigEntryNames(kubeConfigParams, tag, activeCloud.Username)
+	renameKubeconfigEntries(rawConfig, contextName, userName)
+	if err = applyKubeConfigOptions(rawConfig, kubeConfigParams, contextName); err != nil {
+		return nil, err
+	}
+	err = tagKubeConfigEntries(rawConfig, tag)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't tag entries: %w", err)
+	}
+	return rawConfig, nil
+}
+
+func getCertFromServiceProvider(kubeConfigParams KubeConfigParams, clusterID string) (*clusters.Certificate, error) {
 	activeCloud, err := config.GetActiveCloudConfig()
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get active cloud: %w", err)
@@ -238,12 +283,15 @@ func getKubeConfFromServiceProvider(kubeConfigParams KubeConfigParams,
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get project %s: %w", kubeConfigParams.ProjectName, err)
 	}
-	provider, err := openstack.AuthenticatedClient(golangsdk.AuthOptions{
-		IdentityEndpoint: endpoints.BaseURLIam(activeCloud.Region),
-		DomainID:         activeCloud.Domain.ID,
-		TokenID:          project.ScopedToken.Secret,
-		TenantID:         project.ID,
-	})
+	client, err := newCCEClient(activeCloud, project)
+	if err != nil {
+		return nil, err
+	}
+	return getCert(client, clusterID, kubeConfigParams.DaysValid)
+}
+
+func newCCEClient(activeCloud *config.Cloud, project *config.Project) (*golangsdk.ServiceClient, error) {
+	provider, err := openstack.AuthenticatedClient(activeCloud.AuthOptions(project))
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get new openstack client: %w", err)
 	}
@@ -251,9 +299,13 @@ func getKubeConfFromServiceProvider(kubeConfigParams KubeConfigParams,
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get new cce client: %w", err)
 	}
+	return client, nil
+}
 
+func getCert(client *golangsdk.ServiceClient, clusterID string, daysValid string) (*clusters.Certificate, error) {
 	var expOpts clusters.ExpirationOpts
-	expOpts.Duration, err = strconv.Atoi(kubeConfigParams.DaysValid)
+	var err error
+	expOpts.Duration, err = strconv.Atoi(daysValid)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't convert string to int: %w", err)
 	}
@@ -261,17 +313,7 @@ func getKubeConfFromServiceProvider(kubeConfigParams KubeConfigParams,
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get cert: %w", err)
 	}
-
-	rawConfig, err := certToKubeConfig(cert)
-	if err != nil {
-		return nil, err
-	}
-
-	err = renameKubeconfigEntries(rawConfig, kubeConfigParams.ProjectName, kubeConfigParams.ClusterName, alias)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't rename entries: %w", err)
-	}
-	return rawConfig, nil
+	return cert, nil
 }
 
 func decodeB64(encoded, description, name string) ([]byte, error) {
@@ -334,29 +376,69 @@ func certToKubeConfig(cert *clusters.Certificate) (*api.Config, error) {
 	return &rawConfig, nil
 }
 
-func getClusterID(clusterName string, projectName string) (clusterID string, err error) {
+func getClusterID(clusterName string, projectName string, refresh bool) (clusterID string, err error) {
 	activeCloud, err := config.GetActiveCloudConfig()
 	if err != nil {
-		common.ThrowError(err)
+		return "", err
+	}
+	return resolveClusterID(activeCloud, projectName, clusterName, refresh, time.Now(),
+		func() (config.Clusters, error) {
+			clusterArr, errFetch := getClustersForProjectFromServiceProvider(projectName)
+			if errFetch != nil {
+				return nil, errFetch
+			}
+			config.UpdateClusterCache(projectName, clusterArr)
+			return clusterArr, nil
+		})
+}
+
+// certOfCluster fetches the certificate of the cluster. A cluster deleted and
+// recreated under the same name has a new ID, so if the cached ID isn't found
+// the clusters of the project are fetched again and the new ID is tried once.
+func certOfCluster(refresh bool, lookupID func(refresh bool) (string, error),
+	fetchCert func(clusterID string) (*clusters.Certificate, error),
+) (*clusters.Certificate, error) {
+	clusterID, err := lookupID(refresh)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: error receiving cluster id: %w", err)
+	}
+	cert, err := fetchCert(clusterID)
+	var notFound golangsdk.ErrDefault404
+	if err == nil || refresh || !errors.As(err, &notFound) {
+		return cert, err
 	}
 
-	clusterArr, err := getClustersForProjectFromServiceProvider(projectName)
+	glog.V(common.InfoLogLevel).Infof("info: cluster %s wasn't found, looking up its id again", clusterID)
+	clusterID, err = lookupID(true)
 	if err != nil {
-		common.ThrowError(err)
+		return nil, fmt.Errorf("fatal: error receiving cluster id: %w", err)
 	}
+	return fetchCert(clusterID)
+}
 
-	if !activeCloud.Clusters.ContainsClusterByName(clusterName) {
-		config.UpdateClusters(clusterArr)
-		activeCloud, err = config.GetActiveCloudConfig()
-		if err != nil {
-			common.ThrowError(err)
+// resolveClusterID looks the cluster up in the cache of the project. The
+// clusters of the project are only fetched if the cache is stale, doesn't
+// know the cluster or refresh is set. Names are never resolved in other
+// projects.
+func resolveClusterID(activeCloud *config.Cloud, projectName string, clusterName string, refresh bool,
+	now time.Time, fetch func() (config.Clusters, error),
+) (string, error) {
+	cache := activeCloud.ClusterCaches.FindClusterCache(projectName, activeCloud.Region)
+	if !refresh && cache != nil && cache.IsFresh(now) {
+		if cluster := cache.Clusters.FindClusterByName(clusterName); cluster != nil {
+			glog.V(common.DebugLogLevel).Infof("using cached id of cluster %s from %s",
+				clusterName, cache.FetchedAt.Format(common.PrintTimeFormat))
+			return cluster.ID, nil
 		}
 	}
 
-	cluster, err := activeCloud.Clusters.GetClusterByName(clusterName)
+	clusterArr, err := fetch()
 	if err != nil {
 		return "", err
 	}
-
+	cluster, err := clusterArr.GetClusterByName(clusterName)
+	if err != nil {
+		return "", fmt.Errorf("couldn't find cluster %s in project %s: %w", clusterName, projectName, err)
+	}
 	return cluster.ID, nil
 }
@@ -3,9 +3,15 @@ package cce
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
+	"otc-auth/config"
+
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
 	"github.com/opentelekomcloud/gophertelekomcloud/openstack/cce/v3/clusters"
 	"k8s.io/client-go/tools/clientcmd/api"
 )
@@ -117,3 +123,121 @@ func Test_certToKubeConfig_coversAllSDKFields(t *testing.T) {
 		}
 	}
 }
+
+func Test_resolveClusterID(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	cloud := &config.Cloud{
+		Region: "eu-de",
+		ClusterCaches: config.ClusterCaches{
+			{
+				Project: "eu-de_a", Region: "eu-de", FetchedAt: now.Add(-time.Hour),
+				Clusters: config.Clusters{{Name: "shared-name", ID: "cached-a"}},
+			},
+			{
+				Project: "eu-de_b", Region: "eu-de", FetchedAt: now.Add(-time.Hour),
+				Clusters: config.Clusters{{Name: "only-in-b", ID: "cached-b"}},
+			},
+			{
+				Project: "eu-de_stale", Region: "eu-de", FetchedAt: now.Add(-config.ClusterCacheTTL - time.Hour),
+				Clusters: config.Clusters{{Name: "shared-name", ID: "stale"}},
+			},
+		},
+	}
+	fetched := config.Clusters{{Name: "shared-name", ID: "fetched"}}
+
+	tests := []struct {
+		name      string
+		project   string
+		cluster   string
+		refresh   bool
+		want      string
+		wantFetch bool
+		wantErr   bool
+	}{
+		{name: "fresh cache", project: "eu-de_a", cluster: "shared-name", want: "cached-a"},
+		{name: "refresh", project: "eu-de_a", cluster: "shared-name", refresh: true, want: "fetched", wantFetch: true},
+		{name: "stale cache", project: "eu-de_stale", cluster: "shared-name", want: "fetched", wantFetch: true},
+		{name: "no cache", project: "eu-de_new", cluster: "shared-name", want: "fetched", wantFetch: true},
+		{
+			name: "cluster of another project", project: "eu-de_a", cluster: "only-in-b",
+			wantFetch: true, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			didFetch := false
+			got, err := resolveClusterID(cloud, tt.project, tt.cluster, tt.refresh, now,
+				func() (config.Clusters, error) {
+					didFetch = true
+					return fetched, nil
+				})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveClusterID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveClusterID() = %s, want %s", got, tt.want)
+			}
+			if didFetch != tt.wantFetch {
+				t.Errorf("resolveClusterID() fetched = %v, want %v", didFetch, tt.wantFetch)
+			}
+		})
+	}
+}
+
+func Test_certOfCluster(t *testing.T) {
+	t.Parallel()
+	notFound := fmt.Errorf("couldn't get cert: %w", golangsdk.ErrDefault404{})
+	tests := []struct {
+		name        string
+		refresh     bool
+		certs       map[string]error
+		wantLookups []bool
+		wantErr     bool
+	}{
+		{name: "cached id", certs: map[string]error{"cached": nil}, wantLookups: []bool{false}},
+		{
+			name: "recreated cluster", certs: map[string]error{"cached": notFound, "fetched": nil},
+			wantLookups: []bool{false, true},
+		},
+		{
+			name: "deleted cluster", certs: map[string]error{"cached": notFound, "fetched": notFound},
+			wantLookups: []bool{false, true}, wantErr: true,
+		},
+		{
+			name: "other error", certs: map[string]error{"cached": errors.New("forbidden")},
+			wantLookups: []bool{false}, wantErr: true,
+		},
+		{
+			name: "already refreshed", refresh: true, certs: map[string]error{"fetched": notFound},
+			wantLookups: []bool{true}, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var lookups []bool
+			cert, err := certOfCluster(tt.refresh,
+				func(refresh bool) (string, error) {
+					lookups = append(lookups, refresh)
+					if refresh {
+						return "fetched", nil
+					}
+					return "cached", nil
+				},
+				func(clusterID string) (*clusters.Certificate, error) {
+					if certErr := tt.certs[clusterID]; certErr != nil {
+						return nil, certErr
+					}
+					return &clusters.Certificate{}, nil
+				})
+			if (err != nil) != tt.wantErr || (err == nil && cert == nil) {
+				t.Errorf("certOfCluster() = %v, %v, wantErr %v", cert, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(lookups, tt.wantLookups) {
+				t.Errorf("lookups with refresh = %v, want %v", lookups, tt.wantLookups)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package cce
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"otc-auth/common"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Status of a certificate in the report of check-kube-certs.
+const (
+	CertStatusOK          = "ok"
+	CertStatusExpiring    = "expiring"
+	CertStatusExpired     = "expired"
+	CertStatusNotYetValid = "not-yet-valid"
+	CertStatusInvalid     = "invalid"
+)
+
+// Exit codes of check-kube-certs. 2 is taken by common.ThrowError, so a
+// monitoring check can tell a failed run from a bad certificate.
+const (
+	CertExitCodeExpiring = 3
+	CertExitCodeExpired  = 4
+)
+
+// Output formats of check-kube-certs.
+const (
+	CertReportFormatTable = "table"
+	CertReportFormatJSON  = "json"
+	CertReportFormatYAML  = "yaml"
+)
+
+// CertReportFormats returns all formats WriteCertReports understands.
+func CertReportFormats() []string {
+	return []string{CertReportFormatTable, CertReportFormatJSON, CertReportFormatYAML}
+}
+
+// CertReportParams configures CheckKubeCerts.
+type CertReportParams struct {
+	// Paths are kube config files or directories, the default loading rules
+	// apply if empty
+	Paths []string
+	// Within marks certificates expiring within this duration as expiring
+	Within time.Duration
+	Format string
+}
+
+// CertInfo describes a single certificate of a kube config entry.
+type CertInfo struct {
+	Subject       string     `json:"subject,omitempty"       yaml:"subject,omitempty"`
+	Issuer        string     `json:"issuer,omitempty"        yaml:"issuer,omitempty"`
+	NotAfter      *time.Time `json:"notAfter,omitempty"      yaml:"notAfter,omitempty"`
+	DaysRemaining int        `json:"daysRemaining"           yaml:"daysRemaining"`
+	Status        string     `json:"status"                  yaml:"status"`
+	Error         string     `json:"error,omitempty"         yaml:"error,omitempty"`
+}
+
+// ContextCertReport holds the certificates a context uses. CA and ClientCert
+// are nil if the entry has none, e.g. for token or exec users.
+type ContextCertReport struct {
+	KubeConfig string    `json:"kubeConfig"           yaml:"kubeConfig"`
+	Context    string    `json:"context"              yaml:"context"`
+	Cluster    string    `json:"cluster"              yaml:"cluster"`
+	User       string    `json:"user"                 yaml:"user"`
+	Status     string    `json:"status"               yaml:"status"`
+	CA         *CertInfo `json:"ca,omitempty"         yaml:"ca,omitempty"`
+	ClientCert *CertInfo `json:"clientCert,omitempty" yaml:"clientCert,omitempty"`
+}
+
+// CheckKubeCerts writes a report of the certificates of every context in the
+// kube configs and returns the exit code matching the worst status found.
+func CheckKubeCerts(params CertReportParams, out io.Writer) int {
+	files, err := findKubeConfigFiles(params.Paths)
+	if err != nil {
+		common.ThrowError(err)
+	}
+	now := time.Now()
+	var reports []ContextCertReport
+	for _, file := range files {
+		kubeConfig, errLoad := clientcmd.LoadFromFile(file)
+		if errLoad != nil {
+			common.ThrowError(fmt.Errorf("fatal: couldn't read kube config %s\ntrace: %w", file, errLoad))
+		}
+		reports = append(reports, buildCertReports(file, kubeConfig, now, params.Within)...)
+	}
+	if err = WriteCertReports(out, reports, params.Format); err != nil {
+		common.ThrowError(err)
+	}
+	return certExitCode(reports)
+}
+
+// findKubeConfigFiles expands the directories among the paths to the kube
+// configs they contain. Without paths the files of the default loading rules
+// ($KUBECONFIG or ~/.kube/config) are returned.
+func findKubeConfigFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		var files []string
+		for _, file := range clientcmd.NewDefaultClientConfigLoadingRules().GetLoadingPrecedence() {
+			if _, err := os.Stat(file); err == nil {
+				files = append(files, file)
+			}
+		}
+		if len(files) == 0 {
+			return nil, errors.New("fatal: no kube config found, pass its path or set KUBECONFIG")
+		}
+		return files, nil
+	}
+
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: couldn't read kube config %s\ntrace: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		found, err := kubeConfigFilesInDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// kubeConfigFilesInDir walks the directory for files named config or ending in
+// .yaml, .yml or .kubeconfig. Hidden directories and kubectl's cache are
+// skipped.
+func kubeConfigFilesInDir(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == "cache") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".kubeconfig":
+			files = append(files, path)
+		default:
+			if entry.Name() == "config" {
+				files = append(files, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fatal: couldn't read kube configs in %s\ntrace: %w", dir, err)
+	}
+	glog.V(common.DebugLogLevel).Infof("kube configs found in %s: %v", dir, files)
+	return files, nil
+}
+
+// buildCertReports reports the CA and client certificate of every context,
+// sorted by context name.
+func buildCertReports(file string, kubeConfig *api.Config, now time.Time, within time.Duration,
+) []ContextCertReport {
+	reports := make([]ContextCertReport, 0, len(kubeConfig.Contexts))
+	for name, context := range kubeConfig.Contexts {
+		report := ContextCertReport{
+			KubeConfig: file,
+			Context:    name,
+			Cluster:    context.Cluster,
+			User:       context.AuthInfo,
+		}
+		if cluster, ok := kubeConfig.Clusters[context.Cluster]; ok {
+			report.CA = certInfoFor(file, cluster.CertificateAuthorityData, cluster.CertificateAuthority,
+				now, within)
+		}
+		if authInfo, ok := kubeConfig.AuthInfos[context.AuthInfo]; ok {
+			report.ClientCert = certInfoFor(file, authInfo.ClientCertificateData, authInfo.ClientCertificate,
+				now, within)
+		}
+		report.Status = CertStatusOK
+		for _, info := range []*CertInfo{report.CA, report.ClientCert} {
+			if info != nil && certSeverity(info.Status) > certSeverity(report.Status) {
+				report.Status = info.Status
+			}
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Context < reports[j].Context
+	})
+	return reports
+}
+
+// certInfoFor describes the certificate given inline or as a file, relative
+// paths are resolved against the kube config. A bundle is described by its
+// certificate expiring first. It returns nil if there is no certificate.
+func certInfoFor(kubeConfigFile string, data []byte, certFile string, now time.Time, within time.Duration,
+) *CertInfo {
+	if len(data) == 0 && certFile != "" {
+		if !filepath.IsAbs(certFile) {
+			certFile = filepath.Join(filepath.Dir(kubeConfigFile), certFile)
+		}
+		var err error
+		data, err = os.ReadFile(certFile)
+		if err != nil {
+			return &CertInfo{Status: CertStatusInvalid, Error: err.Error()}
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var first *x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return &CertInfo{Status: CertStatusInvalid, Error: err.Error()}
+		}
+		if first == nil || cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+	if first == nil {
+		return &CertInfo{Status: CertStatusInvalid, Error: "no PEM encoded certificate found"}
+	}
+
+	notAfter := first.NotAfter.UTC()
+	info := &CertInfo{
+		Subject:       first.Subject.String(),
+		Issuer:        first.Issuer.String(),
+		NotAfter:      &notAfter,
+		DaysRemaining: int(math.Floor(first.NotAfter.Sub(now).Hours() / 24)), //nolint:mnd // hours per day
+		Status:        CertStatusOK,
+	}
+	switch {
+	case now.Before(first.NotBefore):
+		info.Status = CertStatusNotYetValid
+	case !now.Before(first.NotAfter):
+		info.Status = CertStatusExpired
+	case now.Add(within).After(first.NotAfter):
+		info.Status = CertStatusExpiring
+	}
+	return info
+}
+
+// certSeverity orders the statuses, everything that makes a certificate
+// unusable counts as expired.
+func certSeverity(status string) int {
+	switch status {
+	case CertStatusOK:
+		return 0
+	case CertStatusExpiring:
+		return 1
+	default:
+		return 2 //nolint:mnd // worst severity
+	}
+}
+
+func certExitCode(reports []ContextCertReport) int {
+	worst := 0
+	for _, report := range reports {
+		worst = max(worst, certSeverity(report.Status))
+	}
+	switch worst {
+	case 0:
+		return 0
+	case 1:
+		return CertExitCodeExpiring
+	default:
+		return CertExitCodeExpired
+	}
+}
+
+// WriteCertReports writes the reports in one of CertReportFormats.
+func WriteCertReports(w io.Writer, reports []ContextCertReport, format string) error {
+	switch format {
+	case CertReportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case CertReportFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2) //nolint:mnd // the usual yaml indentation
+		if err := encoder.Encode(reports); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case CertReportFormatTable:
+		return writeCertTable(w, reports)
+	default:
+		return fmt.Errorf("fatal: unknown output format %s, use one of %s",
+			format, strings.Join(CertReportFormats(), ", "))
+	}
+}
+
+func writeCertTable(w io.Writer, reports []ContextCertReport) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // padding between columns
+	fmt.Fprintln(table, "CONTEXT\tSTATUS\tCA EXPIRES\tCLIENT SUBJECT\tCLIENT EXPIRES\tKUBECONFIG")
+	for _, report := range reports {
+		clientSubject := "-"
+		if report.ClientCert != nil {
+			clientSubject = orDash(report.ClientCert.Subject)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			report.Context, report.Status, certInfoText(report.CA), clientSubject,
+			certInfoText(report.ClientCert), report.KubeConfig)
+	}
+	return table.Flush()
+}
+
+func certInfoText(info *CertInfo) string {
+	switch {
+	case info == nil:
+		return "-"
+	case info.Status == CertStatusInvalid:
+		return CertStatusInvalid
+	default:
+		return fmt.Sprintf("%s (%dd, %s)", info.NotAfter.Format(time.DateTime), info.DaysRemaining, info.Status)
+	}
+}
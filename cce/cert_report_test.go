@@ -0,0 +1,135 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_buildCertReports(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, selfSignedCertPEM(t, now.Add(365*24*time.Hour)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kubeConfig := &api.Config{
+		Clusters: map[string]*api.Cluster{
+			"cluster":  {CertificateAuthority: "ca.crt"},
+			"insecure": {InsecureSkipTLSVerify: true},
+		},
+		AuthInfos: map[string]*api.AuthInfo{
+			"valid":    {ClientCertificateData: selfSignedCertPEM(t, now.Add(30*24*time.Hour))},
+			"expiring": {ClientCertificateData: selfSignedCertPEM(t, now.Add(36*time.Hour))},
+			"expired":  {ClientCertificateData: selfSignedCertPEM(t, now.Add(-36*time.Hour))},
+			"future":   {ClientCertificateData: selfSignedCertPEM(t, now.Add(3*365*24*time.Hour))},
+			"broken":   {ClientCertificateData: []byte("not a certificate")},
+			"token":    {Token: "token"},
+		},
+		Contexts: map[string]*api.Context{
+			"valid":    {Cluster: "cluster", AuthInfo: "valid"},
+			"expiring": {Cluster: "cluster", AuthInfo: "expiring"},
+			"expired":  {Cluster: "cluster", AuthInfo: "expired"},
+			"future":   {Cluster: "cluster", AuthInfo: "future"},
+			"broken":   {Cluster: "cluster", AuthInfo: "broken"},
+			"token":    {Cluster: "insecure", AuthInfo: "token"},
+		},
+	}
+
+	reports := buildCertReports(filepath.Join(dir, "config"), kubeConfig, now, 7*24*time.Hour)
+
+	type summary struct {
+		context, status, clientStatus string
+		clientDays                    int
+	}
+	var got []summary
+	for _, report := range reports {
+		s := summary{context: report.Context, status: report.Status}
+		if report.ClientCert != nil {
+			s.clientStatus = report.ClientCert.Status
+			s.clientDays = report.ClientCert.DaysRemaining
+		}
+		got = append(got, s)
+	}
+	want := []summary{
+		{context: "broken", status: CertStatusInvalid, clientStatus: CertStatusInvalid},
+		{context: "expired", status: CertStatusExpired, clientStatus: CertStatusExpired, clientDays: -2},
+		{context: "expiring", status: CertStatusExpiring, clientStatus: CertStatusExpiring, clientDays: 1},
+		{context: "future", status: CertStatusNotYetValid, clientStatus: CertStatusNotYetValid, clientDays: 1095},
+		{context: "token", status: CertStatusOK},
+		{context: "valid", status: CertStatusOK, clientStatus: CertStatusOK, clientDays: 30},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildCertReports() = %+v, want %+v", got, want)
+	}
+
+	ca := reports[0].CA
+	if ca == nil || ca.Status != CertStatusOK || ca.Subject != "CN=user" || ca.DaysRemaining != 365 {
+		t.Errorf("CA read from the relative path = %+v, want a valid certificate", ca)
+	}
+	if reports[4].CA != nil {
+		t.Errorf("CA of a cluster without one = %+v, want nil", reports[4].CA)
+	}
+}
+
+func Test_certExitCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		statuses []string
+		want     int
+	}{
+		{name: "no contexts", want: 0},
+		{name: "all ok", statuses: []string{CertStatusOK, CertStatusOK}, want: 0},
+		{name: "expiring", statuses: []string{CertStatusOK, CertStatusExpiring}, want: CertExitCodeExpiring},
+		{name: "expired", statuses: []string{CertStatusExpired, CertStatusExpiring}, want: CertExitCodeExpired},
+		{name: "invalid", statuses: []string{CertStatusInvalid}, want: CertExitCodeExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			reports := make([]ContextCertReport, 0, len(tt.statuses))
+			for _, status := range tt.statuses {
+				reports = append(reports, ContextCertReport{Status: status})
+			}
+			if got := certExitCode(reports); got != tt.want {
+				t.Errorf("certExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_kubeConfigFilesInDir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	for _, name := range []string{
+		"config", "README.md", "otc/d/p/c.yaml", "otc/d/p/c.yml", "cache/discovery/servers.yaml", ".hidden/x.yaml",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := kubeConfigFilesInDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "config"),
+		filepath.Join(dir, "otc/d/p/c.yaml"),
+		filepath.Join(dir, "otc/d/p/c.yml"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kubeConfigFilesInDir() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+package cce
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"otc-auth/common"
+	"otc-auth/config"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Output formats of cce list.
+const (
+	ClusterListFormatTable = "table"
+	ClusterListFormatJSON  = "json"
+	ClusterListFormatYAML  = "yaml"
+	ClusterListFormatName  = "name"
+)
+
+// ClusterListFormats returns all formats WriteClusterInfos understands.
+func ClusterListFormats() []string {
+	return []string{
+		ClusterListFormatTable, ClusterListFormatJSON, ClusterListFormatYAML, ClusterListFormatName,
+	}
+}
+
+// ClusterInfo describes a CCE cluster and how it is set up in the local kube
+// config.
+type ClusterInfo struct {
+	Name             string     `json:"name"                       yaml:"name"`
+	ID               string     `json:"id"                         yaml:"id"`
+	Status           string     `json:"status"                     yaml:"status"`
+	Version          string     `json:"version"                    yaml:"version"`
+	Flavor           string     `json:"flavor"                     yaml:"flavor"`
+	Type             string     `json:"type"                       yaml:"type"`
+	CreatedAt        string     `json:"createdAt"                  yaml:"createdAt"`
+	InternalEndpoint string     `json:"internalEndpoint,omitempty" yaml:"internalEndpoint,omitempty"`
+	ExternalEndpoint string     `json:"externalEndpoint,omitempty" yaml:"externalEndpoint,omitempty"`
+	KubeContext      string     `json:"kubeContext,omitempty"      yaml:"kubeContext,omitempty"`
+	CertExpiresAt    *time.Time `json:"certExpiresAt,omitempty"    yaml:"certExpiresAt,omitempty"`
+}
+
+// ListClusters returns the clusters of a project with their details. Clusters
+// already in the default kube config get the matching context and the expiry
+// of its client certificate.
+func ListClusters(projectName string) []ClusterInfo {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		common.ThrowError(err)
+	}
+	project, err := activeCloud.Projects.GetProjectByName(projectName)
+	if err != nil {
+		common.ThrowError(err)
+	}
+	client, err := newCCEClient(activeCloud, project)
+	if err != nil {
+		common.ThrowError(err)
+	}
+	items, err := listClusterItems(client)
+	if err != nil {
+		common.ThrowError(fmt.Errorf("fatal: couldn't list clusters of project %s\ntrace: %w", projectName, err))
+	}
+
+	infos := make([]ClusterInfo, 0, len(items))
+	var clustersArr config.Clusters
+	for _, item := range items {
+		infos = append(infos, newClusterInfo(item))
+		clustersArr = append(clustersArr, config.Cluster{Name: item.Metadata.Name, ID: item.Metadata.UID})
+	}
+	config.UpdateClusterCache(projectName, clustersArr)
+	glog.V(common.InfoLogLevel).Infof(
+		"info: CCE clusters for project %s:\n%s",
+		projectName, strings.Join(clustersArr.GetClusterNames(), ",\n"))
+
+	kubeConfig, err := clientcmd.NewDefaultClientConfigLoadingRules().GetStartingConfig()
+	if err != nil {
+		glog.Warningf("warning: couldn't read kube config, contexts are not shown: %s", err)
+		return infos
+	}
+	addKubeConfigDetails(infos, kubeConfig, activeCloud.Domain.Name, projectName)
+	return infos
+}
+
+func newClusterInfo(item cceClusterItem) ClusterInfo {
+	info := ClusterInfo{
+		Name:      item.Metadata.Name,
+		ID:        item.Metadata.UID,
+		Status:    item.Status.Phase,
+		Version:   item.Spec.Version,
+		Flavor:    item.Spec.Flavor,
+		Type:      item.Spec.Type,
+		CreatedAt: item.Metadata.CreationTimestamp,
+	}
+	for _, endpoint := range item.Status.Endpoints {
+		// OTC reports the public endpoint as "External" or "external_otc"
+		switch {
+		case strings.EqualFold(endpoint.Type, "Internal"):
+			info.InternalEndpoint = endpoint.URL
+		case strings.HasPrefix(strings.ToLower(endpoint.Type), "external"):
+			info.ExternalEndpoint = endpoint.URL
+		}
+	}
+	return info
+}
+
+// addKubeConfigDetails looks for a context of every cluster. A context
+// matches if its cluster points to one of the cluster's endpoints, or if it
+// has the default name of get-kube-config.
+func addKubeConfigDetails(infos []ClusterInfo, kubeConfig *api.Config, domainName string, projectName string) {
+	contextNames := make([]string, 0, len(kubeConfig.Contexts))
+	for name := range kubeConfig.Contexts {
+		contextNames = append(contextNames, name)
+	}
+	sort.Strings(contextNames)
+
+	for i := range infos {
+		contextName := findContext(kubeConfig, contextNames, infos[i], projectName)
+		if contextName == "" {
+			continue
+		}
+		infos[i].KubeContext = contextName
+		authInfo, ok := kubeConfig.AuthInfos[kubeConfig.Contexts[contextName].AuthInfo]
+		if !ok {
+			continue
+		}
+		infos[i].CertExpiresAt = certExpiry(authInfo, domainName, projectName, infos[i].Name)
+	}
+}
+
+func findContext(kubeConfig *api.Config, contextNames []string, info ClusterInfo, projectName string) string {
+	// prefer the external endpoint, the intranet context is the second choice
+	for _, endpoint := range []string{info.ExternalEndpoint, info.InternalEndpoint} {
+		if endpoint == "" {
+			continue
+		}
+		for _, name := range contextNames {
+			cluster, ok := kubeConfig.Clusters[kubeConfig.Contexts[name].Cluster]
+			if ok && cluster.Server == endpoint {
+				return name
+			}
+		}
+	}
+	defaultName := fmt.Sprintf("%s/%s", projectName, info.Name)
+	if _, ok := kubeConfig.Contexts[defaultName]; ok {
+		return defaultName
+	}
+	return ""
+}
+
+// certExpiry reads the expiry of a static client certificate, or of the
+// cached one for exec entries written with --exec-credential.
+func certExpiry(authInfo *api.AuthInfo, domainName string, projectName string, clusterName string) *time.Time {
+	if len(authInfo.ClientCertificateData) > 0 {
+		notAfter, err := certificateNotAfter(authInfo.ClientCertificateData)
+		if err != nil {
+			glog.Warningf("warning: %s", err)
+			return nil
+		}
+		return &notAfter
+	}
+	if authInfo.Exec != nil && authInfo.Exec.Command == execCredentialCommand {
+		cached, err := readExecCredentialCache(execCredentialCachePath(domainName, projectName, clusterName))
+		if err != nil || cached == nil {
+			return nil
+		}
+		return &cached.ExpirationTimestamp
+	}
+	return nil
+}
+
+// WriteClusterInfos writes the clusters in one of ClusterListFormats.
+func WriteClusterInfos(w io.Writer, infos []ClusterInfo, format string) error {
+	switch format {
+	case ClusterListFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	case ClusterListFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2) //nolint:mnd // the usual yaml indentation
+		if err := encoder.Encode(infos); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case ClusterListFormatName:
+		for _, info := range infos {
+			if _, err := fmt.Fprintln(w, info.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ClusterListFormatTable:
+		return writeClusterTable(w, infos, time.Now())
+	default:
+		return fmt.Errorf("fatal: unknown output format %s, use one of %s",
+			format, strings.Join(ClusterListFormats(), ", "))
+	}
+}
+
+func writeClusterTable(w io.Writer, infos []ClusterInfo, now time.Time) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // padding between columns
+	fmt.Fprintln(table, "NAME\tSTATUS\tVERSION\tFLAVOR\tTYPE\tCREATED\tINTERNAL\tEXTERNAL\tCONTEXT\tCERT EXPIRES")
+	for _, info := range infos {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			info.Name, info.Status, info.Version, info.Flavor, info.Type, info.CreatedAt,
+			yesNo(info.InternalEndpoint != ""), yesNo(info.ExternalEndpoint != ""),
+			orDash(info.KubeContext), certExpiryText(info.CertExpiresAt, now))
+	}
+	return table.Flush()
+}
+
+func certExpiryText(expiresAt *time.Time, now time.Time) string {
+	switch {
+	case expiresAt == nil:
+		return "-"
+	case expiresAt.Before(now):
+		return expiresAt.Format(time.DateTime) + " (expired)"
+	default:
+		return expiresAt.Format(time.DateTime)
+	}
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,146 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const clusterItemJSON = `{
+	"metadata": {"name": "prod", "uid": "c-1", "creationTimestamp": "2024-03-01 10:00:00.123 +0000 UTC"},
+	"spec": {"type": "VirtualMachine", "flavor": "cce.s2.small", "version": "v1.29"},
+	"status": {
+		"phase": "Available",
+		"endpoints": [
+			{"url": "https://192.168.0.10:5443", "type": "Internal"},
+			{"url": "https://80.158.1.1:5443", "type": "external_otc"}
+		]
+	}
+}`
+
+func Test_newClusterInfo(t *testing.T) {
+	t.Parallel()
+	var item cceClusterItem
+	if err := json.Unmarshal([]byte(clusterItemJSON), &item); err != nil {
+		t.Fatal(err)
+	}
+
+	got := newClusterInfo(item)
+	want := ClusterInfo{
+		Name:             "prod",
+		ID:               "c-1",
+		Status:           "Available",
+		Version:          "v1.29",
+		Flavor:           "cce.s2.small",
+		Type:             "VirtualMachine",
+		CreatedAt:        "2024-03-01 10:00:00.123 +0000 UTC",
+		InternalEndpoint: "https://192.168.0.10:5443",
+		ExternalEndpoint: "https://80.158.1.1:5443",
+	}
+	if got != want {
+		t.Errorf("newClusterInfo() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_addKubeConfigDetails(t *testing.T) {
+	t.Parallel()
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	kubeConfig := &api.Config{
+		Clusters: map[string]*api.Cluster{
+			"my-alias":          {Server: "https://80.158.1.1:5443"},
+			"my-alias-intranet": {Server: "https://192.168.0.10:5443"},
+			"p/dev":             {Server: "https://changed-by-server-flag"},
+		},
+		AuthInfos: map[string]*api.AuthInfo{
+			"p-prod-user": {ClientCertificateData: selfSignedCertPEM(t, notAfter)},
+			"p-dev-user":  {Token: "no certificate"},
+		},
+		Contexts: map[string]*api.Context{
+			"my-alias":          {Cluster: "my-alias", AuthInfo: "p-prod-user"},
+			"my-alias-intranet": {Cluster: "my-alias-intranet", AuthInfo: "p-prod-user"},
+			"p/dev":             {Cluster: "p/dev", AuthInfo: "p-dev-user"},
+		},
+	}
+	infos := []ClusterInfo{
+		{Name: "prod", InternalEndpoint: "https://192.168.0.10:5443", ExternalEndpoint: "https://80.158.1.1:5443"},
+		{Name: "dev", ExternalEndpoint: "https://80.158.2.2:5443"},
+		{Name: "new", ExternalEndpoint: "https://80.158.3.3:5443"},
+	}
+
+	addKubeConfigDetails(infos, kubeConfig, "domain", "p")
+
+	if infos[0].KubeContext != "my-alias" {
+		t.Errorf("context of prod = %q, want my-alias", infos[0].KubeContext)
+	}
+	if infos[0].CertExpiresAt == nil || !infos[0].CertExpiresAt.Equal(notAfter) {
+		t.Errorf("cert expiry of prod = %v, want %v", infos[0].CertExpiresAt, notAfter)
+	}
+	if infos[1].KubeContext != "p/dev" || infos[1].CertExpiresAt != nil {
+		t.Errorf("dev = %+v, want context p/dev without expiry", infos[1])
+	}
+	if infos[2].KubeContext != "" {
+		t.Errorf("context of new = %q, want none", infos[2].KubeContext)
+	}
+}
+
+func TestWriteClusterInfos(t *testing.T) {
+	t.Parallel()
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	infos := []ClusterInfo{
+		{
+			Name: "prod", Status: "Available", Version: "v1.29", ExternalEndpoint: "https://80.158.1.1:5443",
+			KubeContext: "p/prod", CertExpiresAt: &expiresAt,
+		},
+		{Name: "dev", Status: "Unavailable"},
+	}
+	tests := []struct {
+		format  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			format: ClusterListFormatTable,
+			want: []string{
+				"NAME", "CERT EXPIRES",
+				"prod  Available", "p/prod", "2030-01-02 03:04:05",
+				"dev   Unavailable",
+			},
+		},
+		{format: ClusterListFormatJSON, want: []string{`"name": "prod"`, `"certExpiresAt": "2030-01-02T03:04:05Z"`}},
+		{format: ClusterListFormatYAML, want: []string{"- name: prod", "  kubeContext: p/prod"}},
+		{format: ClusterListFormatName, want: []string{"prod\ndev\n"}},
+		{format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+			var buffer bytes.Buffer
+			err := WriteClusterInfos(&buffer, infos, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteClusterInfos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buffer.String(), want) {
+					t.Errorf("WriteClusterInfos() output misses %q:\n%s", want, buffer.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_certExpiryText(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	if got := certExpiryText(nil, now); got != "-" {
+		t.Errorf("certExpiryText(nil) = %q, want -", got)
+	}
+	if got := certExpiryText(&past, now); got != "2024-12-31 23:00:00 (expired)" {
+		t.Errorf("certExpiryText(past) = %q", got)
+	}
+}
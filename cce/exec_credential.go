@@ -0,0 +1,241 @@
+package cce
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"otc-auth/common"
+	"otc-auth/config"
+
+	"github.com/golang/glog"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/cce/v3/clusters"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/homedir"
+)
+
+const (
+	execCredentialKind           = "ExecCredential"
+	execCredentialAPIVersion     = "client.authentication.k8s.io/v1"
+	execCredentialBetaAPIVersion = "client.authentication.k8s.io/v1beta1"
+	execInfoEnv                  = "KUBERNETES_EXEC_INFO"
+	execCredentialCommand        = "otc-auth"
+	execCredentialInstallHint    = "otc-auth is required to authenticate to this cluster.\n" +
+		"See https://github.com/iits-consulting/otc-auth"
+	execCredentialRenewBefore     = 10 * time.Minute
+	execCredentialCacheFileAccess = 0o600
+	execCredentialCacheDirAccess  = 0o700
+)
+
+// execCredential is the ExecCredential object of the
+// client.authentication.k8s.io API, kubectl reads it from stdout.
+type execCredential struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Status     *execCredentialStatus `json:"status,omitempty"`
+}
+
+type execCredentialStatus struct {
+	ClientCertificateData string    `json:"clientCertificateData"`
+	ClientKeyData         string    `json:"clientKeyData"`
+	ExpirationTimestamp   time.Time `json:"expirationTimestamp"`
+}
+
+// GetExecCredential prints the client certificate of a CCE cluster as an
+// ExecCredential for kubectl. The certificate is cached and only fetched
+// again shortly before it expires.
+func GetExecCredential(configParams KubeConfigParams, out io.Writer) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		common.ThrowError(err)
+	}
+
+	cachePath := execCredentialCachePath(activeCloud.Domain.Name, configParams.ProjectName, configParams.ClusterName)
+	status, err := loadOrFetchExecCredential(cachePath, time.Now(), func() (*execCredentialStatus, error) {
+		return fetchExecCredential(configParams)
+	})
+	if err != nil {
+		common.ThrowError(err)
+	}
+
+	output, err := json.Marshal(newExecCredential(os.Getenv(execInfoEnv), status))
+	if err != nil {
+		common.ThrowError(fmt.Errorf("fatal: couldn't marshal exec credential\ntrace: %w", err))
+	}
+	// kubectl reads the credential from stdout, everything else goes to stderr
+	if _, err = fmt.Fprintln(out, string(output)); err != nil {
+		common.ThrowError(fmt.Errorf("fatal: couldn't write exec credential\ntrace: %w", err))
+	}
+}
+
+// useExecCredential replaces the static client certificates of a fetched kube
+// config with an exec entry, so kubectl asks otc-auth for a certificate
+// whenever it needs one.
+func useExecCredential(kubeConfig *api.Config, domainName string, configParams KubeConfigParams) {
+	for name, authInfo := range kubeConfig.AuthInfos {
+		kubeConfig.AuthInfos[name] = &api.AuthInfo{
+			Extensions: authInfo.Extensions,
+			Exec: &api.ExecConfig{
+				APIVersion: execCredentialAPIVersion,
+				Command:    execCredentialCommand,
+				Args: []string{
+					"cce", "exec-credential",
+					"--os-domain-name", domainName,
+					"--os-project-name", configParams.ProjectName,
+					"--cluster", configParams.ClusterName,
+					"--days-valid", configParams.DaysValid,
+				},
+				InstallHint:     execCredentialInstallHint,
+				InteractiveMode: api.NeverExecInteractiveMode,
+			},
+		}
+	}
+}
+
+func fetchExecCredential(configParams KubeConfigParams) (*execCredentialStatus, error) {
+	glog.V(common.InfoLogLevel).Infof("info: fetching client certificate for cce cluster %s...",
+		configParams.ClusterName)
+
+	cert, err := certOfCluster(configParams.RefreshClusters,
+		func(refresh bool) (string, error) {
+			return getClusterID(configParams.ClusterName, configParams.ProjectName, refresh)
+		},
+		func(clusterID string) (*clusters.Certificate, error) {
+			return getCertFromServiceProvider(configParams, clusterID)
+		})
+	if err != nil {
+		return nil, err
+	}
+	rawConfig, err := certToKubeConfig(cert)
+	if err != nil {
+		return nil, err
+	}
+	return execCredentialFromKubeConfig(rawConfig)
+}
+
+func execCredentialFromKubeConfig(kubeConfig *api.Config) (*execCredentialStatus, error) {
+	for _, authInfo := range kubeConfig.AuthInfos {
+		if len(authInfo.ClientCertificateData) == 0 || len(authInfo.ClientKeyData) == 0 {
+			continue
+		}
+		notAfter, err := certificateNotAfter(authInfo.ClientCertificateData)
+		if err != nil {
+			return nil, err
+		}
+		return &execCredentialStatus{
+			ClientCertificateData: string(authInfo.ClientCertificateData),
+			ClientKeyData:         string(authInfo.ClientKeyData),
+			ExpirationTimestamp:   notAfter,
+		}, nil
+	}
+	return nil, errors.New("fatal: the cluster certificate contains no client certificate")
+}
+
+func certificateNotAfter(certPEM []byte) (time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return time.Time{}, errors.New("fatal: couldn't decode client certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fatal: couldn't parse client certificate\ntrace: %w", err)
+	}
+	return cert.NotAfter, nil
+}
+
+// newExecCredential answers in the API version kubectl asked for, older
+// clients only know v1beta1.
+func newExecCredential(execInfo string, status *execCredentialStatus) execCredential {
+	apiVersion := execCredentialAPIVersion
+	if execInfo != "" {
+		var info execCredential
+		if err := json.Unmarshal([]byte(execInfo), &info); err != nil {
+			glog.Warningf("warning: couldn't parse %s, answering with %s: %s", execInfoEnv, apiVersion, err)
+		} else if info.APIVersion == execCredentialBetaAPIVersion {
+			apiVersion = execCredentialBetaAPIVersion
+		}
+	}
+	return execCredential{
+		APIVersion: apiVersion,
+		Kind:       execCredentialKind,
+		Status:     status,
+	}
+}
+
+func execCredentialCachePath(domainName, projectName, clusterName string) string {
+	return filepath.Join(homedir.HomeDir(), ".kube", "cache", "otc-auth",
+		domainName, projectName, clusterName+".json")
+}
+
+// loadOrFetchExecCredential returns the cached credential unless it expires
+// within execCredentialRenewBefore, otherwise it fetches and caches a new one.
+func loadOrFetchExecCredential(cachePath string, now time.Time,
+	fetch func() (*execCredentialStatus, error),
+) (*execCredentialStatus, error) {
+	cached, err := readExecCredentialCache(cachePath)
+	if err != nil {
+		glog.V(common.InfoLogLevel).Infof("info: ignoring exec credential cache: %s", err)
+	} else if cached != nil && now.Add(execCredentialRenewBefore).Before(cached.ExpirationTimestamp) {
+		glog.V(common.DebugLogLevel).Infof("using cached client certificate from %s", cachePath)
+		return cached, nil
+	}
+
+	status, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if err = writeExecCredentialCache(cachePath, status); err != nil {
+		// kubectl still gets its certificate, it is just fetched again next time
+		glog.Warningf("warning: couldn't cache client certificate: %s", err)
+	}
+	return status, nil
+}
+
+func readExecCredentialCache(cachePath string) (*execCredentialStatus, error) {
+	content, err := os.ReadFile(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil //nolint:nilnil // a missing cache is not an error
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status execCredentialStatus
+	if err = json.Unmarshal(content, &status); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %w", cachePath, err)
+	}
+	return &status, nil
+}
+
+func writeExecCredentialCache(cachePath string, status *execCredentialStatus) error {
+	content, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(cachePath), execCredentialCacheDirAccess); err != nil {
+		return err
+	}
+	// write and rename, so a concurrent kubectl never reads half a file
+	tmp, err := os.CreateTemp(filepath.Dir(cachePath), filepath.Base(cachePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = tmp.Chmod(execCredentialCacheFileAccess); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath)
+}
@@ -0,0 +1,206 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func selfSignedCertPEM(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "user"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func Test_execCredentialFromKubeConfig(t *testing.T) {
+	t.Parallel()
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	certPEM := selfSignedCertPEM(t, notAfter)
+
+	got, err := execCredentialFromKubeConfig(&api.Config{AuthInfos: map[string]*api.AuthInfo{
+		"user": {ClientCertificateData: certPEM, ClientKeyData: []byte("key")},
+	}})
+	if err != nil {
+		t.Fatalf("execCredentialFromKubeConfig() error = %v", err)
+	}
+	want := &execCredentialStatus{
+		ClientCertificateData: string(certPEM),
+		ClientKeyData:         "key",
+		ExpirationTimestamp:   notAfter,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("execCredentialFromKubeConfig() = %+v, want %+v", got, want)
+	}
+
+	_, err = execCredentialFromKubeConfig(&api.Config{AuthInfos: map[string]*api.AuthInfo{}})
+	if err == nil {
+		t.Error("execCredentialFromKubeConfig() expected an error without client certificate")
+	}
+}
+
+func Test_newExecCredential(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		execInfo string
+		want     string
+	}{
+		{name: "no exec info", execInfo: "", want: execCredentialAPIVersion},
+		{
+			name:     "v1",
+			execInfo: `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential"}`,
+			want:     execCredentialAPIVersion,
+		},
+		{
+			name:     "v1beta1",
+			execInfo: `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential"}`,
+			want:     execCredentialBetaAPIVersion,
+		},
+		{name: "garbage", execInfo: "not json", want: execCredentialAPIVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := newExecCredential(tt.execInfo, &execCredentialStatus{})
+			if got.APIVersion != tt.want || got.Kind != execCredentialKind {
+				t.Errorf("newExecCredential() = %s %s, want %s %s",
+					got.APIVersion, got.Kind, tt.want, execCredentialKind)
+			}
+		})
+	}
+}
+
+func Test_loadOrFetchExecCredential(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	valid := &execCredentialStatus{ClientCertificateData: "cached", ExpirationTimestamp: now.Add(time.Hour)}
+	expiring := &execCredentialStatus{ClientCertificateData: "cached", ExpirationTimestamp: now.Add(time.Minute)}
+	fresh := &execCredentialStatus{ClientCertificateData: "fresh", ExpirationTimestamp: now.Add(24 * time.Hour)}
+
+	tests := []struct {
+		name      string
+		cached    *execCredentialStatus
+		corrupt   bool
+		fetchErr  error
+		want      string
+		wantFetch bool
+		wantErr   bool
+	}{
+		{name: "no cache", want: "fresh", wantFetch: true},
+		{name: "valid cache", cached: valid, want: "cached"},
+		{name: "expiring cache", cached: expiring, want: "fresh", wantFetch: true},
+		{name: "corrupt cache", corrupt: true, want: "fresh", wantFetch: true},
+		{name: "fetch fails", fetchErr: errors.New("boom"), wantFetch: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cachePath := filepath.Join(t.TempDir(), "domain", "project", "cluster.json")
+			if tt.cached != nil {
+				if err := writeExecCredentialCache(cachePath, tt.cached); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.corrupt {
+				if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(cachePath, []byte("{"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fetched := false
+			got, err := loadOrFetchExecCredential(cachePath, now, func() (*execCredentialStatus, error) {
+				fetched = true
+				return fresh, tt.fetchErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadOrFetchExecCredential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fetched != tt.wantFetch {
+				t.Errorf("loadOrFetchExecCredential() fetched = %v, want %v", fetched, tt.wantFetch)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.ClientCertificateData != tt.want {
+				t.Errorf("loadOrFetchExecCredential() = %s, want %s", got.ClientCertificateData, tt.want)
+			}
+
+			info, err := os.Stat(cachePath)
+			if err != nil {
+				t.Fatalf("cache file missing: %v", err)
+			}
+			if info.Mode().Perm() != execCredentialCacheFileAccess {
+				t.Errorf("cache file mode = %v, want %v", info.Mode().Perm(), os.FileMode(execCredentialCacheFileAccess))
+			}
+			cached, err := readExecCredentialCache(cachePath)
+			if err != nil || cached.ClientCertificateData != tt.want {
+				t.Errorf("cache content = %+v (%v), want %s", cached, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_useExecCredential(t *testing.T) {
+	t.Parallel()
+	kubeConfig := &api.Config{AuthInfos: map[string]*api.AuthInfo{
+		"project-cluster-user": {ClientCertificateData: []byte("cert"), ClientKeyData: []byte("key")},
+	}}
+
+	useExecCredential(kubeConfig, "domain", KubeConfigParams{
+		ProjectName: "project",
+		ClusterName: "cluster",
+		DaysValid:   "7",
+	})
+
+	authInfo := kubeConfig.AuthInfos["project-cluster-user"]
+	if len(authInfo.ClientCertificateData) != 0 || len(authInfo.ClientKeyData) != 0 {
+		t.Error("useExecCredential() kept the static certificate")
+	}
+	if authInfo.Exec == nil {
+		t.Fatal("useExecCredential() wrote no exec entry")
+	}
+	wantArgs := []string{
+		"cce", "exec-credential",
+		"--os-domain-name", "domain",
+		"--os-project-name", "project",
+		"--cluster", "cluster",
+		"--days-valid", "7",
+	}
+	if authInfo.Exec.Command != execCredentialCommand || !reflect.DeepEqual(authInfo.Exec.Args, wantArgs) {
+		t.Errorf("useExecCredential() exec = %s %v, want %s %v",
+			authInfo.Exec.Command, authInfo.Exec.Args, execCredentialCommand, wantArgs)
+	}
+	if authInfo.Exec.APIVersion != execCredentialAPIVersion ||
+		authInfo.Exec.InteractiveMode != api.NeverExecInteractiveMode {
+		t.Errorf("useExecCredential() exec = %+v", authInfo.Exec)
+	}
+}
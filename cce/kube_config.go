@@ -7,10 +7,10 @@ import (
 	"path/filepath"
 
 	"otc-auth/common"
-	"otc-auth/config"
 
 	"github.com/golang/glog"
 	"github.com/imdario/mergo"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/cce/v3/clusters"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/util/homedir"
@@ -24,12 +24,20 @@ const (
 func getKubeConfig(kubeConfigParams KubeConfigParams, alias string) (*api.Config, error) {
 	glog.V(common.InfoLogLevel).Infof("info: getting kube config...")
 
-	clusterID, err := getClusterID(kubeConfigParams.ClusterName, kubeConfigParams.ProjectName)
+	var clusterID string
+	cert, err := certOfCluster(kubeConfigParams.RefreshClusters,
+		func(refresh bool) (string, error) {
+			return getClusterID(kubeConfigParams.ClusterName, kubeConfigParams.ProjectName, refresh)
+		},
+		func(id string) (*clusters.Certificate, error) {
+			clusterID = id
+			return getCertFromServiceProvider(kubeConfigParams, id)
+		})
 	if err != nil {
-		common.ThrowError(fmt.Errorf("fatal: error receiving cluster id: %w", err))
+		return nil, err
 	}
 
-	return getKubeConfFromServiceProvider(kubeConfigParams, clusterID, alias)
+	return kubeConfigFromCert(kubeConfigParams, cert, clusterID, alias)
 }
 
 func mergeKubeConfig(configParams KubeConfigParams, kubeConfig api.Config) {
@@ -81,22 +89,15 @@ func determineTargetLocation(targetLocation string) string {
 	return defaultKubeConfigLocation
 }
 
-func renameKubeconfigEntries(rawConfig *api.Config, projectName, clusterName, alias string) error {
-	activeCloud, err := config.GetActiveCloudConfig()
-	if err != nil {
-		return err
-	}
-
-	if alias == "" {
-		alias = fmt.Sprintf("%s/%s", projectName, clusterName)
-	}
-
+// renameKubeconfigEntries names the external context and cluster after the
+// alias, the internal ones get an -intranet suffix.
+func renameKubeconfigEntries(rawConfig *api.Config, alias string, userName string) {
 	clusterRenames := map[string]string{
 		internalClusterName: fmt.Sprintf("%s-intranet", alias),
 		externalClusterName: alias,
 	}
 	userRenames := map[string]string{
-		"user": fmt.Sprintf("%s-%s-%s", projectName, clusterName, activeCloud.Username),
+		"user": userName,
 	}
 	contextRenames := map[string]string{
 		"internal": fmt.Sprintf("%s-intranet", alias),
@@ -134,6 +135,4 @@ func renameKubeconfigEntries(rawConfig *api.Config, projectName, clusterName, al
 	if newName, ok := contextRenames[rawConfig.CurrentContext]; ok {
 		rawConfig.CurrentContext = newName
 	}
-
-	return nil
 }
@@ -0,0 +1,220 @@
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"otc-auth/common"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/homedir"
+)
+
+// Layouts of the kube configs written by get-kube-config.
+const (
+	// KubeConfigLayoutMerged merges all clusters into the target location
+	KubeConfigLayoutMerged = "merged"
+	// KubeConfigLayoutSplit writes every cluster into its own file, see
+	// DefaultKubeConfigPathTemplate
+	KubeConfigLayoutSplit = "split"
+)
+
+// KubeConfigLayouts returns all layouts get-kube-config understands.
+func KubeConfigLayouts() []string {
+	return []string{KubeConfigLayoutMerged, KubeConfigLayoutSplit}
+}
+
+// DefaultKubeConfigPathTemplate is where the split layout puts the kube
+// config of a cluster. {domain}, {project} and {cluster} are replaced.
+const DefaultKubeConfigPathTemplate = "~/.kube/otc/{domain}/{project}/{cluster}.yaml"
+
+var pathTemplatePlaceholder = regexp.MustCompile(`\{(domain|project|cluster)\}`)
+
+// expandPathTemplate returns the kube config path of the cluster the tag
+// belongs to. Path separators in the names are replaced, so every cluster
+// ends up in the directory the template says.
+func expandPathTemplate(pathTemplate string, tag kubeConfigTag) string {
+	if strings.HasPrefix(pathTemplate, "~") {
+		pathTemplate = strings.Replace(pathTemplate, "~", homedir.HomeDir(), 1)
+	}
+	sanitize := strings.NewReplacer("/", "_", string(filepath.Separator), "_")
+	return pathTemplatePlaceholder.ReplaceAllStringFunc(pathTemplate, func(placeholder string) string {
+		switch placeholder {
+		case "{domain}":
+			return sanitize.Replace(tag.Domain)
+		case "{project}":
+			return sanitize.Replace(tag.Project)
+		default:
+			return sanitize.Replace(tag.Cluster)
+		}
+	})
+}
+
+// pathTemplateGlob turns the template into a pattern for filepath.Glob
+// matching the files of all clusters, or of all clusters of one domain if it
+// isn't empty.
+func pathTemplateGlob(pathTemplate string, domainName string) string {
+	if strings.HasPrefix(pathTemplate, "~") {
+		pathTemplate = strings.Replace(pathTemplate, "~", homedir.HomeDir(), 1)
+	}
+	escape := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+	if runtime.GOOS == "windows" {
+		// backslashes separate paths there, filepath.Glob can't escape
+		escape = strings.NewReplacer()
+	}
+
+	var glob strings.Builder
+	last := 0
+	for _, match := range pathTemplatePlaceholder.FindAllStringIndex(pathTemplate, -1) {
+		glob.WriteString(escape.Replace(pathTemplate[last:match[0]]))
+		if pathTemplate[match[0]:match[1]] == "{domain}" && domainName != "" {
+			glob.WriteString(escape.Replace(expandPathTemplate("{domain}", kubeConfigTag{Domain: domainName})))
+		} else {
+			glob.WriteString("*")
+		}
+		last = match[1]
+	}
+	glob.WriteString(escape.Replace(pathTemplate[last:]))
+	return glob.String()
+}
+
+// splitKubeConfig groups the entries by the cluster they are tagged with.
+// Clusters and users follow the contexts using them.
+func splitKubeConfig(kubeConfig api.Config) (map[kubeConfigTag]*api.Config, error) {
+	split := map[kubeConfigTag]*api.Config{}
+	for name, context := range kubeConfig.Contexts {
+		tag := readKubeConfigTag(context.Extensions)
+		if tag == nil {
+			return nil, fmt.Errorf("fatal: context %s isn't tagged by otc-auth", name)
+		}
+		tag.ClusterID = ""
+		clusterConfig, ok := split[*tag]
+		if !ok {
+			clusterConfig = api.NewConfig()
+			split[*tag] = clusterConfig
+		}
+		clusterConfig.Contexts[name] = context
+		if cluster, exists := kubeConfig.Clusters[context.Cluster]; exists {
+			clusterConfig.Clusters[context.Cluster] = cluster
+		}
+		if authInfo, exists := kubeConfig.AuthInfos[context.AuthInfo]; exists {
+			clusterConfig.AuthInfos[context.AuthInfo] = authInfo
+		}
+		if kubeConfig.CurrentContext == name {
+			clusterConfig.CurrentContext = name
+		}
+	}
+	return split, nil
+}
+
+// writeSplitKubeConfigs merges the entries of every cluster into the file the
+// path template gives for it. A file without a current context gets the
+// first context of its cluster, so it can be used on its own.
+func writeSplitKubeConfigs(pathTemplate string, kubeConfig api.Config) ([]string, error) {
+	split, err := splitKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for tag, clusterConfig := range split {
+		location := expandPathTemplate(pathTemplate, tag)
+		currentConfig := api.NewConfig()
+		if _, errStat := os.Stat(location); errStat == nil {
+			currentConfig, err = clientcmd.LoadFromFile(location)
+			if err != nil {
+				return nil, fmt.Errorf("fatal: couldn't read kube config %s\ntrace: %w", location, err)
+			}
+		}
+		currentContext := currentConfig.CurrentContext
+		if err = merge(currentConfig, *clusterConfig); err != nil {
+			return nil, err
+		}
+		switch {
+		case clusterConfig.CurrentContext != "":
+			currentConfig.CurrentContext = clusterConfig.CurrentContext
+		case currentContext != "":
+			currentConfig.CurrentContext = currentContext
+		default:
+			contexts := make([]string, 0, len(clusterConfig.Contexts))
+			for name := range clusterConfig.Contexts {
+				contexts = append(contexts, name)
+			}
+			sort.Strings(contexts)
+			currentConfig.CurrentContext = contexts[0]
+		}
+
+		if err = os.MkdirAll(filepath.Dir(location), 0o700); err != nil { //nolint:mnd // only the user reads the keys
+			return nil, fmt.Errorf("fatal: couldn't create directory for %s\ntrace: %w", location, err)
+		}
+		if err = clientcmd.WriteToFile(*currentConfig, location); err != nil {
+			return nil, fmt.Errorf("fatal: couldn't write kube config %s\ntrace: %w", location, err)
+		}
+		glog.V(common.InfoLogLevel).Infof("info: wrote kube config of %s/%s to %s", tag.Project, tag.Cluster,
+			location)
+		paths = append(paths, location)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// FindManagedKubeConfigs returns the files matching the path template which
+// hold entries otc-auth wrote, restricted to a domain if it isn't empty.
+func FindManagedKubeConfigs(pathTemplate string, domainName string) ([]string, error) {
+	candidates, err := filepath.Glob(pathTemplateGlob(pathTemplate, domainName))
+	if err != nil {
+		return nil, fmt.Errorf("fatal: invalid path template %s\ntrace: %w", pathTemplate, err)
+	}
+	var files []string
+	for _, candidate := range candidates {
+		if info, errStat := os.Stat(candidate); errStat != nil || info.IsDir() {
+			continue
+		}
+		kubeConfig, errLoad := clientcmd.LoadFromFile(candidate)
+		if errLoad != nil {
+			glog.Warningf("warning: skipping %s: %s", candidate, errLoad)
+			continue
+		}
+		if hasTaggedContext(kubeConfig, domainName) {
+			files = append(files, candidate)
+		}
+	}
+	return files, nil
+}
+
+func hasTaggedContext(kubeConfig *api.Config, domainName string) bool {
+	for _, context := range kubeConfig.Contexts {
+		if tag := readKubeConfigTag(context.Extensions); tag != nil && (domainName == "" || tag.Domain == domainName) {
+			return true
+		}
+	}
+	return false
+}
+
+// KubeConfigPathValue joins the files for the KUBECONFIG variable. The
+// default kube config comes first if it exists, so kubectl keeps writing
+// the current context there.
+func KubeConfigPathValue(pathTemplate string, domainName string, includeDefault bool) string {
+	files, err := FindManagedKubeConfigs(pathTemplate, domainName)
+	if err != nil {
+		common.ThrowError(err)
+	}
+	if includeDefault {
+		defaultLocation := determineTargetLocation("")
+		if _, errStat := os.Stat(defaultLocation); errStat == nil {
+			files = append([]string{defaultLocation}, files...)
+		}
+	}
+	if len(files) == 0 {
+		common.ThrowError(errors.New("fatal: no kube configs found, fetch some with " +
+			"`otc-auth cce get-kube-config --layout split` first"))
+	}
+	return strings.Join(files, string(filepath.ListSeparator))
+}
@@ -0,0 +1,139 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_expandPathTemplate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		template string
+		tag      kubeConfigTag
+		want     string
+	}{
+		{
+			name:     "all placeholders",
+			template: "/kube/{domain}/{project}/{cluster}.yaml",
+			tag:      kubeConfigTag{Domain: "d", Project: "eu-de_p", Cluster: "c"},
+			want:     "/kube/d/eu-de_p/c.yaml",
+		},
+		{
+			name:     "flat",
+			template: "/kube/{project}-{cluster}.yaml",
+			tag:      kubeConfigTag{Domain: "d", Project: "p", Cluster: "c"},
+			want:     "/kube/p-c.yaml",
+		},
+		{
+			name:     "separators in names",
+			template: "/kube/{cluster}.yaml",
+			tag:      kubeConfigTag{Domain: "d", Project: "p", Cluster: "../c"},
+			want:     "/kube/.._c.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := expandPathTemplate(tt.template, tt.tag); got != tt.want {
+				t.Errorf("expandPathTemplate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeSplitKubeConfigs(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	pathTemplate := filepath.Join(dir, "{domain}", "{project}", "{cluster}.yaml")
+	// get-kube-config only passes on the entries it fetched
+	fetched := func(tags ...kubeConfigTag) *api.Config {
+		kubeConfig := taggedKubeConfig(t, tags...)
+		delete(kubeConfig.Contexts, "minikube")
+		return kubeConfig
+	}
+	kubeConfig := fetched(
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "a", ClusterID: "1"},
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "b", ClusterID: "2"},
+		kubeConfigTag{Domain: "other", Project: "p", Cluster: "c", ClusterID: "3"},
+	)
+	kubeConfig.CurrentContext = "p/b-intranet"
+
+	// a file from an earlier run the user changed
+	existing := filepath.Join(dir, "d", "p", "a.yaml")
+	if _, err := writeSplitKubeConfigs(pathTemplate,
+		*fetched(kubeConfigTag{Domain: "d", Project: "p", Cluster: "a", ClusterID: "1"})); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := clientcmd.LoadFromFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous.CurrentContext = "p/a-intranet"
+	previous.Contexts["mine"] = &api.Context{Cluster: "p/a", AuthInfo: "p-a-me", Namespace: "team"}
+	if err = clientcmd.WriteToFile(*previous, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := writeSplitKubeConfigs(pathTemplate, *kubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPaths := []string{existing, filepath.Join(dir, "d", "p", "b.yaml"), filepath.Join(dir, "other", "p", "c.yaml")}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("writeSplitKubeConfigs() = %v, want %v", paths, wantPaths)
+	}
+
+	tests := []struct {
+		path           string
+		currentContext string
+		contexts       []string
+	}{
+		{path: wantPaths[0], currentContext: "p/a-intranet", contexts: []string{"mine", "p/a", "p/a-intranet"}},
+		{path: wantPaths[1], currentContext: "p/b-intranet", contexts: []string{"p/b", "p/b-intranet"}},
+		{path: wantPaths[2], currentContext: "p/c", contexts: []string{"p/c", "p/c-intranet"}},
+	}
+	for _, tt := range tests {
+		written, errLoad := clientcmd.LoadFromFile(tt.path)
+		if errLoad != nil {
+			t.Fatal(errLoad)
+		}
+		if written.CurrentContext != tt.currentContext {
+			t.Errorf("%s: current context = %s, want %s", tt.path, written.CurrentContext, tt.currentContext)
+		}
+		for name, context := range written.Contexts {
+			if written.Clusters[context.Cluster] == nil || written.AuthInfos[context.AuthInfo] == nil {
+				t.Errorf("%s: context %s lacks its cluster or user", tt.path, name)
+			}
+		}
+		if contexts := slices.Sorted(maps.Keys(written.Contexts)); !reflect.DeepEqual(contexts, tt.contexts) {
+			t.Errorf("%s: contexts = %v, want %v", tt.path, contexts, tt.contexts)
+		}
+	}
+
+	managed, err := FindManagedKubeConfigs(pathTemplate, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(managed, wantPaths[:2]) {
+		t.Errorf("FindManagedKubeConfigs(d) = %v, want %v", managed, wantPaths[:2])
+	}
+	if managed, err = FindManagedKubeConfigs(pathTemplate, ""); err != nil || len(managed) != 3 {
+		t.Errorf("FindManagedKubeConfigs() = %v, %v, want all three files", managed, err)
+	}
+}
+
+func Test_splitKubeConfig_untagged(t *testing.T) {
+	t.Parallel()
+	kubeConfig := taggedKubeConfig(t, kubeConfigTag{Domain: "d", Project: "p", Cluster: "c"})
+	if _, err := splitKubeConfig(*kubeConfig); err == nil {
+		t.Error("splitKubeConfig() of an untagged context succeeded")
+	}
+}
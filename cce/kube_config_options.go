@@ -0,0 +1,93 @@
+package cce
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Endpoints get-kube-config writes contexts for.
+const (
+	KubeEndpointsBoth     = "both"
+	KubeEndpointsExternal = "external"
+	KubeEndpointsInternal = "internal"
+)
+
+// KubeEndpoints returns all endpoint choices get-kube-config understands.
+func KubeEndpoints() []string {
+	return []string{KubeEndpointsBoth, KubeEndpointsExternal, KubeEndpointsInternal}
+}
+
+// Default name templates of the kube config entries. {domain}, {project},
+// {cluster} and {user} are replaced, the internal context and cluster get an
+// -intranet suffix.
+const (
+	DefaultKubeContextTemplate = "{project}/{cluster}"
+	DefaultKubeUserTemplate    = "{project}-{cluster}-{user}"
+)
+
+var nameTemplatePlaceholder = regexp.MustCompile(`\{(domain|project|cluster|user)\}`)
+
+// expandNameTemplate fills the template with the names of the cluster and the
+// user logged in.
+func expandNameTemplate(nameTemplate string, tag kubeConfigTag, username string) string {
+	values := map[string]string{
+		"{domain}":  tag.Domain,
+		"{project}": tag.Project,
+		"{cluster}": tag.Cluster,
+		"{user}":    username,
+	}
+	return nameTemplatePlaceholder.ReplaceAllStringFunc(nameTemplate, func(placeholder string) string {
+		return values[placeholder]
+	})
+}
+
+// kubeConfigEntryNames returns the name of the external context and cluster
+// and the name of the user, empty templates fall back to the defaults.
+func kubeConfigEntryNames(configParams KubeConfigParams, tag kubeConfigTag, username string) (string, string) {
+	contextTemplate := configParams.ContextTemplate
+	if contextTemplate == "" {
+		contextTemplate = DefaultKubeContextTemplate
+	}
+	userTemplate := configParams.UserTemplate
+	if userTemplate == "" {
+		userTemplate = DefaultKubeUserTemplate
+	}
+	return expandNameTemplate(contextTemplate, tag, username), expandNameTemplate(userTemplate, tag, username)
+}
+
+// applyKubeConfigOptions drops the context of the endpoint not asked for, sets
+// the default namespace and clears the current context if the one of the
+// target kube config should stay. It expects the entries to be renamed
+// already.
+func applyKubeConfigOptions(kubeConfig *api.Config, configParams KubeConfigParams, contextName string) error {
+	external, internal := contextName, contextName+"-intranet"
+	keep, drop := "", ""
+	switch configParams.Endpoints {
+	case KubeEndpointsExternal:
+		keep, drop = external, internal
+	case KubeEndpointsInternal:
+		keep, drop = internal, external
+	}
+	if keep != "" {
+		if _, ok := kubeConfig.Contexts[keep]; !ok {
+			return fmt.Errorf("fatal: cluster %s has no %s endpoint", contextName, configParams.Endpoints)
+		}
+		if context, ok := kubeConfig.Contexts[drop]; ok {
+			delete(kubeConfig.Clusters, context.Cluster)
+			delete(kubeConfig.Contexts, drop)
+		}
+		kubeConfig.CurrentContext = keep
+	}
+
+	if configParams.Namespace != "" {
+		for _, context := range kubeConfig.Contexts {
+			context.Namespace = configParams.Namespace
+		}
+	}
+	if configParams.KeepCurrentContext {
+		kubeConfig.CurrentContext = ""
+	}
+	return nil
+}
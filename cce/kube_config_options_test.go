@@ -0,0 +1,137 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// fetchedKubeConfig is what certToKubeConfig returns for a cluster with an
+// EIP, renamed like get-kube-config does.
+func fetchedKubeConfig(contextName string, userName string) *api.Config {
+	kubeConfig := &api.Config{
+		Clusters: map[string]*api.Cluster{
+			internalClusterName: {Server: "https://192.168.0.1:5443"},
+			externalClusterName: {Server: "https://80.158.0.1:5443"},
+		},
+		AuthInfos: map[string]*api.AuthInfo{"user": {Token: "token"}},
+		Contexts: map[string]*api.Context{
+			"internal": {Cluster: internalClusterName, AuthInfo: "user"},
+			"external": {Cluster: externalClusterName, AuthInfo: "user"},
+		},
+		CurrentContext: "external",
+	}
+	renameKubeconfigEntries(kubeConfig, contextName, userName)
+	return kubeConfig
+}
+
+func Test_kubeConfigEntryNames(t *testing.T) {
+	t.Parallel()
+	tag := kubeConfigTag{Domain: "d", Project: "p", Cluster: "c"}
+	tests := []struct {
+		name        string
+		params      KubeConfigParams
+		wantContext string
+		wantUser    string
+	}{
+		{name: "defaults", wantContext: "p/c", wantUser: "p-c-me"},
+		{
+			name:        "templates",
+			params:      KubeConfigParams{ContextTemplate: "{domain}-{cluster}", UserTemplate: "{user}@{domain}/{cluster}"},
+			wantContext: "d-c",
+			wantUser:    "me@d/c",
+		},
+		{name: "alias", params: KubeConfigParams{ContextTemplate: "prod"}, wantContext: "prod", wantUser: "p-c-me"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			gotContext, gotUser := kubeConfigEntryNames(tt.params, tag, "me")
+			if gotContext != tt.wantContext || gotUser != tt.wantUser {
+				t.Errorf("kubeConfigEntryNames() = %s, %s, want %s, %s", gotContext, gotUser, tt.wantContext,
+					tt.wantUser)
+			}
+		})
+	}
+}
+
+func Test_applyKubeConfigOptions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name               string
+		params             KubeConfigParams
+		wantContexts       []string
+		wantCurrentContext string
+		wantErr            bool
+	}{
+		{
+			name:               "both",
+			params:             KubeConfigParams{Endpoints: KubeEndpointsBoth},
+			wantContexts:       []string{"p/c", "p/c-intranet"},
+			wantCurrentContext: "p/c",
+		},
+		{
+			name:               "external",
+			params:             KubeConfigParams{Endpoints: KubeEndpointsExternal},
+			wantContexts:       []string{"p/c"},
+			wantCurrentContext: "p/c",
+		},
+		{
+			name:               "internal",
+			params:             KubeConfigParams{Endpoints: KubeEndpointsInternal},
+			wantContexts:       []string{"p/c-intranet"},
+			wantCurrentContext: "p/c-intranet",
+		},
+		{
+			name:         "keep current context",
+			params:       KubeConfigParams{Endpoints: KubeEndpointsInternal, KeepCurrentContext: true},
+			wantContexts: []string{"p/c-intranet"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			kubeConfig := fetchedKubeConfig("p/c", "p-c-me")
+			if err := applyKubeConfigOptions(kubeConfig, tt.params, "p/c"); err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Sorted(maps.Keys(kubeConfig.Contexts)); !reflect.DeepEqual(got, tt.wantContexts) {
+				t.Errorf("contexts = %v, want %v", got, tt.wantContexts)
+			}
+			if got := slices.Sorted(maps.Keys(kubeConfig.Clusters)); !reflect.DeepEqual(got, tt.wantContexts) {
+				t.Errorf("clusters = %v, want %v", got, tt.wantContexts)
+			}
+			if kubeConfig.CurrentContext != tt.wantCurrentContext {
+				t.Errorf("current context = %q, want %q", kubeConfig.CurrentContext, tt.wantCurrentContext)
+			}
+		})
+	}
+}
+
+func Test_applyKubeConfigOptions_namespace(t *testing.T) {
+	t.Parallel()
+	kubeConfig := fetchedKubeConfig("p/c", "p-c-me")
+	if err := applyKubeConfigOptions(kubeConfig, KubeConfigParams{Namespace: "team"}, "p/c"); err != nil {
+		t.Fatal(err)
+	}
+	for name, context := range kubeConfig.Contexts {
+		if context.Namespace != "team" || context.AuthInfo != "p-c-me" {
+			t.Errorf("context %s = %+v, want namespace team and user p-c-me", name, context)
+		}
+	}
+}
+
+func Test_applyKubeConfigOptions_missingEndpoint(t *testing.T) {
+	t.Parallel()
+	// a cluster without an EIP only has the internal endpoint
+	kubeConfig := fetchedKubeConfig("p/c", "p-c-me")
+	delete(kubeConfig.Contexts, "p/c")
+	delete(kubeConfig.Clusters, "p/c")
+	if err := applyKubeConfigOptions(kubeConfig, KubeConfigParams{Endpoints: KubeEndpointsExternal}, "p/c"); err == nil {
+		t.Error("applyKubeConfigOptions() without an external endpoint succeeded")
+	}
+}
@@ -0,0 +1,63 @@
+package cce
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubeConfigTagExtension is the name of the extension marking kube config
+// entries written by otc-auth.
+const kubeConfigTagExtension = "otc-auth"
+
+// kubeConfigTag records which CCE cluster a kube config entry belongs to, so
+// entries of deleted clusters can be found again regardless of their names.
+type kubeConfigTag struct {
+	Domain    string `json:"domain"`
+	Project   string `json:"project"`
+	Cluster   string `json:"cluster"`
+	ClusterID string `json:"clusterID"`
+}
+
+// tagKubeConfigEntries adds the tag to all clusters, users and contexts.
+func tagKubeConfigEntries(kubeConfig *api.Config, tag kubeConfigTag) error {
+	raw, err := json.Marshal(tag)
+	if err != nil {
+		return err
+	}
+	extension := func() *runtime.Unknown {
+		return &runtime.Unknown{Raw: raw, ContentType: runtime.ContentTypeJSON}
+	}
+	for _, cluster := range kubeConfig.Clusters {
+		cluster.Extensions = withExtension(cluster.Extensions, extension())
+	}
+	for _, authInfo := range kubeConfig.AuthInfos {
+		authInfo.Extensions = withExtension(authInfo.Extensions, extension())
+	}
+	for _, context := range kubeConfig.Contexts {
+		context.Extensions = withExtension(context.Extensions, extension())
+	}
+	return nil
+}
+
+func withExtension(extensions map[string]runtime.Object, extension runtime.Object) map[string]runtime.Object {
+	if extensions == nil {
+		extensions = map[string]runtime.Object{}
+	}
+	extensions[kubeConfigTagExtension] = extension
+	return extensions
+}
+
+// readKubeConfigTag returns the tag of an entry, nil if it has none.
+func readKubeConfigTag(extensions map[string]runtime.Object) *kubeConfigTag {
+	extension, ok := extensions[kubeConfigTagExtension].(*runtime.Unknown)
+	if !ok {
+		return nil
+	}
+	var tag kubeConfigTag
+	if err := json.Unmarshal(extension.Raw, &tag); err != nil || tag.Project == "" || tag.Cluster == "" {
+		return nil
+	}
+	return &tag
+}
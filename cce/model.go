@@ -6,11 +6,47 @@ type KubeConfigParams struct {
 	DaysValid      string
 	TargetLocation string
 	Server         string
+	// ExecCredential writes an exec user entry calling otc-auth instead of
+	// static client certificates
+	ExecCredential bool
+	// RefreshClusters fetches the clusters of the project even if they are
+	// cached
+	RefreshClusters bool
+	// Layout is one of KubeConfigLayouts, merged if empty
+	Layout string
+	// PathTemplate gives the file of each cluster in the split layout
+	PathTemplate string
+	// Endpoints is one of KubeEndpoints, both if empty
+	Endpoints string
+	// KeepCurrentContext leaves the current context of the target kube
+	// config as it is
+	KeepCurrentContext bool
+	// Namespace is the default namespace of the contexts
+	Namespace string
+	// ContextTemplate and UserTemplate name the entries, see
+	// DefaultKubeContextTemplate and DefaultKubeUserTemplate
+	ContextTemplate string
+	UserTemplate    string
 }
 
 type cceClusterItem struct {
 	Metadata struct {
-		Name string `json:"name"`
-		UID  string `json:"uid"`
+		Name              string `json:"name"`
+		UID               string `json:"uid"`
+		CreationTimestamp string `json:"creationTimestamp"`
 	} `json:"metadata"`
+	Spec struct {
+		Type    string `json:"type"`
+		Flavor  string `json:"flavor"`
+		Version string `json:"version"`
+	} `json:"spec"`
+	Status struct {
+		Phase     string               `json:"phase"`
+		Endpoints []cceClusterEndpoint `json:"endpoints"`
+	} `json:"status"`
+}
+
+type cceClusterEndpoint struct {
+	URL  string `json:"url"`
+	Type string `json:"type"`
 }
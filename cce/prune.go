@@ -0,0 +1,231 @@
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"otc-auth/common"
+	"otc-auth/config"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// PruneParams configures PruneKubeConfig.
+type PruneParams struct {
+	TargetLocation string
+	DryRun         bool
+	// Yes skips the confirmation
+	Yes bool
+}
+
+// clusterEntries holds the names of the kube config entries otc-auth wrote
+// for a cluster.
+type clusterEntries struct {
+	Tag      kubeConfigTag
+	Contexts []string
+	Clusters []string
+	Users    []string
+}
+
+// PruneKubeConfig removes the kube config entries otc-auth wrote for clusters
+// of the active cloud which were deleted since.
+func PruneKubeConfig(params PruneParams, out io.Writer) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		common.ThrowError(err)
+	}
+	location := determineTargetLocation(params.TargetLocation)
+	kubeConfig, err := clientcmd.LoadFromFile(location)
+	if err != nil {
+		common.ThrowError(fmt.Errorf("fatal: couldn't read kube config %s\ntrace: %w", location, err))
+	}
+
+	stale := findStaleClusters(kubeConfig, activeCloud.Domain.Name, func(projectName string) (config.Clusters, error) {
+		project, errProject := activeCloud.Projects.GetProjectByName(projectName)
+		if errProject != nil {
+			return nil, errProject
+		}
+		client, errClient := newCCEClient(activeCloud, project)
+		if errClient != nil {
+			return nil, errClient
+		}
+		return listClusters(client)
+	})
+	if len(stale) == 0 {
+		fmt.Fprintf(out, "no entries of deleted clusters found in %s\n", location)
+		return
+	}
+
+	writeClusterEntries(out, "entries of deleted clusters:", stale)
+	if params.DryRun {
+		fmt.Fprintln(out, "dry run, nothing was removed")
+		return
+	}
+	if !params.Yes {
+		confirmed, errConfirm := common.Confirm(fmt.Sprintf("Remove these entries from %s?", location))
+		if errConfirm != nil {
+			common.ThrowError(fmt.Errorf("%w.\n\nUse --yes to prune without confirmation", errConfirm))
+		}
+		if !confirmed {
+			fmt.Fprintln(out, "nothing was removed")
+			return
+		}
+	}
+
+	removed := removeClusterEntries(kubeConfig, stale)
+	if err = clientcmd.WriteToFile(*kubeConfig, location); err != nil {
+		common.ThrowError(fmt.Errorf("fatal: couldn't write kube config %s\ntrace: %w", location, err))
+	}
+	for _, cluster := range stale {
+		removeExecCredentialCache(cluster.Tag)
+	}
+	fmt.Fprintf(out, "removed %d entries from %s\n", removed, location)
+}
+
+// findStaleClusters groups the tagged entries of the domain by cluster and
+// returns those whose cluster isn't listed in its project anymore. Projects
+// which can't be listed are skipped, their clusters may well exist.
+func findStaleClusters(kubeConfig *api.Config, domainName string,
+	list func(projectName string) (config.Clusters, error),
+) []clusterEntries {
+	byCluster := map[kubeConfigTag]*clusterEntries{}
+	entry := func(tag *kubeConfigTag) *clusterEntries {
+		if tag == nil || tag.Domain != domainName {
+			return nil
+		}
+		if _, ok := byCluster[*tag]; !ok {
+			byCluster[*tag] = &clusterEntries{Tag: *tag}
+		}
+		return byCluster[*tag]
+	}
+	for name, context := range kubeConfig.Contexts {
+		if cluster := entry(readKubeConfigTag(context.Extensions)); cluster != nil {
+			cluster.Contexts = append(cluster.Contexts, name)
+		}
+	}
+	for name, kubeCluster := range kubeConfig.Clusters {
+		if cluster := entry(readKubeConfigTag(kubeCluster.Extensions)); cluster != nil {
+			cluster.Clusters = append(cluster.Clusters, name)
+		}
+	}
+	for name, authInfo := range kubeConfig.AuthInfos {
+		if cluster := entry(readKubeConfigTag(authInfo.Extensions)); cluster != nil {
+			cluster.Users = append(cluster.Users, name)
+		}
+	}
+
+	existing := map[string]config.Clusters{}
+	var stale []clusterEntries
+	for tag, cluster := range byCluster {
+		clusterArr, listed := existing[tag.Project]
+		if !listed {
+			var err error
+			clusterArr, err = list(tag.Project)
+			if err != nil {
+				glog.Warningf("warning: skipping project %s, couldn't list its clusters: %s", tag.Project, err)
+				clusterArr = nil
+			}
+			existing[tag.Project] = clusterArr
+		}
+		if clusterArr == nil || clusterExists(clusterArr, tag) {
+			continue
+		}
+		sort.Strings(cluster.Contexts)
+		sort.Strings(cluster.Clusters)
+		sort.Strings(cluster.Users)
+		stale = append(stale, *cluster)
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		if stale[i].Tag.Project != stale[j].Tag.Project {
+			return stale[i].Tag.Project < stale[j].Tag.Project
+		}
+		return stale[i].Tag.Cluster < stale[j].Tag.Cluster
+	})
+	return stale
+}
+
+// clusterExists matches by id, a cluster recreated under the same name has
+// a new id and new certificates.
+func clusterExists(clusterArr config.Clusters, tag kubeConfigTag) bool {
+	for _, cluster := range clusterArr {
+		if tag.ClusterID != "" && cluster.ID == tag.ClusterID {
+			return true
+		}
+		if tag.ClusterID == "" && cluster.Name == tag.Cluster {
+			return true
+		}
+	}
+	return false
+}
+
+func writeClusterEntries(w io.Writer, title string, entries []clusterEntries) {
+	fmt.Fprintln(w, title)
+	for _, cluster := range entries {
+		if cluster.Tag.Project == "" {
+			fmt.Fprintf(w, "  %s\n", cluster.Tag.Cluster)
+		} else {
+			fmt.Fprintf(w, "  %s/%s\n", cluster.Tag.Project, cluster.Tag.Cluster)
+		}
+		for _, kind := range []struct {
+			name  string
+			names []string
+		}{{"contexts", cluster.Contexts}, {"clusters", cluster.Clusters}, {"users", cluster.Users}} {
+			if len(kind.names) > 0 {
+				fmt.Fprintf(w, "    %s: %s\n", kind.name, strings.Join(kind.names, ", "))
+			}
+		}
+	}
+}
+
+// removeClusterEntries deletes the entries and returns how many were removed.
+// Clusters and users still referenced by a remaining context are kept.
+func removeClusterEntries(kubeConfig *api.Config, entries []clusterEntries) int {
+	removed := 0
+	for _, cluster := range entries {
+		for _, name := range cluster.Contexts {
+			delete(kubeConfig.Contexts, name)
+			removed++
+			if kubeConfig.CurrentContext == name {
+				kubeConfig.CurrentContext = ""
+			}
+		}
+	}
+	referencedClusters := map[string]bool{}
+	referencedUsers := map[string]bool{}
+	for _, context := range kubeConfig.Contexts {
+		referencedClusters[context.Cluster] = true
+		referencedUsers[context.AuthInfo] = true
+	}
+	for _, cluster := range entries {
+		for _, name := range cluster.Clusters {
+			if referencedClusters[name] {
+				glog.Warningf("warning: keeping cluster %s, a remaining context uses it", name)
+				continue
+			}
+			delete(kubeConfig.Clusters, name)
+			removed++
+		}
+		for _, name := range cluster.Users {
+			if referencedUsers[name] {
+				glog.Warningf("warning: keeping user %s, a remaining context uses it", name)
+				continue
+			}
+			delete(kubeConfig.AuthInfos, name)
+			removed++
+		}
+	}
+	return removed
+}
+
+func removeExecCredentialCache(tag kubeConfigTag) {
+	err := os.Remove(execCredentialCachePath(tag.Domain, tag.Project, tag.Cluster))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		glog.Warningf("warning: couldn't remove cached client certificate: %s", err)
+	}
+}
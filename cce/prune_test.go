@@ -0,0 +1,146 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"otc-auth/config"
+
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// taggedKubeConfig builds the entries get-kube-config writes for a cluster
+// and round-trips them through the kube config file format.
+func taggedKubeConfig(t *testing.T, tags ...kubeConfigTag) *api.Config {
+	t.Helper()
+	kubeConfig := api.NewConfig()
+	for _, tag := range tags {
+		alias := tag.Project + "/" + tag.Cluster
+		user := tag.Project + "-" + tag.Cluster + "-me"
+		clusterConfig := &api.Config{
+			Clusters: map[string]*api.Cluster{
+				alias:               {Server: "https://external"},
+				alias + "-intranet": {Server: "https://internal"},
+			},
+			AuthInfos: map[string]*api.AuthInfo{user: {Token: "token"}},
+			Contexts: map[string]*api.Context{
+				alias:               {Cluster: alias, AuthInfo: user},
+				alias + "-intranet": {Cluster: alias + "-intranet", AuthInfo: user},
+			},
+		}
+		if err := tagKubeConfigEntries(clusterConfig, tag); err != nil {
+			t.Fatal(err)
+		}
+		if err := merge(kubeConfig, *clusterConfig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an entry otc-auth didn't write
+	kubeConfig.Clusters["minikube"] = &api.Cluster{Server: "https://minikube"}
+	kubeConfig.AuthInfos["minikube"] = &api.AuthInfo{Token: "token"}
+	kubeConfig.Contexts["minikube"] = &api.Context{Cluster: "minikube", AuthInfo: "minikube"}
+
+	content, err := clientcmd.Write(*kubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := clientcmd.Load(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func Test_readKubeConfigTag(t *testing.T) {
+	t.Parallel()
+	tag := kubeConfigTag{Domain: "d", Project: "p", Cluster: "c", ClusterID: "id"}
+	kubeConfig := taggedKubeConfig(t, tag)
+
+	if got := readKubeConfigTag(kubeConfig.Contexts["p/c"].Extensions); got == nil || *got != tag {
+		t.Errorf("readKubeConfigTag(context) = %+v, want %+v", got, tag)
+	}
+	if got := readKubeConfigTag(kubeConfig.AuthInfos["p-c-me"].Extensions); got == nil || *got != tag {
+		t.Errorf("readKubeConfigTag(user) = %+v, want %+v", got, tag)
+	}
+	if got := readKubeConfigTag(kubeConfig.Clusters["minikube"].Extensions); got != nil {
+		t.Errorf("readKubeConfigTag(untagged) = %+v, want nil", got)
+	}
+}
+
+func Test_findStaleClusters(t *testing.T) {
+	t.Parallel()
+	kubeConfig := taggedKubeConfig(t,
+		kubeConfigTag{Domain: "d", Project: "p1", Cluster: "alive", ClusterID: "1"},
+		kubeConfigTag{Domain: "d", Project: "p1", Cluster: "deleted", ClusterID: "2"},
+		kubeConfigTag{Domain: "d", Project: "p1", Cluster: "recreated", ClusterID: "3"},
+		kubeConfigTag{Domain: "d", Project: "p2", Cluster: "unknown", ClusterID: "4"},
+		kubeConfigTag{Domain: "other", Project: "p1", Cluster: "foreign", ClusterID: "5"},
+	)
+
+	stale := findStaleClusters(kubeConfig, "d", func(projectName string) (config.Clusters, error) {
+		if projectName == "p1" {
+			return config.Clusters{{Name: "alive", ID: "1"}, {Name: "recreated", ID: "33"}}, nil
+		}
+		return nil, errors.New("forbidden")
+	})
+
+	want := []clusterEntries{
+		{
+			Tag:      kubeConfigTag{Domain: "d", Project: "p1", Cluster: "deleted", ClusterID: "2"},
+			Contexts: []string{"p1/deleted", "p1/deleted-intranet"},
+			Clusters: []string{"p1/deleted", "p1/deleted-intranet"},
+			Users:    []string{"p1-deleted-me"},
+		},
+		{
+			Tag:      kubeConfigTag{Domain: "d", Project: "p1", Cluster: "recreated", ClusterID: "3"},
+			Contexts: []string{"p1/recreated", "p1/recreated-intranet"},
+			Clusters: []string{"p1/recreated", "p1/recreated-intranet"},
+			Users:    []string{"p1-recreated-me"},
+		},
+	}
+	if !reflect.DeepEqual(stale, want) {
+		t.Errorf("findStaleClusters() = %+v, want %+v", stale, want)
+	}
+}
+
+func Test_removeClusterEntries(t *testing.T) {
+	t.Parallel()
+	tag := kubeConfigTag{Domain: "d", Project: "p", Cluster: "deleted", ClusterID: "1"}
+	kubeConfig := taggedKubeConfig(t, tag, kubeConfigTag{Domain: "d", Project: "p", Cluster: "alive", ClusterID: "2"})
+	kubeConfig.CurrentContext = "p/deleted"
+	// a hand written context sharing the user of the deleted cluster
+	kubeConfig.Contexts["custom"] = &api.Context{Cluster: "p/alive", AuthInfo: "p-deleted-me"}
+
+	removed := removeClusterEntries(kubeConfig, []clusterEntries{{
+		Tag:      tag,
+		Contexts: []string{"p/deleted", "p/deleted-intranet"},
+		Clusters: []string{"p/deleted", "p/deleted-intranet"},
+		Users:    []string{"p-deleted-me"},
+	}})
+
+	if removed != 4 {
+		t.Errorf("removeClusterEntries() = %d, want 4", removed)
+	}
+	if kubeConfig.CurrentContext != "" {
+		t.Errorf("current context = %q, want it unset", kubeConfig.CurrentContext)
+	}
+	for _, name := range []string{"p/deleted", "p/deleted-intranet"} {
+		if _, ok := kubeConfig.Contexts[name]; ok {
+			t.Errorf("context %s was kept", name)
+		}
+		if _, ok := kubeConfig.Clusters[name]; ok {
+			t.Errorf("cluster %s was kept", name)
+		}
+	}
+	if _, ok := kubeConfig.AuthInfos["p-deleted-me"]; !ok {
+		t.Error("user p-deleted-me was removed although a context uses it")
+	}
+	for _, name := range []string{"p/alive", "minikube"} {
+		if _, ok := kubeConfig.Contexts[name]; !ok {
+			t.Errorf("context %s was removed", name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cce
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"otc-auth/common"
+	"otc-auth/config"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// RemoveParams configures RemoveKubeConfig. At least one of ClusterName and
+// Alias is set.
+type RemoveParams struct {
+	ProjectName    string
+	ClusterName    string
+	Alias          string
+	TargetLocation string
+	DryRun         bool
+}
+
+// RemoveKubeConfig removes the contexts, clusters and users get-kube-config
+// wrote for a cluster, the -intranet variants included.
+func RemoveKubeConfig(params RemoveParams, out io.Writer) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		common.ThrowError(err)
+	}
+	location := determineTargetLocation(params.TargetLocation)
+	kubeConfig, err := clientcmd.LoadFromFile(location)
+	if err != nil {
+		common.ThrowError(fmt.Errorf("fatal: couldn't read kube config %s\ntrace: %w", location, err))
+	}
+
+	entries := findClusterEntries(kubeConfig, activeCloud.Domain.Name, activeCloud.Username, params)
+	if len(entries.Contexts)+len(entries.Clusters)+len(entries.Users) == 0 {
+		fmt.Fprintf(out, "no kube config entries of %s found in %s\n", entries.Tag.Cluster, location)
+		return
+	}
+	writeClusterEntries(out, "entries to remove:", []clusterEntries{entries})
+	if params.DryRun {
+		fmt.Fprintln(out, "dry run, nothing was removed")
+		return
+	}
+
+	currentContext := kubeConfig.CurrentContext
+	removed := removeClusterEntries(kubeConfig, []clusterEntries{entries})
+	if currentContext != "" && kubeConfig.CurrentContext == "" {
+		kubeConfig.CurrentContext = replacementContext(kubeConfig, currentContext)
+	}
+	if err = clientcmd.WriteToFile(*kubeConfig, location); err != nil {
+		common.ThrowError(fmt.Errorf("fatal: couldn't write kube config %s\ntrace: %w", location, err))
+	}
+	if entries.Tag.Project != "" {
+		removeExecCredentialCache(entries.Tag)
+	}
+	switch {
+	case currentContext == "" || currentContext == kubeConfig.CurrentContext:
+	case kubeConfig.CurrentContext == "":
+		fmt.Fprintf(out, "the current context %s was removed, no other context is left\n", currentContext)
+	default:
+		fmt.Fprintf(out, "the current context %s was removed, switched to %s\n", currentContext,
+			kubeConfig.CurrentContext)
+	}
+	fmt.Fprintf(out, "removed %d entries from %s\n", removed, location)
+}
+
+// replacementContext picks the context to switch to after the current one was
+// removed: its -intranet or external sibling if that is left, otherwise the
+// first remaining context by name. It is empty if no context is left.
+func replacementContext(kubeConfig *api.Config, removed string) string {
+	for _, sibling := range []string{removed + "-intranet", strings.TrimSuffix(removed, "-intranet")} {
+		if _, ok := kubeConfig.Contexts[sibling]; ok && sibling != removed {
+			return sibling
+		}
+	}
+	names := make([]string, 0, len(kubeConfig.Contexts))
+	for name := range kubeConfig.Contexts {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// findClusterEntries selects the entries named after the alias (project/cluster
+// by default) and, if the cluster name is given, all entries tagged with that
+// cluster. Tagged entries of other clusters are never selected, untagged
+// entries written by older versions are selected by name.
+func findClusterEntries(kubeConfig *api.Config, domainName string, username string,
+	params RemoveParams,
+) clusterEntries {
+	alias := params.Alias
+	if alias == "" {
+		alias = fmt.Sprintf("%s/%s", params.ProjectName, params.ClusterName)
+	}
+	entries := clusterEntries{Tag: kubeConfigTag{Cluster: alias}}
+	if params.ClusterName != "" {
+		entries.Tag = kubeConfigTag{Domain: domainName, Project: params.ProjectName, Cluster: params.ClusterName}
+	}
+
+	belongs := func(extensions map[string]runtime.Object, named bool) bool {
+		tag := readKubeConfigTag(extensions)
+		if tag == nil {
+			return named
+		}
+		if tag.Domain != domainName || tag.Project != params.ProjectName {
+			return false
+		}
+		if params.ClusterName == "" {
+			if named {
+				// learn the cluster from the tag to clean up its cached certificate
+				entries.Tag = *tag
+			}
+			return named
+		}
+		return tag.Cluster == params.ClusterName
+	}
+	isAliasName := func(name string) bool {
+		return name == alias || name == alias+"-intranet"
+	}
+
+	selectedUsers := map[string]bool{}
+	for name, context := range kubeConfig.Contexts {
+		if belongs(context.Extensions, isAliasName(name)) {
+			entries.Contexts = append(entries.Contexts, name)
+			selectedUsers[context.AuthInfo] = true
+		}
+	}
+	for name, cluster := range kubeConfig.Clusters {
+		if belongs(cluster.Extensions, isAliasName(name)) {
+			entries.Clusters = append(entries.Clusters, name)
+		}
+	}
+	defaultUser := ""
+	if params.ClusterName != "" {
+		defaultUser = fmt.Sprintf("%s-%s-%s", params.ProjectName, params.ClusterName, username)
+	}
+	for name, authInfo := range kubeConfig.AuthInfos {
+		if belongs(authInfo.Extensions, name == defaultUser || selectedUsers[name]) {
+			entries.Users = append(entries.Users, name)
+		}
+	}
+
+	sort.Strings(entries.Contexts)
+	sort.Strings(entries.Clusters)
+	sort.Strings(entries.Users)
+	return entries
+}
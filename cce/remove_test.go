@@ -0,0 +1,111 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_findClusterEntries(t *testing.T) {
+	t.Parallel()
+	kubeConfig := taggedKubeConfig(t,
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "c", ClusterID: "1"},
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "other", ClusterID: "2"},
+	)
+	// fetched with --alias my-alias by an older version, so untagged
+	kubeConfig.Clusters["my-alias"] = &api.Cluster{Server: "https://external"}
+	kubeConfig.Clusters["my-alias-intranet"] = &api.Cluster{Server: "https://internal"}
+	kubeConfig.AuthInfos["p-old-me"] = &api.AuthInfo{Token: "token"}
+	kubeConfig.Contexts["my-alias"] = &api.Context{Cluster: "my-alias", AuthInfo: "p-old-me"}
+	kubeConfig.Contexts["my-alias-intranet"] = &api.Context{Cluster: "my-alias-intranet", AuthInfo: "p-old-me"}
+
+	tests := []struct {
+		name   string
+		params RemoveParams
+		want   clusterEntries
+	}{
+		{
+			name:   "cluster",
+			params: RemoveParams{ProjectName: "p", ClusterName: "c"},
+			want: clusterEntries{
+				Tag:      kubeConfigTag{Domain: "d", Project: "p", Cluster: "c"},
+				Contexts: []string{"p/c", "p/c-intranet"},
+				Clusters: []string{"p/c", "p/c-intranet"},
+				Users:    []string{"p-c-me"},
+			},
+		},
+		{
+			name:   "alias of a tagged cluster",
+			params: RemoveParams{ProjectName: "p", Alias: "p/other"},
+			want: clusterEntries{
+				Tag:      kubeConfigTag{Domain: "d", Project: "p", Cluster: "other", ClusterID: "2"},
+				Contexts: []string{"p/other", "p/other-intranet"},
+				Clusters: []string{"p/other", "p/other-intranet"},
+				Users:    []string{"p-other-me"},
+			},
+		},
+		{
+			name:   "untagged alias",
+			params: RemoveParams{ProjectName: "p", Alias: "my-alias"},
+			want: clusterEntries{
+				Tag:      kubeConfigTag{Cluster: "my-alias"},
+				Contexts: []string{"my-alias", "my-alias-intranet"},
+				Clusters: []string{"my-alias", "my-alias-intranet"},
+				Users:    []string{"p-old-me"},
+			},
+		},
+		{
+			name:   "alias of a cluster in another project",
+			params: RemoveParams{ProjectName: "q", Alias: "p/c"},
+			want:   clusterEntries{Tag: kubeConfigTag{Cluster: "p/c"}},
+		},
+		{
+			name:   "unknown cluster",
+			params: RemoveParams{ProjectName: "p", ClusterName: "gone"},
+			want:   clusterEntries{Tag: kubeConfigTag{Domain: "d", Project: "p", Cluster: "gone"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := findClusterEntries(kubeConfig, "d", "me", tt.params)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findClusterEntries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_replacementContext(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		contexts []string
+		removed  string
+		want     string
+	}{
+		{
+			name:     "intranet sibling",
+			contexts: []string{"a/other", "p/prod-intranet"},
+			removed:  "p/prod",
+			want:     "p/prod-intranet",
+		},
+		{name: "external sibling", contexts: []string{"a/other", "p/prod"}, removed: "p/prod-intranet", want: "p/prod"},
+		{name: "first remaining", contexts: []string{"z/last", "a/first"}, removed: "p/prod", want: "a/first"},
+		{name: "none left", removed: "p/prod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			kubeConfig := api.NewConfig()
+			for _, name := range tt.contexts {
+				kubeConfig.Contexts[name] = api.NewContext()
+			}
+			if got := replacementContext(kubeConfig, tt.removed); got != tt.want {
+				t.Errorf("replacementContext() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"otc-auth/common"
+	"otc-auth/config"
+
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/cce/v3/clusters"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// RenewParams configures RenewKubeCerts.
+type RenewParams struct {
+	TargetLocation string
+	// PathTemplate finds the files of the split layout, which are renewed as
+	// well, none are looked for if empty
+	PathTemplate string
+	// Within renews certificates expiring within this duration
+	Within    time.Duration
+	DaysValid string
+}
+
+// renewResult is the outcome for a single user entry.
+type renewResult struct {
+	User     string
+	Tag      kubeConfigTag
+	Renewed  bool
+	NotAfter time.Time
+	Err      error
+}
+
+// RenewKubeCerts fetches new client certificates for the users otc-auth wrote
+// into the kube config at the target location and into the files of the split
+// layout, if theirs expire within the threshold. The entries are rewritten in
+// place, names and contexts stay as they are.
+func RenewKubeCerts(params RenewParams, out io.Writer) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		common.ThrowError(err)
+	}
+	locations := []string{determineTargetLocation(params.TargetLocation)}
+	if params.PathTemplate != "" {
+		splitFiles, errFind := FindManagedKubeConfigs(params.PathTemplate, activeCloud.Domain.Name)
+		if errFind != nil {
+			common.ThrowError(errFind)
+		}
+		for _, splitFile := range splitFiles {
+			if !slices.Contains(locations, splitFile) {
+				locations = append(locations, splitFile)
+			}
+		}
+	}
+
+	clients := newProjectClients(activeCloud)
+	renew := func(tag kubeConfigTag) (*execCredentialStatus, error) {
+		project, errProject := activeCloud.Projects.GetProjectByName(tag.Project)
+		if errProject != nil {
+			return nil, errProject
+		}
+		client, errClient := clients.get(*project)
+		if errClient != nil {
+			return nil, errClient
+		}
+		cert, errCert := certOfCluster(false,
+			func(refresh bool) (string, error) {
+				if !refresh && tag.ClusterID != "" {
+					return tag.ClusterID, nil
+				}
+				return getClusterID(tag.Cluster, tag.Project, refresh)
+			},
+			func(clusterID string) (*clusters.Certificate, error) {
+				return getCert(client, clusterID, params.DaysValid)
+			})
+		if errCert != nil {
+			return nil, errCert
+		}
+		rawConfig, errConfig := certToKubeConfig(cert)
+		if errConfig != nil {
+			return nil, errConfig
+		}
+		return execCredentialFromKubeConfig(rawConfig)
+	}
+
+	failed := 0
+	for index, location := range locations {
+		kubeConfig, errLoad := clientcmd.LoadFromFile(location)
+		if errLoad != nil {
+			// only the target location has to exist, the split files were found on disk
+			if index == 0 && len(locations) > 1 && errors.Is(errLoad, os.ErrNotExist) {
+				continue
+			}
+			common.ThrowError(fmt.Errorf("fatal: couldn't read kube config %s\ntrace: %w", location, errLoad))
+		}
+		failed += renewKubeConfigFile(kubeConfig, location, activeCloud.Domain.Name, params.Within, renew, out)
+	}
+	if failed > 0 {
+		common.ThrowError(fmt.Errorf("fatal: couldn't renew %d client certificates", failed))
+	}
+}
+
+// renewKubeConfigFile renews the certificates of one kube config, prints the
+// outcome of every user and writes the file if any was renewed. It returns
+// how many certificates couldn't be renewed.
+func renewKubeConfigFile(kubeConfig *api.Config, location string, domainName string, within time.Duration,
+	renew func(tag kubeConfigTag) (*execCredentialStatus, error), out io.Writer,
+) int {
+	results := renewKubeConfigCerts(kubeConfig, domainName, time.Now(), within, renew)
+	renewed, failed := 0, 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Fprintf(out, "failed   %s (%s/%s): %s\n", result.User, result.Tag.Project,
+				result.Tag.Cluster, result.Err)
+		case result.Renewed:
+			renewed++
+			fmt.Fprintf(out, "renewed  %s (%s/%s), valid until %s\n", result.User, result.Tag.Project,
+				result.Tag.Cluster, result.NotAfter.Format(common.PrintTimeFormat))
+		default:
+			fmt.Fprintf(out, "valid    %s (%s/%s) until %s\n", result.User, result.Tag.Project,
+				result.Tag.Cluster, result.NotAfter.Format(common.PrintTimeFormat))
+		}
+	}
+	if renewed > 0 {
+		if err := clientcmd.WriteToFile(*kubeConfig, location); err != nil {
+			common.ThrowError(fmt.Errorf("fatal: couldn't write kube config %s\ntrace: %w", location, err))
+		}
+	}
+	fmt.Fprintf(out, "renewed %d of %d client certificates in %s\n", renewed, len(results), location)
+	return failed
+}
+
+// renewKubeConfigCerts renews the static client certificates of the users
+// tagged with the domain which expire before now+within. Users of exec
+// entries fetch their certificates themselves and are left alone.
+func renewKubeConfigCerts(kubeConfig *api.Config, domainName string, now time.Time, within time.Duration,
+	renew func(tag kubeConfigTag) (*execCredentialStatus, error),
+) []renewResult {
+	var results []renewResult
+	for name, authInfo := range kubeConfig.AuthInfos {
+		tag := readKubeConfigTag(authInfo.Extensions)
+		if tag == nil || tag.Domain != domainName || len(authInfo.ClientCertificateData) == 0 {
+			continue
+		}
+		result := renewResult{User: name, Tag: *tag}
+		notAfter, err := certificateNotAfter(authInfo.ClientCertificateData)
+		if err == nil && now.Add(within).Before(notAfter) {
+			result.NotAfter = notAfter
+			results = append(results, result)
+			continue
+		}
+
+		credential, err := renew(*tag)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+		authInfo.ClientCertificateData = []byte(credential.ClientCertificateData)
+		authInfo.ClientKeyData = []byte(credential.ClientKeyData)
+		result.Renewed = true
+		result.NotAfter = credential.ExpirationTimestamp
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].User < results[j].User
+	})
+	return results
+}
@@ -0,0 +1,76 @@
+//nolint:testpackage // whitebox testing
+package cce
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_renewKubeConfigCerts(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	renewedUntil := now.Add(14 * 24 * time.Hour)
+	kubeConfig := taggedKubeConfig(t,
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "expiring", ClusterID: "1"},
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "valid", ClusterID: "2"},
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "broken", ClusterID: "3"},
+		kubeConfigTag{Domain: "d", Project: "p", Cluster: "exec", ClusterID: "4"},
+		kubeConfigTag{Domain: "other", Project: "p", Cluster: "foreign", ClusterID: "5"},
+	)
+	setCert := func(user string, notAfter time.Time) {
+		kubeConfig.AuthInfos[user].Token = ""
+		kubeConfig.AuthInfos[user].ClientCertificateData = selfSignedCertPEM(t, notAfter)
+		kubeConfig.AuthInfos[user].ClientKeyData = []byte("old key")
+	}
+	setCert("p-expiring-me", now.Add(time.Hour))
+	setCert("p-valid-me", now.Add(30*24*time.Hour))
+	setCert("p-broken-me", now.Add(-time.Hour))
+	setCert("p-foreign-me", now.Add(time.Hour))
+	kubeConfig.AuthInfos["p-exec-me"].Exec = &api.ExecConfig{Command: execCredentialCommand}
+	newCert := string(selfSignedCertPEM(t, renewedUntil))
+
+	var renewedClusters []string
+	results := renewKubeConfigCerts(kubeConfig, "d", now, 72*time.Hour,
+		func(tag kubeConfigTag) (*execCredentialStatus, error) {
+			renewedClusters = append(renewedClusters, tag.Cluster)
+			if tag.Cluster == "broken" {
+				return nil, errors.New("cluster unavailable")
+			}
+			return &execCredentialStatus{
+				ClientCertificateData: newCert,
+				ClientKeyData:         "new key",
+				ExpirationTimestamp:   renewedUntil,
+			}, nil
+		})
+
+	if len(results) != 3 {
+		t.Fatalf("renewKubeConfigCerts() = %+v, want results for broken, expiring and valid", results)
+	}
+	broken, expiring, valid := results[0], results[1], results[2]
+	if broken.User != "p-broken-me" || broken.Err == nil {
+		t.Errorf("broken = %+v, want an error", broken)
+	}
+	if expiring.User != "p-expiring-me" || !expiring.Renewed || !expiring.NotAfter.Equal(renewedUntil) {
+		t.Errorf("expiring = %+v, want it renewed until %s", expiring, renewedUntil)
+	}
+	if valid.User != "p-valid-me" || valid.Renewed || valid.Err != nil {
+		t.Errorf("valid = %+v, want it untouched", valid)
+	}
+
+	renewedUser := kubeConfig.AuthInfos["p-expiring-me"]
+	if string(renewedUser.ClientCertificateData) != newCert || string(renewedUser.ClientKeyData) != "new key" {
+		t.Error("the expiring user wasn't rewritten")
+	}
+	if readKubeConfigTag(renewedUser.Extensions) == nil {
+		t.Error("the renewed user lost its tag")
+	}
+	if string(kubeConfig.AuthInfos["p-foreign-me"].ClientKeyData) != "old key" {
+		t.Error("the user of another domain was renewed")
+	}
+	if len(renewedClusters) != 2 {
+		t.Errorf("renewed clusters = %v, want expiring and broken only", renewedClusters)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -37,3 +38,20 @@ func configureCmdFlagsAgainstEnvs(flagToEnvMapping map[string]string) func(*cobr
 		return initializeConfig(cmd, flagToEnvMapping)
 	}
 }
+
+/*
+requireFlags fails like a flag marked as required if one of the flags was neither passed nor set from its environment
+variable. It is meant for flags shared by several commands of which only some, or only some modes, need them.
+*/
+func requireFlags(cmd *cobra.Command, flags ...string) error {
+	var missing []string
+	for _, name := range flags {
+		if flag := cmd.Flags().Lookup(name); flag == nil || !flag.Changed {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf(`required flag(s) "%s" not set`, strings.Join(missing, `", "`))
+	}
+	return nil
+}
@@ -63,22 +63,45 @@ func TestFlagToEnvMaps_RequiredEntries(t *testing.T) {
 			mapName:   "loginIamFlagToEnv",
 			flagToEnv: loginIamFlagToEnv,
 			requiredFlags: map[string]string{
-				domainNameFlag: domainNameEnv,
-				usernameFlag:   usernameEnv,
-				passwordFlag:   passwordEnv,
-				userIDFlag:     userIDEnv,
-				regionFlag:     regionEnv,
+				domainNameFlag:   domainNameEnv,
+				usernameFlag:     usernameEnv,
+				passwordFlag:     passwordEnv,
+				passwordFileFlag: passwordFileEnv,
+				userIDFlag:       userIDEnv,
+				regionFlag:       regionEnv,
 			},
 		},
 		{
 			mapName:   "loginIdpSamlFlagToEnv",
 			flagToEnv: loginIdpSamlFlagToEnv,
 			requiredFlags: map[string]string{
+				domainNameFlag:   domainNameEnv,
+				usernameFlag:     usernameEnv,
+				passwordFlag:     passwordEnv,
+				passwordFileFlag: passwordFileEnv,
+				idpNameFlag:      idpNameEnv,
+				idpURLFlag:       idpURLEnv,
+				regionFlag:       regionEnv,
+				idpTypeFlag:      idpTypeEnv,
+			},
+		},
+		{
+			mapName:   "openstackConfigCreateFlagToEnv",
+			flagToEnv: openstackConfigCreateFlagToEnv,
+			requiredFlags: map[string]string{
+				passwordFlag:     passwordEnv,
+				passwordFileFlag: passwordFileEnv,
+				accessKeyFlag:    accessKeyEnv,
+				secretKeyFlag:    secretKeyEnv,
+			},
+		},
+		{
+			mapName:   "loginAkSkFlagToEnv",
+			flagToEnv: loginAkSkFlagToEnv,
+			requiredFlags: map[string]string{
+				accessKeyFlag:  accessKeyEnv,
+				secretKeyFlag:  secretKeyEnv,
 				domainNameFlag: domainNameEnv,
-				usernameFlag:   usernameEnv,
-				passwordFlag:   passwordEnv,
-				idpNameFlag:    idpNameEnv,
-				idpURLFlag:     idpURLEnv,
 				regionFlag:     regionEnv,
 			},
 		},
@@ -86,13 +109,16 @@ func TestFlagToEnvMaps_RequiredEntries(t *testing.T) {
 			mapName:   "loginIdpOidcFlagToEnv",
 			flagToEnv: loginIdpOidcFlagToEnv,
 			requiredFlags: map[string]string{
-				domainNameFlag:   domainNameEnv,
-				idpNameFlag:      idpNameEnv,
-				idpURLFlag:       idpURLEnv,
-				regionFlag:       regionEnv,
-				clientIDFlag:     clientIDEnv,
-				clientSecretFlag: clientSecretEnv,
-				oidcScopesFlag:   oidcScopesEnv,
+				domainNameFlag:     domainNameEnv,
+				idpNameFlag:        idpNameEnv,
+				idpURLFlag:         idpURLEnv,
+				regionFlag:         regionEnv,
+				clientIDFlag:       clientIDEnv,
+				clientSecretFlag:   clientSecretEnv,
+				oidcScopesFlag:     oidcScopesEnv,
+				idTokenFileFlag:    idTokenFileEnv,
+				idTokenEnvFlag:     idTokenEnvEnv,
+				idTokenCommandFlag: idTokenCommandEnv,
 			},
 		},
 		{
@@ -117,12 +143,35 @@ func TestFlagToEnvMaps_RequiredEntries(t *testing.T) {
 				regionFlag: regionEnv,
 			},
 		},
+		{
+			mapName:   "cceRemoveKubeConfigFlagToEnv",
+			flagToEnv: cceRemoveKubeConfigFlagToEnv,
+			requiredFlags: map[string]string{
+				clusterNameFlag: clusterNameEnv,
+			},
+		},
+		{
+			mapName:   "cceExecCredentialFlagToEnv",
+			flagToEnv: cceExecCredentialFlagToEnv,
+			requiredFlags: map[string]string{
+				clusterNameFlag: clusterNameEnv,
+			},
+		},
 		{
 			mapName:   "cceGetKubeConfigFlagToEnv",
 			flagToEnv: cceGetKubeConfigFlagToEnv,
 			requiredFlags: map[string]string{
-				clusterNameFlag: clusterNameEnv,
-				regionFlag:      regionEnv,
+				clusterNameFlag:            clusterNameEnv,
+				regionFlag:                 regionEnv,
+				kubeConfigLayoutFlag:       kubeConfigLayoutEnv,
+				kubeConfigPathTemplateFlag: kubeConfigPathTemplateEnv,
+			},
+		},
+		{
+			mapName:   "cceKubeConfigPathFlagToEnv",
+			flagToEnv: cceKubeConfigPathFlagToEnv,
+			requiredFlags: map[string]string{
+				kubeConfigPathTemplateFlag: kubeConfigPathTemplateEnv,
 			},
 		},
 		{
@@ -132,6 +181,22 @@ func TestFlagToEnvMaps_RequiredEntries(t *testing.T) {
 				domainNameFlag: domainNameEnv,
 			},
 		},
+		{
+			mapName:   "tempAccessTokenFlagToEnv",
+			flagToEnv: tempAccessTokenFlagToEnv,
+			requiredFlags: map[string]string{
+				domainNameFlag:  domainNameEnv,
+				projectNameFlag: projectNameEnv,
+			},
+		},
+		{
+			mapName:   "serveCredentialsFlagToEnv",
+			flagToEnv: serveCredentialsFlagToEnv,
+			requiredFlags: map[string]string{
+				domainNameFlag:  domainNameEnv,
+				projectNameFlag: projectNameEnv,
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -151,3 +216,23 @@ func TestFlagToEnvMaps_RequiredEntries(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireFlags(t *testing.T) {
+	t.Setenv(domainNameEnv, "FromEnv")
+
+	var domainVal, projectVal string
+	cmd := &cobra.Command{Use: "test-cmd"}
+	cmd.Flags().StringVar(&domainVal, domainNameFlag, "", "")
+	cmd.Flags().StringVar(&projectVal, projectNameFlag, "", "")
+	if err := initializeConfig(cmd, cceFlagToEnv); err != nil {
+		t.Fatalf("initializeConfig returned error: %v", err)
+	}
+
+	if err := requireFlags(cmd, domainNameFlag); err != nil {
+		t.Errorf("requireFlags() = %v, want the flag set from the env to count", err)
+	}
+	err := requireFlags(cmd, domainNameFlag, projectNameFlag)
+	if err == nil || err.Error() != `required flag(s) "`+projectNameFlag+`" not set` {
+		t.Errorf("requireFlags() = %v, want %s reported as missing", err, projectNameFlag)
+	}
+}
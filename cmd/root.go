@@ -22,10 +22,12 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"otc-auth/accesstoken"
@@ -35,11 +37,11 @@ import (
 	"otc-auth/iam"
 	"otc-auth/login"
 	"otc-auth/openstack"
+	"otc-auth/saml"
 
 	"github.com/golang/glog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
 
@@ -76,20 +78,34 @@ var loginIamCmd = &cobra.Command{
 				usernameFlag, userIDFlag))
 		}
 
+		secrets := newSecretReader()
+		loginPassword, err := secrets.password(password, passwordStdin, passwordFile)
+		if err != nil {
+			common.ThrowError(err)
+		}
+		otp := totp
+		if userID != "" {
+			// logging in by user id is how MFA is done, so ask for the code
+			otp, err = secrets.totp(totp)
+			if err != nil {
+				common.ThrowError(err)
+			}
+		}
+
 		loginCtx, cancel := context.WithTimeout(cmd.Context(), loginTimeout)
 		defer cancel()
 		authInfo := common.AuthInfo{
 			AuthType:      common.AuthTypeIAM,
 			Username:      username,
-			Password:      password,
+			Password:      loginPassword,
 			DomainName:    domainName,
-			Otp:           totp,
+			Otp:           otp,
 			UserID:        userID,
 			OverwriteFile: overwriteToken,
 			Region:        region,
 			SkipTLS:       skipTLS,
 		}
-		err := login.AuthenticateAndGetUnscopedToken(loginCtx, authInfo)
+		err = login.AuthenticateAndGetUnscopedToken(loginCtx, authInfo)
 		if err != nil {
 			common.ThrowError(err)
 		}
@@ -102,13 +118,32 @@ var loginIdpSamlCmd = &cobra.Command{
 	Example: loginIdpSamlCmdExample,
 	PreRunE: configureCmdFlagsAgainstEnvs(loginIdpSamlFlagToEnv),
 	Run: func(cmd *cobra.Command, args []string) {
+		loginPassword := password
+		if samlBrowser && idpURL == "" {
+			common.ThrowError(fmt.Errorf(
+				"--%s is required, with --%s it is the single sign-on URL of the IdP",
+				idpURLFlag, samlBrowserFlag))
+		}
+		if !samlBrowser {
+			if username == "" || idpURL == "" {
+				common.ThrowError(fmt.Errorf(
+					"--%s and --%s are required unless logging in with --%s",
+					usernameFlag, idpURLFlag, samlBrowserFlag))
+			}
+			var err error
+			loginPassword, err = newSecretReader().password(password, passwordStdin, passwordFile)
+			if err != nil {
+				common.ThrowError(err)
+			}
+		}
+
 		loginCtx, cancel := context.WithTimeout(cmd.Context(), loginTimeout)
 		defer cancel()
 
 		authInfo := common.AuthInfo{
 			AuthType:      common.AuthTypeIDP,
 			Username:      username,
-			Password:      password,
+			Password:      loginPassword,
 			DomainName:    domainName,
 			IdpName:       idpName,
 			IdpURL:        idpURL,
@@ -116,6 +151,10 @@ var loginIdpSamlCmd = &cobra.Command{
 			OverwriteFile: overwriteToken,
 			Region:        region,
 			SkipTLS:       skipTLS,
+			SamlBrowser:   samlBrowser,
+			SamlIdpType:   samlIdpType,
+			SamlListen:    samlListen,
+			Otp:           totp,
 		}
 		err := login.AuthenticateAndGetUnscopedToken(loginCtx, authInfo)
 		if err != nil {
@@ -130,6 +169,23 @@ var loginIdpOidcCmd = &cobra.Command{
 	Example: loginIdpOidcCmdExample,
 	PreRunE: configureCmdFlagsAgainstEnvs(loginIdpOidcFlagToEnv),
 	Run: func(cmd *cobra.Command, args []string) {
+		idTokenSources := 0
+		for _, source := range []string{idTokenFile, idTokenEnv, idTokenCommand} {
+			if source != "" {
+				idTokenSources++
+			}
+		}
+		if idTokenSources > 1 {
+			common.ThrowError(fmt.Errorf(
+				"only one of --%s, --%s or --%s may be set",
+				idTokenFileFlag, idTokenEnvFlag, idTokenCommandFlag))
+		}
+		if idTokenSources == 0 && (clientID == "" || idpURL == "") {
+			common.ThrowError(fmt.Errorf(
+				"--%s and --%s are required unless an external id token is passed via --%s, --%s or --%s",
+				clientIDFlag, idpURLFlag, idTokenFileFlag, idTokenEnvFlag, idTokenCommandFlag))
+		}
+
 		loginCtx, cancel := context.WithTimeout(cmd.Context(), loginTimeout)
 		defer cancel()
 
@@ -146,6 +202,9 @@ var loginIdpOidcCmd = &cobra.Command{
 			OidcScopes:       oidcScopes,
 			IsServiceAccount: isServiceAccount,
 			SkipTLS:          skipTLS,
+			IDTokenFile:      idTokenFile,
+			IDTokenEnv:       idTokenEnv,
+			IDTokenCommand:   idTokenCommand,
 		}
 		err := login.AuthenticateAndGetUnscopedToken(loginCtx, authInfo)
 		if err != nil {
@@ -154,6 +213,32 @@ var loginIdpOidcCmd = &cobra.Command{
 	},
 }
 
+var loginAkSkCmd = &cobra.Command{
+	Use:     "aksk",
+	Short:   loginAkSkCmdHelp,
+	Example: loginAkSkCmdExample,
+	PreRunE: configureCmdFlagsAgainstEnvs(loginAkSkFlagToEnv),
+	Run: func(cmd *cobra.Command, args []string) {
+		if accessKey == "" {
+			common.ThrowError(fmt.Errorf("fatal: --%s (or %s) is required", accessKeyFlag, accessKeyEnv))
+		}
+		loginSecretKey, err := newSecretReader().secret(secretKey, "Secret key: ", secretKeyFlag, secretKeyEnv)
+		if err != nil {
+			common.ThrowError(err)
+		}
+
+		authInfo := common.AuthInfo{
+			AccessKey:  accessKey,
+			SecretKey:  loginSecretKey,
+			DomainName: domainName,
+			Region:     region,
+		}
+		if err = login.AuthenticateWithAccessKey(authInfo); err != nil {
+			common.ThrowError(err)
+		}
+	},
+}
+
 var loginRemoveCmd = &cobra.Command{
 	Use:     "remove",
 	Short:   loginRemoveCmdHelp,
@@ -191,7 +276,12 @@ var cceListCmd = &cobra.Command{
 	Use:     cmdUseList,
 	Short:   cceListCmdHelp,
 	Example: cceListCmdExample,
-	PreRunE: configureCmdFlagsAgainstEnvs(cceListFlagToEnv),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := configureCmdFlagsAgainstEnvs(cceListFlagToEnv)(cmd, args); err != nil {
+			return err
+		}
+		return requireFlags(cmd, domainNameFlag, projectNameFlag)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		err := config.LoadCloudConfig(domainName)
 		if err != nil {
@@ -202,31 +292,80 @@ var cceListCmd = &cobra.Command{
 				errors.New("fatal: no valid unscoped token found." +
 					"\n\nPlease obtain an unscoped token by logging in first"))
 		}
-		clusterList := cce.GetClusterNames(projectName)
+		if !slices.Contains(cce.ClusterListFormats(), clusterListFormat) {
+			common.ThrowError(fmt.Errorf("fatal: unknown output format %s, use one of %s",
+				clusterListFormat, strings.Join(cce.ClusterListFormats(), ", ")))
+		}
+		clusterList := cce.ListClusters(projectName)
 		if len(clusterList) == 0 {
 			glog.V(common.InfoLogLevel).Infof("info: no CCE clusters found for project %s", projectName)
-			return
+			if clusterListFormat != cce.ClusterListFormatJSON && clusterListFormat != cce.ClusterListFormatYAML {
+				return
+			}
 		}
-		_, err = os.Stdout.WriteString(strings.Join(clusterList.GetClusterNames(), "\n") + "\n")
-		if err != nil {
-			common.ThrowError(errors.New("error writing cluster names to STDOUT"))
+		if err = cce.WriteClusterInfos(cmd.OutOrStdout(), clusterList, clusterListFormat); err != nil {
+			common.ThrowError(fmt.Errorf("fatal: error writing cluster list\ntrace: %w", err))
 		}
 	},
 }
 
 var cceCheckKubeConfigCmd = &cobra.Command{
-	Use:     "check-kube-certs",
+	Use:     "check-kube-certs [kubeconfig or directory...]",
 	Short:   cceCheckKubeCertsCmdHelp,
 	Example: cceCheckKubeCertsCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// the report only reads local files, renewing needs the domain
+		if !renewKubeCerts {
+			return nil
+		}
+		return requireFlags(cmd, domainNameFlag)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		currentConfig, err := clientcmd.NewDefaultClientConfigLoadingRules().GetStartingConfig()
-		if err != nil {
-			common.ThrowError(err)
+		if renewKubeCerts {
+			if len(args) > 0 {
+				common.ThrowError(errors.New("fatal: --renew works on --target-location and --path-template, " +
+					"it doesn't take paths"))
+			}
+			err := config.LoadCloudConfig(domainName)
+			if err != nil {
+				common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+			}
+			if !config.IsAuthenticationValid() {
+				common.ThrowError(
+					errors.New("fatal: no valid unscoped token found." +
+						"\n\nPlease obtain an unscoped token by logging in first"))
+			}
+			if strings.HasPrefix(targetLocation, "~") {
+				targetLocation = strings.Replace(targetLocation, "~", homedir.HomeDir(), 1)
+			}
+			cce.RenewKubeCerts(cce.RenewParams{
+				TargetLocation: targetLocation,
+				PathTemplate:   kubeConfigPathTemplate,
+				Within:         renewWithin,
+				DaysValid:      strconv.Itoa(daysValid),
+			}, cmd.OutOrStdout())
+			return
+		}
+		if !slices.Contains(cce.CertReportFormats(), certReportFormat) {
+			common.ThrowError(fmt.Errorf("fatal: unknown output format %s, use one of %s",
+				certReportFormat, strings.Join(cce.CertReportFormats(), ", ")))
+		}
+		paths := make([]string, 0, len(args))
+		for _, path := range args {
+			if strings.HasPrefix(path, "~") {
+				path = strings.Replace(path, "~", homedir.HomeDir(), 1)
+			}
+			paths = append(paths, path)
 		}
-		if currentConfig == nil {
-			common.ThrowError(errors.New(""))
+		exitCode := cce.CheckKubeCerts(cce.CertReportParams{
+			Paths:  paths,
+			Within: expiringWithin,
+			Format: certReportFormat,
+		}, cmd.OutOrStdout())
+		if exitCode != 0 {
+			glog.Flush()
+			os.Exit(exitCode)
 		}
-		cce.CheckAndWarnCertsValidity(*currentConfig)
 	},
 }
 
@@ -234,7 +373,16 @@ var cceGetKubeConfigCmd = &cobra.Command{
 	Use:     "get-kube-config",
 	Short:   cceGetKubeConfigCmdHelp,
 	Example: cceGetKubeConfigCmdExample,
-	PreRunE: configureCmdFlagsAgainstEnvs(cceGetKubeConfigFlagToEnv),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := configureCmdFlagsAgainstEnvs(cceGetKubeConfigFlagToEnv)(cmd, args); err != nil {
+			return err
+		}
+		if allClusters {
+			// --all walks every project and cluster, the filters replace both names
+			return requireFlags(cmd, domainNameFlag)
+		}
+		return requireFlags(cmd, domainNameFlag, projectNameFlag, clusterNameFlag)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		err := config.LoadCloudConfig(domainName)
 		if err != nil {
@@ -252,28 +400,227 @@ var cceGetKubeConfigCmd = &cobra.Command{
 			targetLocation = strings.Replace(targetLocation, "~", homedir.HomeDir(), 1)
 		}
 
+		if !slices.Contains(cce.KubeConfigLayouts(), kubeConfigLayout) {
+			common.ThrowError(fmt.Errorf("fatal: unknown layout %s, use one of %s",
+				kubeConfigLayout, strings.Join(cce.KubeConfigLayouts(), ", ")))
+		}
+		if kubeConfigLayout == cce.KubeConfigLayoutSplit && cmd.Flags().Changed(targetLocationFlag) {
+			common.ThrowError(fmt.Errorf("fatal: --%s can't be used with --%s %s, use --%s instead",
+				targetLocationFlag, kubeConfigLayoutFlag, cce.KubeConfigLayoutSplit, kubeConfigPathTemplateFlag))
+		}
+
+		if !slices.Contains(cce.KubeEndpoints(), kubeEndpoints) {
+			common.ThrowError(fmt.Errorf("fatal: unknown endpoints %s, use one of %s",
+				kubeEndpoints, strings.Join(cce.KubeEndpoints(), ", ")))
+		}
+		if alias != "" && cmd.Flags().Changed(kubeContextTemplateFlag) {
+			common.ThrowError(fmt.Errorf("fatal: --%s and --%s can't be used together",
+				aliasFlag, kubeContextTemplateFlag))
+		}
+		// every cluster has its own client certificate, a shared user would
+		// be overwritten by the next cluster
+		if !strings.Contains(kubeUserTemplate, "{cluster}") {
+			common.ThrowError(fmt.Errorf("fatal: --%s must contain {cluster}", kubeUserTemplateFlag))
+		}
+
 		kubeConfigParams := cce.KubeConfigParams{
+			ProjectName:        projectName,
+			ClusterName:        clusterName,
+			DaysValid:          daysValidString,
+			TargetLocation:     targetLocation,
+			Server:             server,
+			ExecCredential:     kubeExecCredential,
+			RefreshClusters:    refreshClusters,
+			Layout:             kubeConfigLayout,
+			PathTemplate:       kubeConfigPathTemplate,
+			Endpoints:          kubeEndpoints,
+			KeepCurrentContext: keepCurrentContext,
+			Namespace:          kubeNamespace,
+			ContextTemplate:    kubeContextTemplate,
+			UserTemplate:       kubeUserTemplate,
+		}
+
+		if allClusters {
+			if alias != "" || server != "" {
+				common.ThrowError(fmt.Errorf("fatal: --%s and --%s can't be used with --%s",
+					aliasFlag, serverFlag, allClustersFlag))
+			}
+			// clusters of different projects may share a name, their entries
+			// would replace each other in the merged kube config
+			for _, template := range []struct{ flag, value string }{
+				{kubeContextTemplateFlag, kubeContextTemplate},
+				{kubeUserTemplateFlag, kubeUserTemplate},
+			} {
+				if !strings.Contains(template.value, "{project}") || !strings.Contains(template.value, "{cluster}") {
+					common.ThrowError(fmt.Errorf("fatal: --%s must contain {project} and {cluster} with --%s",
+						template.flag, allClustersFlag))
+				}
+			}
+			cce.GetAllKubeConfigs(kubeConfigParams, cce.ClusterFilter{
+				Projects: projectFilter,
+				Clusters: clusterFilter,
+			}, skipKubeTLS, printKubeConfig, cmd.ErrOrStderr())
+			return
+		}
+		cce.GetKubeConfig(kubeConfigParams, skipKubeTLS, printKubeConfig, alias)
+	},
+}
+
+var cceExecCredentialCmd = &cobra.Command{
+	Use:     "exec-credential",
+	Short:   cceExecCredentialCmdHelp,
+	Example: cceExecCredentialCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := configureCmdFlagsAgainstEnvs(cceExecCredentialFlagToEnv)(cmd, args); err != nil {
+			return err
+		}
+		return requireFlags(cmd, domainNameFlag, projectNameFlag)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.LoadCloudConfig(domainName)
+		if err != nil {
+			common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+		}
+		if !config.IsAuthenticationValid() {
+			common.ThrowError(
+				errors.New("fatal: no valid unscoped token found." +
+					"\n\nPlease obtain an unscoped token by logging in first"))
+		}
+
+		cce.GetExecCredential(cce.KubeConfigParams{
+			ProjectName:     projectName,
+			ClusterName:     clusterName,
+			DaysValid:       strconv.Itoa(daysValid),
+			RefreshClusters: refreshClusters,
+		}, cmd.OutOrStdout())
+	},
+}
+
+var ccePruneKubeConfigCmd = &cobra.Command{
+	Use:     "prune-kube-config",
+	Short:   ccePruneKubeConfigCmdHelp,
+	Example: ccePruneKubeConfigCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// all projects of the cloud are checked
+		return requireFlags(cmd, domainNameFlag)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.LoadCloudConfig(domainName)
+		if err != nil {
+			common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+		}
+		if !config.IsAuthenticationValid() {
+			common.ThrowError(
+				errors.New("fatal: no valid unscoped token found." +
+					"\n\nPlease obtain an unscoped token by logging in first"))
+		}
+
+		if strings.HasPrefix(targetLocation, "~") {
+			targetLocation = strings.Replace(targetLocation, "~", homedir.HomeDir(), 1)
+		}
+		cce.PruneKubeConfig(cce.PruneParams{
+			TargetLocation: targetLocation,
+			DryRun:         dryRun,
+			Yes:            assumeYes,
+		}, cmd.OutOrStdout())
+	},
+}
+
+var cceRemoveKubeConfigCmd = &cobra.Command{
+	Use:     "remove-kube-config",
+	Short:   cceRemoveKubeConfigCmdHelp,
+	Example: cceRemoveKubeConfigCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := configureCmdFlagsAgainstEnvs(cceRemoveKubeConfigFlagToEnv)(cmd, args); err != nil {
+			return err
+		}
+		return requireFlags(cmd, domainNameFlag, projectNameFlag)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if clusterName == "" && alias == "" {
+			common.ThrowError(fmt.Errorf("fatal: either --%s (or %s) or --%s is required",
+				clusterNameFlag, clusterNameEnv, aliasFlag))
+		}
+		// only local files are changed, an expired login is fine
+		err := config.LoadCloudConfig(domainName)
+		if err != nil {
+			common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+		}
+
+		if strings.HasPrefix(targetLocation, "~") {
+			targetLocation = strings.Replace(targetLocation, "~", homedir.HomeDir(), 1)
+		}
+		cce.RemoveKubeConfig(cce.RemoveParams{
 			ProjectName:    projectName,
 			ClusterName:    clusterName,
-			DaysValid:      daysValidString,
+			Alias:          alias,
 			TargetLocation: targetLocation,
-			Server:         server,
-		}
+			DryRun:         dryRun,
+		}, cmd.OutOrStdout())
+	},
+}
 
-		cce.GetKubeConfig(kubeConfigParams, skipKubeTLS, printKubeConfig, alias)
+var cceKubeConfigPathCmd = &cobra.Command{
+	Use:     "kube-config-path",
+	Short:   cceKubeConfigPathCmdHelp,
+	Example: cceKubeConfigPathCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// only local files are read, the domain merely narrows them down
+		return configureCmdFlagsAgainstEnvs(cceKubeConfigPathFlagToEnv)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(cmd.OutOrStdout(), cce.KubeConfigPathValue(kubeConfigPathTemplate, domainName,
+			includeDefaultKubeConfig))
 	},
 }
 
 var tempAccessTokenCmd = &cobra.Command{
 	Use:               "temp-access-token",
 	Short:             accessTokenCmdHelp,
-	PersistentPreRunE: configureCmdFlagsAgainstEnvs(accessTokenFlagToEnv),
+	PersistentPreRunE: configureCmdFlagsAgainstEnvs(tempAccessTokenFlagToEnv),
 }
 
 var tempAccessTokenCreateCmd = &cobra.Command{
 	Use:     "create",
 	Short:   tempAccessTokenCreateCmdHelp,
 	Example: tempAccessTokenCreateCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return requireFlags(cmd, domainNameFlag)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.LoadCloudConfig(domainName)
+		if err != nil {
+			common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+		}
+		if !config.IsAuthenticationValid() {
+			return errors.New(
+				"fatal: no valid unscoped token found, please obtain an unscoped token by logging in first",
+			)
+		}
+
+		if err = validateTemporaryAccessTokenDuration(); err != nil {
+			return err
+		}
+		output, err := akSkOutputOptions()
+		if err != nil {
+			return err
+		}
+		params, err := temporaryAccessTokenParams()
+		if err != nil {
+			return err
+		}
+		return accesstoken.CreateTemporaryAccessToken(params, output)
+	},
+}
+
+var tempAccessTokenCredentialProcessCmd = &cobra.Command{
+	Use:     "credential-process",
+	Short:   tempAccessTokenCredentialProcessCmdHelp,
+	Long:    tempAccessTokenCredentialProcessCmdLong,
+	Example: tempAccessTokenCredentialProcessCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return requireFlags(cmd, domainNameFlag)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		err := config.LoadCloudConfig(domainName)
 		if err != nil {
@@ -284,14 +631,179 @@ var tempAccessTokenCreateCmd = &cobra.Command{
 				"fatal: no valid unscoped token found, please obtain an unscoped token by logging in first",
 			)
 		}
+		if err = validateTemporaryAccessTokenDuration(); err != nil {
+			return err
+		}
+		params, err := temporaryAccessTokenParams()
+		if err != nil {
+			return err
+		}
+		return accesstoken.PrintCredentialProcess(params, cmd.OutOrStdout())
+	},
+}
+
+var tempAccessTokenPurgeCmd = &cobra.Command{
+	Use:     "purge",
+	Short:   tempAccessTokenPurgeCmdHelp,
+	Example: tempAccessTokenPurgeCmdExample,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if purgeAllDomains {
+			return nil
+		}
+		return requireFlags(cmd, domainNameFlag)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		purgedDomain := domainName
+		if purgeAllDomains {
+			purgedDomain = ""
+		}
+		purged, err := accesstoken.PurgeTemporaryCredentialCache(purgedDomain)
+		if err != nil {
+			common.ThrowError(err)
+		}
+		glog.V(common.InfoLogLevel).Infof("info: removed %d cached temporary access keys", purged)
+	},
+}
 
-		if temporaryAccessTokenDurationSeconds < 900 || temporaryAccessTokenDurationSeconds > 86400 {
-			return errors.New("fatal: token duration must be between 900 and 86400 seconds (15m and 24h)")
+var serveCredentialsCmd = &cobra.Command{
+	Use:               "serve-credentials",
+	Short:             serveCredentialsCmdHelp,
+	Long:              serveCredentialsCmdLong,
+	Example:           serveCredentialsCmdExample,
+	PersistentPreRunE: configureCmdFlagsAgainstEnvs(serveCredentialsFlagToEnv),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.LoadCloudConfig(domainName)
+		if err != nil {
+			common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+		}
+		if !config.IsAuthenticationValid() {
+			return errors.New(
+				"fatal: no valid unscoped token found, please obtain an unscoped token by logging in first",
+			)
+		}
+		if err = validateTemporaryAccessTokenDuration(); err != nil {
+			return err
+		}
+		params, err := temporaryAccessTokenParams()
+		if err != nil {
+			return err
 		}
-		return accesstoken.CreateTemporaryAccessToken(temporaryAccessTokenDurationSeconds, printAkSk)
+		authToken, err := serveAuthToken()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return accesstoken.ServeCredentials(ctx, accesstoken.ServeParams{
+			Address:   serveAddress,
+			AuthToken: authToken,
+			Temporary: params,
+		}, cmd.OutOrStdout())
 	},
 }
 
+// serveAuthToken reads the auth token of serve-credentials from
+// --auth-token-file, a random one is generated if it isn't set.
+func serveAuthToken() (string, error) {
+	if serveAuthTokenFile == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(serveAuthTokenFile, "~") {
+		serveAuthTokenFile = strings.Replace(serveAuthTokenFile, "~", homedir.HomeDir(), 1)
+	}
+	content, err := os.ReadFile(serveAuthTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("fatal: error reading auth token file\ntrace: %w", err)
+	}
+	authToken := strings.TrimSpace(string(content))
+	if authToken == "" {
+		return "", fmt.Errorf("fatal: no auth token found in %s", serveAuthTokenFile)
+	}
+	return authToken, nil
+}
+
+func validateTemporaryAccessTokenDuration() error {
+	if temporaryAccessTokenDurationSeconds < 900 || temporaryAccessTokenDurationSeconds > 86400 {
+		return errors.New("fatal: token duration must be between 900 and 86400 seconds (15m and 24h)")
+	}
+	return nil
+}
+
+// temporaryAccessTokenParams collects the flags selecting how temporary
+// AK/SKs are issued.
+func temporaryAccessTokenParams() (accesstoken.TemporaryParams, error) {
+	if !slices.Contains(accesstoken.TemporaryMethods(), temporaryMethod) {
+		return accesstoken.TemporaryParams{}, fmt.Errorf("fatal: unknown value %s for --%s, use one of %s",
+			temporaryMethod, temporaryMethodFlag, strings.Join(accesstoken.TemporaryMethods(), ", "))
+	}
+	if temporaryMethod == accesstoken.TemporaryMethodAgency && agencyName == "" {
+		return accesstoken.TemporaryParams{}, fmt.Errorf("fatal: --%s %s needs --%s",
+			temporaryMethodFlag, accesstoken.TemporaryMethodAgency, agencyNameFlag)
+	}
+	if temporaryMethod == accesstoken.TemporaryMethodToken && (agencyName != "" || agencyDomainName != "") {
+		return accesstoken.TemporaryParams{}, fmt.Errorf("fatal: --%s and --%s need --%s %s",
+			agencyNameFlag, agencyDomainNameFlag, temporaryMethodFlag, accesstoken.TemporaryMethodAgency)
+	}
+	if strings.HasPrefix(sessionPolicyFile, "~") {
+		sessionPolicyFile = strings.Replace(sessionPolicyFile, "~", homedir.HomeDir(), 1)
+	}
+	policy, err := accesstoken.ReadSessionPolicy(sessionPolicy, sessionPolicyFile)
+	if err != nil {
+		return accesstoken.TemporaryParams{}, err
+	}
+	return accesstoken.TemporaryParams{
+		DurationSeconds:  temporaryAccessTokenDurationSeconds,
+		ProjectName:      projectName,
+		ForceNew:         forceNewAccessToken,
+		Method:           temporaryMethod,
+		AgencyName:       agencyName,
+		AgencyDomainName: agencyDomainName,
+		Policy:           policy,
+	}, nil
+}
+
+func addTemporaryAccessTokenFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&temporaryMethod, temporaryMethodFlag, "", accesstoken.TemporaryMethodToken,
+		temporaryMethodUsage)
+	cmd.Flags().StringVarP(&agencyName, agencyNameFlag, "", "", agencyNameUsage)
+	cmd.Flags().StringVarP(&agencyDomainName, agencyDomainNameFlag, "", "", agencyDomainNameUsage)
+	cmd.Flags().StringVarP(&sessionPolicy, sessionPolicyFlag, "", "", sessionPolicyUsage)
+	cmd.Flags().StringVarP(&sessionPolicyFile, sessionPolicyFileFlag, "", "", sessionPolicyFileUsage)
+	cmd.MarkFlagsMutuallyExclusive(sessionPolicyFlag, sessionPolicyFileFlag)
+}
+
+// akSkOutputOptions collects the flags selecting how created AK/SKs are
+// written.
+func akSkOutputOptions() (accesstoken.OutputOptions, error) {
+	if !slices.Contains(accesstoken.Formats(), akSkFormat) {
+		return accesstoken.OutputOptions{}, fmt.Errorf("fatal: unknown format %s, use one of %s",
+			akSkFormat, strings.Join(accesstoken.Formats(), ", "))
+	}
+	return accesstoken.OutputOptions{
+		Format:  akSkFormat,
+		Path:    akSkPath,
+		Profile: akSkProfile,
+		Print:   printAkSk,
+	}, nil
+}
+
+// accessTokenUser selects the user whose permanent AK/SKs are managed.
+func accessTokenUser() accesstoken.UserSelector {
+	return accesstoken.UserSelector{Name: accessTokenUserName, ID: accessTokenUserID}
+}
+
+func addAccessTokenUserFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&accessTokenUserName, accessTokenUserNameFlag, "", "", accessTokenUserNameUsage)
+	cmd.Flags().StringVarP(&accessTokenUserID, accessTokenUserIDFlag, "", "", accessTokenUserIDUsage)
+	cmd.MarkFlagsMutuallyExclusive(accessTokenUserNameFlag, accessTokenUserIDFlag)
+}
+
+func addAkSkOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&akSkFormat, akSkFormatFlag, "", accesstoken.FormatShell, akSkFormatUsage)
+	cmd.Flags().StringVarP(&akSkPath, akSkPathFlag, "", "", akSkPathUsage)
+	cmd.Flags().StringVarP(&akSkProfile, akSkProfileFlag, "", accesstoken.DefaultProfile, akSkProfileUsage)
+}
+
 var accessTokenCmd = &cobra.Command{
 	Use:               "access-token",
 	Short:             accessTokenCmdHelp,
@@ -313,13 +825,74 @@ var accessTokenCreateCmd = &cobra.Command{
 					"fatal: no valid unscoped token found.\n\nPlease obtain an unscoped token by logging in first"))
 		}
 
-		accesstoken.CreateAccessToken(accessTokenCreateDescription, printAkSk)
+		output, err := akSkOutputOptions()
+		if err != nil {
+			common.ThrowError(err)
+		}
+		accesstoken.CreateAccessToken(accessTokenCreateDescription, accessTokenUser(), output)
+	},
+}
+
+var accessTokenRotateCmd = &cobra.Command{
+	Use:     "rotate",
+	Short:   accessTokenRotateCmdHelp,
+	Long:    accessTokenRotateCmdLong,
+	Example: accessTokenRotateCmdExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.LoadCloudConfig(domainName)
+		if err != nil {
+			common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+		}
+		if !config.IsAuthenticationValid() {
+			common.ThrowError(
+				errors.New(
+					"fatal: no valid unscoped token found.\n\nPlease obtain an unscoped token by logging in first"))
+		}
+		if !slices.Contains(accesstoken.RetireActions(), rotateRetire) {
+			common.ThrowError(fmt.Errorf("fatal: unknown value %s for --%s, use one of %s",
+				rotateRetire, rotateRetireFlag, strings.Join(accesstoken.RetireActions(), ", ")))
+		}
+		outputs, err := akSkRotateOutputOptions()
+		if err != nil {
+			common.ThrowError(err)
+		}
+
+		err = accesstoken.RotateAccessToken(accesstoken.RotateParams{
+			AccessKey:     token,
+			Description:   accessTokenCreateDescription,
+			Outputs:       outputs,
+			VerifyCommand: rotateVerifyCommand,
+			Retire:        rotateRetire,
+			Force:         rotateForce,
+			User:          accessTokenUser(),
+		}, cmd.OutOrStdout())
+		if err != nil {
+			common.ThrowError(err)
+		}
 	},
 }
 
+// akSkRotateOutputOptions collects the outputs of a rotation, which may write
+// the new key in several formats.
+func akSkRotateOutputOptions() ([]accesstoken.OutputOptions, error) {
+	if akSkPath != "" && len(akSkFormats) > 1 {
+		return nil, fmt.Errorf("fatal: --%s can only be used with a single --%s", akSkPathFlag, akSkFormatFlag)
+	}
+	outputs := make([]accesstoken.OutputOptions, 0, len(akSkFormats))
+	for _, format := range akSkFormats {
+		if !slices.Contains(accesstoken.Formats(), format) {
+			return nil, fmt.Errorf("fatal: unknown format %s, use one of %s",
+				format, strings.Join(accesstoken.Formats(), ", "))
+		}
+		outputs = append(outputs, accesstoken.OutputOptions{Format: format, Path: akSkPath, Profile: akSkProfile})
+	}
+	return outputs, nil
+}
+
 var accessTokenListCmd = &cobra.Command{
-	Use:   cmdUseList,
-	Short: accessTokenListCmdHelp,
+	Use:     cmdUseList,
+	Short:   accessTokenListCmdHelp,
+	Example: accessTokenListCmdExample,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := config.LoadCloudConfig(domainName)
 		if err != nil {
@@ -329,28 +902,93 @@ var accessTokenListCmd = &cobra.Command{
 			common.ThrowError(
 				errors.New("fatal: no valid unscoped token found.\n\nPlease obtain an unscoped token by logging in first"))
 		}
+		if !slices.Contains(accesstoken.ListFormats(), accessTokenListFormat) {
+			common.ThrowError(fmt.Errorf("fatal: unknown output format %s, use one of %s",
+				accessTokenListFormat, strings.Join(accesstoken.ListFormats(), ", ")))
+		}
+		if accessTokenStatus != "" && !slices.Contains(accesstoken.Statuses(), accessTokenStatus) {
+			common.ThrowError(fmt.Errorf("fatal: unknown status %s, use one of %s",
+				accessTokenStatus, strings.Join(accesstoken.Statuses(), ", ")))
+		}
 
-		accessTokens, errListToken := accesstoken.ListAccessToken()
+		accessTokens, errListToken := accesstoken.ListAccessTokenInfos(accessTokenUser(), accesstoken.ListFilter{
+			Status:      accessTokenStatus,
+			Description: accessTokenDescriptionPattern,
+			UnusedFor:   accessTokenUnusedFor,
+		})
 		if errListToken != nil {
 			common.ThrowError(errListToken)
 		}
-		if len(accessTokens) > 0 {
-			output := "\nAccess Tokens:"
-			for _, aT := range accessTokens {
-				output += fmt.Sprintf("\nToken: \t\t%s\n"+
-					"Description: \t%s\n"+
-					"Created by: \t%s\n"+
-					"Last Used: \t%s\n"+
-					"Active: \t%s\n \n",
-					aT.AccessKey, aT.Description, aT.UserID, aT.LastUseTime, aT.Status)
+		if len(accessTokens) == 0 {
+			glog.V(common.InfoLogLevel).Info("info: no access-tokens found")
+			if accessTokenListFormat == accesstoken.ListFormatTable {
+				return
+			}
+		}
+		if err = accesstoken.WriteAccessTokenInfos(cmd.OutOrStdout(), accessTokens, accessTokenListFormat); err != nil {
+			common.ThrowError(fmt.Errorf("fatal: couldn't write output: %w", err))
+		}
+	},
+}
+
+// accessTokenStatusCmd creates the enable and disable commands.
+func accessTokenStatusCmd(use string, short string, example string, status string) *cobra.Command {
+	return &cobra.Command{
+		Use:     use,
+		Short:   short,
+		Example: example,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := config.LoadCloudConfig(domainName)
+			if err != nil {
+				common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
 			}
-			_, wErr := log.Writer().Write([]byte(output))
-			if wErr != nil {
-				common.ThrowError(fmt.Errorf("fatal: couldn't write output: %w", wErr))
+			if !config.IsAuthenticationValid() {
+				common.ThrowError(
+					errors.New(
+						"fatal: no valid unscoped token found.\n\nPlease obtain an unscoped token by logging in first"))
 			}
-		} else {
-			glog.V(common.InfoLogLevel).Info("info: no access-tokens found")
+			if token == "" {
+				common.ThrowError(errors.New("fatal: argument token cannot be empty"))
+			}
+			if err = accesstoken.SetAccessTokenStatus(token, status); err != nil {
+				common.ThrowError(err)
+			}
+			glog.V(common.InfoLogLevel).Infof("info: access key %s is %s", token, status)
+		},
+	}
+}
+
+var (
+	accessTokenEnableCmd = accessTokenStatusCmd("enable", accessTokenEnableCmdHelp, accessTokenEnableCmdExample,
+		accesstoken.StatusActive)
+	accessTokenDisableCmd = accessTokenStatusCmd("disable", accessTokenDisableCmdHelp, accessTokenDisableCmdExample,
+		accesstoken.StatusInactive)
+)
+
+var accessTokenUpdateCmd = &cobra.Command{
+	Use:     "update",
+	Short:   accessTokenUpdateCmdHelp,
+	Example: accessTokenUpdateCmdExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.LoadCloudConfig(domainName)
+		if err != nil {
+			common.ThrowError(errors.New("fatal: couldn't load cloud config: " + err.Error()))
+		}
+		if !config.IsAuthenticationValid() {
+			common.ThrowError(
+				errors.New(
+					"fatal: no valid unscoped token found.\n\nPlease obtain an unscoped token by logging in first"))
+		}
+		if token == "" {
+			common.ThrowError(errors.New("fatal: argument token cannot be empty"))
+		}
+		if accessTokenUpdateDescription == "" {
+			common.ThrowError(errors.New("fatal: argument description cannot be empty"))
+		}
+		if err = accesstoken.SetAccessTokenDescription(token, accessTokenUpdateDescription); err != nil {
+			common.ThrowError(err)
 		}
+		glog.V(common.InfoLogLevel).Infof("info: updated the description of access key %s", token)
 	},
 }
 
@@ -373,7 +1011,7 @@ var accessTokenDeleteCmd = &cobra.Command{
 		if token == "" {
 			common.ThrowError(errors.New("fatal: argument token cannot be empty"))
 		}
-		errDelete := accesstoken.DeleteAccessToken(token)
+		errDelete := accesstoken.DeleteUserAccessToken(token, accessTokenUser(), cmd.OutOrStdout())
 		if errDelete != nil {
 			common.ThrowError(errDelete)
 		}
@@ -386,13 +1024,39 @@ var openstackCmd = &cobra.Command{
 }
 
 var openstackConfigCreateCmd = &cobra.Command{
-	Use:   "config-create",
-	Short: openstackConfigCreateCmdHelp,
+	Use:     "config-create",
+	Short:   openstackConfigCreateCmdHelp,
+	Long:    openstackConfigCreateCmdLong,
+	Example: openstackConfigCreateCmdExample,
+	PreRunE: configureCmdFlagsAgainstEnvs(openstackConfigCreateFlagToEnv),
 	Run: func(cmd *cobra.Command, args []string) {
 		if strings.HasPrefix(openStackConfigLocation, "~") {
 			openStackConfigLocation = strings.Replace(openStackConfigLocation, "~", homedir.HomeDir(), 1)
 		}
-		openstack.WriteOpenStackCloudsYaml(openStackConfigLocation)
+		options := openstack.Options{
+			Location:          openStackConfigLocation,
+			SecureFile:        openStackSecureFile,
+			AuthType:          openStackAuthType,
+			EndpointOverrides: openStackEndpointOverrides,
+		}
+		switch openStackAuthType {
+		case openstack.AuthTypePassword:
+			entryPassword, err := newSecretReader().password(password, passwordStdin, passwordFile)
+			if err != nil {
+				common.ThrowError(err)
+			}
+			options.Password = entryPassword
+		case openstack.AuthTypeAkSk:
+			// without --access-key the AK/SK of the login is written
+			if accessKey != "" {
+				entrySecretKey, err := newSecretReader().secret(secretKey, "Secret key: ", secretKeyFlag, secretKeyEnv)
+				if err != nil {
+					common.ThrowError(err)
+				}
+				options.AccessKey = &config.AccessKeyPair{AccessKey: accessKey, SecretKey: entrySecretKey}
+			}
+		}
+		openstack.WriteOpenStackCloudsYaml(options)
 	},
 }
 
@@ -414,6 +1078,8 @@ func setupRootCmd() {
 	loginCmd.AddCommand(loginIamCmd)
 	loginIamCmd.Flags().StringVarP(&username, usernameFlag, usernameShortFlag, "", usernameUsage)
 	loginIamCmd.Flags().StringVarP(&password, passwordFlag, passwordShortFlag, "", passwordUsage)
+	loginIamCmd.Flags().BoolVarP(&passwordStdin, passwordStdinFlag, "", false, passwordStdinUsage)
+	loginIamCmd.Flags().StringVarP(&passwordFile, passwordFileFlag, "", "", passwordFileUsage)
 	loginIamCmd.Flags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", domainNameUsage)
 	loginIamCmd.Flags().BoolVarP(&overwriteToken, overwriteTokenFlag, overwriteTokenShortFlag, false, overwriteTokenUsage)
 	loginIamCmd.Flags().StringVarP(&totp, totpFlag, totpShortFlag, "", totpUsage)
@@ -423,6 +1089,8 @@ func setupRootCmd() {
 	loginCmd.AddCommand(loginIdpSamlCmd)
 	loginIdpSamlCmd.Flags().StringVarP(&username, usernameFlag, usernameShortFlag, "", usernameUsage)
 	loginIdpSamlCmd.Flags().StringVarP(&password, passwordFlag, passwordShortFlag, "", passwordUsage)
+	loginIdpSamlCmd.Flags().BoolVarP(&passwordStdin, passwordStdinFlag, "", false, passwordStdinUsage)
+	loginIdpSamlCmd.Flags().StringVarP(&passwordFile, passwordFileFlag, "", "", passwordFileUsage)
 	loginIdpSamlCmd.Flags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", domainNameUsage)
 	loginIdpSamlCmd.Flags().BoolVarP(
 		&overwriteToken,
@@ -434,6 +1102,11 @@ func setupRootCmd() {
 	loginIdpSamlCmd.PersistentFlags().StringVarP(&idpName, idpNameFlag, idpNameShortFlag, "", idpNameUsage)
 	loginIdpSamlCmd.PersistentFlags().StringVarP(&idpURL, idpURLFlag, "", "", idpURLUsage)
 	loginIdpSamlCmd.Flags().StringVarP(&region, regionFlag, regionShortFlag, "", regionUsage)
+	loginIdpSamlCmd.Flags().BoolVarP(&samlBrowser, samlBrowserFlag, "", false, samlBrowserUsage)
+	loginIdpSamlCmd.Flags().StringVarP(&samlListen, samlListenFlag, "", saml.DefaultBrowserListenAddress,
+		samlListenUsage)
+	loginIdpSamlCmd.Flags().StringVarP(&samlIdpType, idpTypeFlag, "", "generic", idpTypeUsage)
+	loginIdpSamlCmd.Flags().StringVarP(&totp, totpFlag, totpShortFlag, "", samlTotpUsage)
 
 	loginCmd.AddCommand(loginIdpOidcCmd)
 	loginIdpOidcCmd.Flags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", domainNameUsage)
@@ -453,6 +1126,15 @@ func setupRootCmd() {
 		[]string{"openid"}, oidcScopesUsage)
 	loginIdpOidcCmd.Flags().BoolVarP(&isServiceAccount, isServiceAccountFlag, isServiceAccountShortFlag, false,
 		isServiceAccountUsage)
+	loginIdpOidcCmd.Flags().StringVarP(&idTokenFile, idTokenFileFlag, "", "", idTokenFileUsage)
+	loginIdpOidcCmd.Flags().StringVarP(&idTokenEnv, idTokenEnvFlag, "", "", idTokenEnvUsage)
+	loginIdpOidcCmd.Flags().StringVarP(&idTokenCommand, idTokenCommandFlag, "", "", idTokenCommandUsage)
+
+	loginCmd.AddCommand(loginAkSkCmd)
+	loginAkSkCmd.Flags().StringVarP(&accessKey, accessKeyFlag, "", "", accessKeyUsage)
+	loginAkSkCmd.Flags().StringVarP(&secretKey, secretKeyFlag, "", "", secretKeyUsage)
+	loginAkSkCmd.Flags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", loginAkSkDomainNameUsage)
+	loginAkSkCmd.Flags().StringVarP(&region, regionFlag, regionShortFlag, "", regionUsage)
 
 	loginCmd.AddCommand(loginRemoveCmd)
 	loginRemoveCmd.Flags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", domainNameUsage)
@@ -465,6 +1147,8 @@ func setupRootCmd() {
 	cceCmd.PersistentFlags().StringVarP(&projectName, projectNameFlag, projectNameShortFlag, "", projectNameUsage)
 
 	cceCmd.AddCommand(cceListCmd)
+	cceListCmd.Flags().StringVarP(&clusterListFormat, outputFormatFlag, "", cce.ClusterListFormatName,
+		clusterListFormatUsage)
 
 	cceCmd.AddCommand(cceGetKubeConfigCmd)
 	cceGetKubeConfigCmd.Flags().BoolVarP(&printKubeConfig, printKubeConfigFlag, printKubeConfigShortFlag,
@@ -492,10 +1176,82 @@ func setupRootCmd() {
 		"~/.kube/config",
 		targetLocationUsage,
 	)
+	cceGetKubeConfigCmd.Flags().BoolVarP(&kubeExecCredential, kubeExecCredentialFlag, "", false,
+		kubeExecCredentialUsage)
+	cceGetKubeConfigCmd.Flags().BoolVarP(&refreshClusters, refreshClustersFlag, "", false, refreshClustersUsage)
+	cceGetKubeConfigCmd.Flags().BoolVarP(&allClusters, allClustersFlag, "", false, allClustersUsage)
+	cceGetKubeConfigCmd.Flags().StringSliceVarP(&projectFilter, projectFilterFlag, "", nil, projectFilterUsage)
+	cceGetKubeConfigCmd.Flags().StringSliceVarP(&clusterFilter, clusterFilterFlag, "", nil, clusterFilterUsage)
+	cceGetKubeConfigCmd.Flags().StringVarP(&kubeConfigLayout, kubeConfigLayoutFlag, "", cce.KubeConfigLayoutMerged,
+		kubeConfigLayoutUsage)
+	cceGetKubeConfigCmd.Flags().StringVarP(&kubeConfigPathTemplate, kubeConfigPathTemplateFlag, "",
+		cce.DefaultKubeConfigPathTemplate, kubeConfigPathTemplateUsage)
+	cceGetKubeConfigCmd.Flags().StringVarP(&kubeEndpoints, kubeEndpointsFlag, "", cce.KubeEndpointsBoth,
+		kubeEndpointsUsage)
+	cceGetKubeConfigCmd.Flags().BoolVarP(&keepCurrentContext, keepCurrentContextFlag, "", false,
+		keepCurrentContextUsage)
+	cceGetKubeConfigCmd.Flags().StringVarP(&kubeNamespace, kubeNamespaceFlag, "", "", kubeNamespaceUsage)
+	cceGetKubeConfigCmd.Flags().StringVarP(&kubeContextTemplate, kubeContextTemplateFlag, "",
+		cce.DefaultKubeContextTemplate, kubeContextTemplateUsage)
+	cceGetKubeConfigCmd.Flags().StringVarP(&kubeUserTemplate, kubeUserTemplateFlag, "", cce.DefaultKubeUserTemplate,
+		kubeUserTemplateUsage)
 	cceCmd.AddCommand(cceCheckKubeConfigCmd)
+	cceCheckKubeConfigCmd.Flags().StringVarP(&certReportFormat, outputFormatFlag, "", cce.CertReportFormatTable,
+		certReportFormatUsage)
+	cceCheckKubeConfigCmd.Flags().DurationVarP(&expiringWithin, expiringWithinFlag, "",
+		expiringWithinDefaultValue, expiringWithinUsage)
+	cceCheckKubeConfigCmd.Flags().BoolVarP(&renewKubeCerts, renewKubeCertsFlag, "", false, renewKubeCertsUsage)
+	cceCheckKubeConfigCmd.Flags().DurationVarP(&renewWithin, renewWithinFlag, "", renewWithinDefaultValue,
+		renewWithinUsage)
+	cceCheckKubeConfigCmd.Flags().IntVarP(&daysValid, daysValidFlag, "", daysValidDefaultValue, daysValidUsage)
+	cceCheckKubeConfigCmd.Flags().StringVarP(
+		&targetLocation,
+		targetLocationFlag,
+		targetLocationShortFlag,
+		"~/.kube/config",
+		renewTargetLocationUsage,
+	)
+	cceCheckKubeConfigCmd.Flags().StringVarP(&kubeConfigPathTemplate, kubeConfigPathTemplateFlag, "",
+		cce.DefaultKubeConfigPathTemplate, renewPathTemplateUsage)
+
+	cceCmd.AddCommand(ccePruneKubeConfigCmd)
+	ccePruneKubeConfigCmd.Flags().BoolVarP(&dryRun, dryRunFlag, "", false, pruneDryRunUsage)
+	ccePruneKubeConfigCmd.Flags().BoolVarP(&assumeYes, assumeYesFlag, assumeYesShortFlag, false, assumeYesUsage)
+	ccePruneKubeConfigCmd.Flags().StringVarP(
+		&targetLocation,
+		targetLocationFlag,
+		targetLocationShortFlag,
+		"~/.kube/config",
+		pruneTargetLocationUsage,
+	)
+
+	cceCmd.AddCommand(cceRemoveKubeConfigCmd)
+	cceRemoveKubeConfigCmd.Flags().StringVarP(&clusterName, clusterNameFlag, clusterNameShortFlag, "", clusterNameUsage)
+	cceRemoveKubeConfigCmd.Flags().StringVarP(&alias, aliasFlag, aliasShortFlag, "", removeAliasUsage)
+	cceRemoveKubeConfigCmd.Flags().BoolVarP(&dryRun, dryRunFlag, "", false, removeDryRunUsage)
+	cceRemoveKubeConfigCmd.Flags().StringVarP(
+		&targetLocation,
+		targetLocationFlag,
+		targetLocationShortFlag,
+		"~/.kube/config",
+		removeTargetLocationUsage,
+	)
+
+	cceCmd.AddCommand(cceKubeConfigPathCmd)
+	cceKubeConfigPathCmd.Flags().StringVarP(&kubeConfigPathTemplate, kubeConfigPathTemplateFlag, "",
+		cce.DefaultKubeConfigPathTemplate, kubeConfigPathTemplateUsage)
+	cceKubeConfigPathCmd.Flags().BoolVarP(&includeDefaultKubeConfig, includeDefaultKubeConfigFlag, "", true,
+		includeDefaultKubeConfigUsage)
+
+	cceCmd.AddCommand(cceExecCredentialCmd)
+	cceExecCredentialCmd.Flags().StringVarP(&clusterName, clusterNameFlag, clusterNameShortFlag, "", clusterNameUsage)
+	cceExecCredentialCmd.Flags().IntVarP(&daysValid, daysValidFlag, "", daysValidDefaultValue, daysValidUsage)
+	cceExecCredentialCmd.Flags().BoolVarP(&refreshClusters, refreshClustersFlag, "", false, refreshClustersUsage)
 
 	RootCmd.AddCommand(tempAccessTokenCmd)
 	tempAccessTokenCmd.PersistentFlags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", domainNameUsage)
+	tempAccessTokenCmd.PersistentFlags().StringVarP(&projectName, projectNameFlag, projectNameShortFlag, "",
+		tempAccessTokenProjectNameUsage)
 	tempAccessTokenCmd.AddCommand(tempAccessTokenCreateCmd)
 	tempAccessTokenCreateCmd.Flags().IntVarP(
 		&temporaryAccessTokenDurationSeconds,
@@ -506,6 +1262,39 @@ func setupRootCmd() {
 	)
 	tempAccessTokenCreateCmd.Flags().BoolVarP(&printAkSk, printAkSkFlag, printAkSkShortFlag,
 		false, printAkSkUsage)
+	addAkSkOutputFlags(tempAccessTokenCreateCmd)
+	tempAccessTokenCreateCmd.Flags().BoolVarP(&forceNewAccessToken, forceNewAccessTokenFlag, "", false,
+		forceNewAccessTokenUsage)
+	addTemporaryAccessTokenFlags(tempAccessTokenCreateCmd)
+	tempAccessTokenCmd.AddCommand(tempAccessTokenCredentialProcessCmd)
+	tempAccessTokenCredentialProcessCmd.Flags().IntVarP(
+		&temporaryAccessTokenDurationSeconds,
+		temporaryAccessTokenDurationSecondsFlag,
+		temporaryAccessTokenDurationSecondsShortFlag,
+		credentialProcessLifetime,
+		temporaryAccessTokenDurationSecondsUsage,
+	)
+	tempAccessTokenCredentialProcessCmd.Flags().BoolVarP(&forceNewAccessToken, forceNewAccessTokenFlag, "", false,
+		forceNewAccessTokenUsage)
+	addTemporaryAccessTokenFlags(tempAccessTokenCredentialProcessCmd)
+	tempAccessTokenCmd.AddCommand(tempAccessTokenPurgeCmd)
+	tempAccessTokenPurgeCmd.Flags().BoolVarP(&purgeAllDomains, purgeAllDomainsFlag, "", false, purgeAllDomainsUsage)
+	RootCmd.AddCommand(serveCredentialsCmd)
+	serveCredentialsCmd.Flags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", domainNameUsage)
+	serveCredentialsCmd.Flags().StringVarP(&projectName, projectNameFlag, projectNameShortFlag, "",
+		serveCredentialsProjectNameUsage)
+	serveCredentialsCmd.Flags().IntVarP(
+		&temporaryAccessTokenDurationSeconds,
+		temporaryAccessTokenDurationSecondsFlag,
+		temporaryAccessTokenDurationSecondsShortFlag,
+		credentialProcessLifetime,
+		temporaryAccessTokenDurationSecondsUsage,
+	)
+	addTemporaryAccessTokenFlags(serveCredentialsCmd)
+	serveCredentialsCmd.Flags().StringVarP(&serveAddress, serveAddressFlag, "", accesstoken.DefaultServeAddress,
+		serveAddressUsage)
+	serveCredentialsCmd.Flags().StringVarP(&serveAuthTokenFile, serveAuthTokenFileFlag, "", "",
+		serveAuthTokenFileUsage)
 	RootCmd.AddCommand(accessTokenCmd)
 	accessTokenCmd.PersistentFlags().StringVarP(&domainName, domainNameFlag, domainNameShortFlag, "", domainNameUsage)
 	accessTokenCmd.AddCommand(accessTokenCreateCmd)
@@ -513,14 +1302,46 @@ func setupRootCmd() {
 		&accessTokenCreateDescription,
 		accessTokenDescriptionFlag,
 		accessTokenDescriptionShortFlag,
-		"Token by otc-auth",
+		accesstoken.DefaultTokenDescription,
 		accessTokenDescriptionUsage,
 	)
 	accessTokenCreateCmd.Flags().BoolVarP(&printAkSk, printAkSkFlag, printAkSkShortFlag,
 		false, printAkSkUsage)
-
+	addAkSkOutputFlags(accessTokenCreateCmd)
+
+	addAccessTokenUserFlags(accessTokenCreateCmd)
+	accessTokenCmd.AddCommand(accessTokenRotateCmd)
+	addAccessTokenUserFlags(accessTokenRotateCmd)
+	accessTokenRotateCmd.Flags().StringVarP(&token, accessTokenTokenFlag, accessTokenTokenShortFlag, "",
+		accessTokenRotateTokenUsage)
+	accessTokenRotateCmd.Flags().StringVarP(
+		&accessTokenCreateDescription,
+		accessTokenDescriptionFlag,
+		accessTokenDescriptionShortFlag,
+		accesstoken.DefaultTokenDescription,
+		accessTokenDescriptionUsage,
+	)
+	accessTokenRotateCmd.Flags().StringSliceVar(&akSkFormats, akSkFormatFlag, []string{accesstoken.FormatShell},
+		akSkFormatsUsage)
+	accessTokenRotateCmd.Flags().StringVarP(&akSkPath, akSkPathFlag, "", "", akSkPathUsage)
+	accessTokenRotateCmd.Flags().StringVarP(&akSkProfile, akSkProfileFlag, "", accesstoken.DefaultProfile,
+		akSkProfileUsage)
+	accessTokenRotateCmd.Flags().StringVarP(&rotateVerifyCommand, rotateVerifyCommandFlag, "", "",
+		rotateVerifyCommandUsage)
+	accessTokenRotateCmd.Flags().StringVarP(&rotateRetire, rotateRetireFlag, "", accesstoken.RetireDelete,
+		rotateRetireUsage)
+	accessTokenRotateCmd.Flags().BoolVarP(&rotateForce, rotateForceFlag, "", false, rotateForceUsage)
 	accessTokenCmd.AddCommand(accessTokenListCmd)
+	addAccessTokenUserFlags(accessTokenListCmd)
+	accessTokenListCmd.Flags().StringVarP(&accessTokenListFormat, outputFormatFlag, "", accesstoken.ListFormatTable,
+		accessTokenListFormatUsage)
+	accessTokenListCmd.Flags().StringVarP(&accessTokenStatus, accessTokenStatusFlag, "", "", accessTokenStatusUsage)
+	accessTokenListCmd.Flags().StringVarP(&accessTokenDescriptionPattern, accessTokenDescriptionFlag,
+		accessTokenDescriptionShortFlag, "", accessTokenDescriptionPatternUsage)
+	accessTokenListCmd.Flags().DurationVar(&accessTokenUnusedFor, accessTokenUnusedForFlag, 0,
+		accessTokenUnusedForUsage)
 	accessTokenCmd.AddCommand(accessTokenDeleteCmd)
+	addAccessTokenUserFlags(accessTokenDeleteCmd)
 	accessTokenDeleteCmd.Flags().StringVarP(
 		&token,
 		accessTokenTokenFlag,
@@ -528,6 +1349,16 @@ func setupRootCmd() {
 		"",
 		accessTokenTokenUsage,
 	)
+	for _, statusCmd := range []*cobra.Command{accessTokenEnableCmd, accessTokenDisableCmd} {
+		accessTokenCmd.AddCommand(statusCmd)
+		statusCmd.Flags().StringVarP(&token, accessTokenTokenFlag, accessTokenTokenShortFlag, "",
+			accessTokenStatusTokenUsage)
+	}
+	accessTokenCmd.AddCommand(accessTokenUpdateCmd)
+	accessTokenUpdateCmd.Flags().StringVarP(&token, accessTokenTokenFlag, accessTokenTokenShortFlag, "",
+		accessTokenUpdateTokenUsage)
+	accessTokenUpdateCmd.Flags().StringVarP(&accessTokenUpdateDescription, accessTokenDescriptionFlag,
+		accessTokenDescriptionShortFlag, "", accessTokenUpdateDescriptionUsage)
 
 	RootCmd.AddCommand(openstackCmd)
 	openstackCmd.AddCommand(openstackConfigCreateCmd)
@@ -538,27 +1369,32 @@ func setupRootCmd() {
 		"~/.config/openstack/clouds.yaml",
 		openstackConfigCreateConfigLocationUsage,
 	)
+	openstackConfigCreateCmd.Flags().BoolVarP(&openStackSecureFile, openstackConfigCreateSecureFileFlag, "", false,
+		openstackConfigCreateSecureFileUsage)
+	openstackConfigCreateCmd.Flags().StringVarP(&openStackAuthType, openstackConfigCreateAuthTypeFlag, "", "",
+		openstackConfigCreateAuthTypeUsage)
+	openstackConfigCreateCmd.Flags().BoolVarP(&openStackEndpointOverrides, openstackConfigCreateEndpointOverridesFlag, "",
+		false, openstackConfigCreateEndpointOverridesUsage)
+	openstackConfigCreateCmd.Flags().StringVarP(&password, passwordFlag, passwordShortFlag, "",
+		openstackConfigCreatePasswordUsage)
+	openstackConfigCreateCmd.Flags().BoolVarP(&passwordStdin, passwordStdinFlag, "", false, passwordStdinUsage)
+	openstackConfigCreateCmd.Flags().StringVarP(&passwordFile, passwordFileFlag, "", "", passwordFileUsage)
+	openstackConfigCreateCmd.Flags().StringVarP(&accessKey, accessKeyFlag, "", "", openstackConfigCreateAccessKeyUsage)
+	openstackConfigCreateCmd.Flags().StringVarP(&secretKey, secretKeyFlag, "", "", secretKeyUsage)
 
 	cobra.CheckErr(errors.Join(
-		loginIamCmd.MarkFlagRequired(passwordFlag),
 		loginIamCmd.MarkFlagRequired(domainNameFlag),
 		loginIamCmd.MarkFlagRequired(regionFlag),
-		loginIdpSamlCmd.MarkFlagRequired(usernameFlag),
-		loginIdpSamlCmd.MarkFlagRequired(passwordFlag),
 		loginIdpSamlCmd.MarkFlagRequired(domainNameFlag),
 		loginIdpSamlCmd.MarkPersistentFlagRequired(idpNameFlag),
 		loginIdpSamlCmd.MarkFlagRequired(regionFlag),
 		loginIdpOidcCmd.MarkFlagRequired(domainNameFlag),
-		loginIdpSamlCmd.MarkPersistentFlagRequired(idpURLFlag),
 		loginIdpOidcCmd.MarkPersistentFlagRequired(idpNameFlag),
-		loginIdpOidcCmd.MarkPersistentFlagRequired(idpURLFlag),
 		loginIdpOidcCmd.MarkFlagRequired(regionFlag),
-		loginIdpOidcCmd.MarkFlagRequired(clientIDFlag),
+		loginAkSkCmd.MarkFlagRequired(regionFlag),
 		loginRemoveCmd.MarkFlagRequired(domainNameFlag),
-		cceCmd.MarkPersistentFlagRequired(domainNameFlag),
-		cceCmd.MarkPersistentFlagRequired(projectNameFlag),
-		cceGetKubeConfigCmd.MarkFlagRequired(clusterNameFlag),
-		tempAccessTokenCmd.MarkPersistentFlagRequired(domainNameFlag),
+		cceExecCredentialCmd.MarkFlagRequired(clusterNameFlag),
+		serveCredentialsCmd.MarkFlagRequired(domainNameFlag),
 		accessTokenCmd.MarkPersistentFlagRequired(domainNameFlag),
 		accessTokenDeleteCmd.MarkFlagRequired(accessTokenTokenFlag),
 	))
@@ -567,6 +1403,10 @@ func setupRootCmd() {
 var (
 	username                            string
 	password                            string
+	accessKey                           string
+	secretKey                           string
+	passwordStdin                       bool
+	passwordFile                        string
 	domainName                          string
 	overwriteToken                      bool
 	idpName                             string
@@ -584,50 +1424,115 @@ var (
 	temporaryAccessTokenDurationSeconds int
 	token                               string
 	openStackConfigLocation             string
+	openStackSecureFile                 bool
+	openStackAuthType                   string
+	openStackEndpointOverrides          bool
 	skipTLS                             bool
 	printKubeConfig                     bool
+	kubeExecCredential                  bool
+	clusterListFormat                   string
+	refreshClusters                     bool
+	dryRun                              bool
+	renewKubeCerts                      bool
+	renewWithin                         time.Duration
+	certReportFormat                    string
+	kubeConfigLayout                    string
+	kubeConfigPathTemplate              string
+	includeDefaultKubeConfig            bool
+	kubeEndpoints                       string
+	keepCurrentContext                  bool
+	kubeNamespace                       string
+	kubeContextTemplate                 string
+	kubeUserTemplate                    string
+	expiringWithin                      time.Duration
+	assumeYes                           bool
+	allClusters                         bool
+	projectFilter                       []string
+	clusterFilter                       []string
 	alias                               string
 	clientSecret                        string
 	clientID                            string
 	oidcScopes                          []string
 	printAkSk                           bool
+	akSkFormat                          string
+	akSkPath                            string
+	akSkProfile                         string
+	akSkFormats                         []string
+	rotateVerifyCommand                 string
+	rotateRetire                        string
+	rotateForce                         bool
+	forceNewAccessToken                 bool
+	temporaryMethod                     string
+	agencyName                          string
+	agencyDomainName                    string
+	sessionPolicy                       string
+	sessionPolicyFile                   string
+	purgeAllDomains                     bool
+	serveAddress                        string
+	accessTokenUserName                 string
+	accessTokenUserID                   string
+	serveAuthTokenFile                  string
+	accessTokenListFormat               string
+	accessTokenStatus                   string
+	accessTokenDescriptionPattern       string
+	accessTokenUnusedFor                time.Duration
+	accessTokenUpdateDescription        string
 	isServiceAccount                    bool
+	idTokenFile                         string
+	idTokenEnv                          string
+	idTokenCommand                      string
+	samlBrowser                         bool
+	samlListen                          string
+	samlIdpType                         string
 
 	rootFlagToEnv = map[string]string{
 		skipTLSFlag: skipTLSEnv,
 	}
 
 	loginIamFlagToEnv = map[string]string{
-		usernameFlag:   usernameEnv,
-		passwordFlag:   passwordEnv,
-		domainNameFlag: domainNameEnv,
-		userIDFlag:     userIDEnv,
-		idpNameFlag:    idpNameEnv,
-		idpURLFlag:     idpURLEnv,
-		regionFlag:     regionEnv,
+		usernameFlag:     usernameEnv,
+		passwordFlag:     passwordEnv,
+		passwordFileFlag: passwordFileEnv,
+		domainNameFlag:   domainNameEnv,
+		userIDFlag:       userIDEnv,
+		idpNameFlag:      idpNameEnv,
+		idpURLFlag:       idpURLEnv,
+		regionFlag:       regionEnv,
 	}
 
 	loginIdpSamlFlagToEnv = map[string]string{
-		usernameFlag:   usernameEnv,
-		passwordFlag:   passwordEnv,
-		domainNameFlag: domainNameEnv,
-		userIDFlag:     userIDEnv,
-		idpNameFlag:    idpNameEnv,
-		idpURLFlag:     idpURLEnv,
-		regionFlag:     regionEnv,
-	}
-
-	loginIdpOidcFlagToEnv = map[string]string{
 		usernameFlag:     usernameEnv,
 		passwordFlag:     passwordEnv,
+		passwordFileFlag: passwordFileEnv,
 		domainNameFlag:   domainNameEnv,
 		userIDFlag:       userIDEnv,
 		idpNameFlag:      idpNameEnv,
 		idpURLFlag:       idpURLEnv,
 		regionFlag:       regionEnv,
-		clientIDFlag:     clientIDEnv,
-		clientSecretFlag: clientSecretEnv,
-		oidcScopesFlag:   oidcScopesEnv,
+		idpTypeFlag:      idpTypeEnv,
+	}
+
+	loginIdpOidcFlagToEnv = map[string]string{
+		usernameFlag:       usernameEnv,
+		passwordFlag:       passwordEnv,
+		domainNameFlag:     domainNameEnv,
+		userIDFlag:         userIDEnv,
+		idpNameFlag:        idpNameEnv,
+		idpURLFlag:         idpURLEnv,
+		regionFlag:         regionEnv,
+		clientIDFlag:       clientIDEnv,
+		clientSecretFlag:   clientSecretEnv,
+		oidcScopesFlag:     oidcScopesEnv,
+		idTokenFileFlag:    idTokenFileEnv,
+		idTokenEnvFlag:     idTokenEnvEnv,
+		idTokenCommandFlag: idTokenCommandEnv,
+	}
+
+	loginAkSkFlagToEnv = map[string]string{
+		accessKeyFlag:  accessKeyEnv,
+		secretKeyFlag:  secretKeyEnv,
+		domainNameFlag: domainNameEnv,
+		regionFlag:     regionEnv,
 	}
 
 	loginRemoveFlagToEnv = map[string]string{
@@ -645,20 +1550,53 @@ var (
 	}
 
 	cceGetKubeConfigFlagToEnv = map[string]string{
+		clusterNameFlag:            clusterNameEnv,
+		regionFlag:                 regionEnv,
+		kubeConfigLayoutFlag:       kubeConfigLayoutEnv,
+		kubeConfigPathTemplateFlag: kubeConfigPathTemplateEnv,
+	}
+
+	cceKubeConfigPathFlagToEnv = map[string]string{
+		kubeConfigPathTemplateFlag: kubeConfigPathTemplateEnv,
+	}
+
+	cceExecCredentialFlagToEnv = map[string]string{
+		clusterNameFlag: clusterNameEnv,
+	}
+
+	cceRemoveKubeConfigFlagToEnv = map[string]string{
 		clusterNameFlag: clusterNameEnv,
-		regionFlag:      regionEnv,
 	}
 
 	accessTokenFlagToEnv = map[string]string{
 		domainNameFlag: domainNameEnv,
 	}
+
+	tempAccessTokenFlagToEnv = map[string]string{
+		domainNameFlag:  domainNameEnv,
+		projectNameFlag: projectNameEnv,
+	}
+
+	serveCredentialsFlagToEnv = map[string]string{
+		domainNameFlag:  domainNameEnv,
+		projectNameFlag: projectNameEnv,
+	}
+
+	openstackConfigCreateFlagToEnv = map[string]string{
+		passwordFlag:     passwordEnv,
+		passwordFileFlag: passwordFileEnv,
+		accessKeyFlag:    accessKeyEnv,
+		secretKeyFlag:    secretKeyEnv,
+	}
 )
 
 //nolint:lll // Long lines required for formatting reasons
 const (
-	loginCmdHelp       = "Login to the Open Telekom Cloud and receive an unscoped token"
-	loginIamCmdHelp    = "Login to the Open Telekom Cloud through its Identity and Access Management system and receive an unscoped token"
-	loginIamCmdExample = `$ otc-auth login iam --os-username YourUsername --os-password YourPassword --os-domain-name YourDomainName
+	loginCmdHelp    = "Login to the Open Telekom Cloud and receive an unscoped token"
+	loginIamCmdHelp = "Login to the Open Telekom Cloud through its Identity and Access Management system and receive an " +
+		"unscoped token"
+	loginIamCmdExample = `$ otc-auth login iam --os-username YourUsername --os-password YourPassword \
+    --os-domain-name YourDomainName
 
 $ export OS_USERNAME=YourUsername
 $ export OS_PASSWORD=YourPassword
@@ -669,9 +1607,17 @@ $ export OS_USERNAME=YourUsername
 $ export OS_PASSWORD=YourPassword
 $ export OS_DOMAIN_NAME=YourDomainName
 $ export REGION=YourRegion
-$ otc-auth login iam --overwrite-token --region YourRegion`
-	loginIdpSamlCmdHelp    = "Login to the Open Telekom Cloud through an Identity Provider and SAML and receive an unscoped token"
-	loginIdpSamlCmdExample = `otc-auth login idp-saml --os-username YourUsername --os-password YourPassword --os-domain-name YourDomainName
+$ otc-auth login iam --overwrite-token --region YourRegion
+
+$ otc-auth login iam --os-username YourUsername --os-domain-name YourDomainName --region YourRegion
+Password:
+
+$ pass show otc | otc-auth login iam --password-stdin --os-username YourUsername --os-domain-name YourDomainName \
+    --region YourRegion`
+	loginIdpSamlCmdHelp = "Login to the Open Telekom Cloud through an Identity Provider and SAML and receive an " +
+		"unscoped token"
+	loginIdpSamlCmdExample = `otc-auth login idp-saml --os-username YourUsername --os-password YourPassword \
+    --os-domain-name YourDomainName
 
 export OS_DOMAIN_NAME=MyDomain
 export OS_USERNAME=MyUsername
@@ -681,9 +1627,14 @@ otc-auth login idp-saml --idp-name MyIdP --idp-url https://example.com/saml
 
 export OS_DOMAIN_NAME=MyDomain
 export OS_PASSWORD=MyPassword
-otc-auth login idp-saml --idp-name MyIdP --idp-url https://example.com/saml --os-username MyUsername --region MyRegion`
-	loginIdpOidcCmdHelp    = "Login to the Open Telekom Cloud through an Identity Provider and OIDC and receive an unscoped token"
-	loginIdpOidcCmdExample = `otc-auth login idp-oidc --os-username YourUsername --os-password YourPassword --os-domain-name YourDomainName
+otc-auth login idp-saml --idp-name MyIdP --idp-url https://example.com/saml --os-username MyUsername --region MyRegion
+
+otc-auth login idp-saml --browser --idp-name MyIdP --idp-url https://example.com/saml/sso \
+    --os-domain-name MyDomain --region MyRegion`
+	loginIdpOidcCmdHelp = "Login to the Open Telekom Cloud through an Identity Provider and OIDC and receive an " +
+		"unscoped token"
+	loginIdpOidcCmdExample = `otc-auth login idp-oidc --os-username YourUsername --os-password YourPassword \
+    --os-domain-name YourDomainName
 
 export OS_DOMAIN_NAME=MyDomain
 export OS_USERNAME=MyUsername
@@ -693,7 +1644,20 @@ otc-auth login idp-oidc --idp-name MyIdP --idp-url https://example.com/oidc
 
 export OS_DOMAIN_NAME=MyDomain
 export OS_PASSWORD=MyPassword
-otc-auth login idp-oidc --idp-name MyIdP --idp-url https://example.com/oidc --os-username MyUsername --region MyRegion`
+otc-auth login idp-oidc --idp-name MyIdP --idp-url https://example.com/oidc --os-username MyUsername --region MyRegion
+
+otc-auth login idp-oidc --idp-name MyCiIdP --os-domain-name MyDomain --region MyRegion \
+    --id-token-file /var/run/secrets/tokens/otc-token
+
+otc-auth login idp-oidc --idp-name MyCiIdP --os-domain-name MyDomain --region MyRegion --id-token-env CI_JOB_JWT_V2`
+	loginAkSkCmdHelp    = "Login to the Open Telekom Cloud with a permanent access key and secret key"
+	loginAkSkCmdExample = `$ otc-auth login aksk --access-key YourAccessKey --region eu-de
+Secret key:
+
+$ export OS_ACCESS_KEY=YourAccessKey
+$ export OS_SECRET_KEY=YourSecretKey
+$ export REGION=eu-de
+$ otc-auth login aksk`
 	loginRemoveCmdHelp    = "Removes login information for a cloud"
 	loginRemoveCmdExample = `$ otc-auth login remove --os-domain-name MyLogin
 
@@ -711,13 +1675,20 @@ $ export OS_PROJECT_NAME=MyProject
 $ otc-auth cce list
 
 $ export OS_PROJECT_NAME=MyProject
-$ otc-auth cce list`
-	cceCheckKubeCertsCmdHelp    = "Reads KubeConfig and warns when malformed or expired certificates are found.\nThis does NOT check to make sure certs are correctly signed or that the hostnames are correct for your usecase."
-	cceCheckKubeCertsCmdExample = `$ otc-auth cce check-kube-certs --os-project-name MyProject
+$ otc-auth cce list
+
+$ otc-auth cce list --format table
+
+$ otc-auth cce list --format json`
+	cceCheckKubeCertsCmdHelp = "Reports the CA and client certificates of every context in the kube configs and exits " +
+		"with 3 if one expires soon or 4 if one is expired or invalid.\nThis does NOT check to make sure certs are " +
+		"correctly signed or that the hostnames are correct for your usecase."
+	cceCheckKubeCertsCmdExample = `$ otc-auth cce check-kube-certs
+
+$ otc-auth cce check-kube-certs ~/.kube/config ~/.kube/otc --expiring-within 336h --format json
 
 $ export OS_DOMAIN_NAME=MyDomain
-$ export OS_PROJECT_NAME=MyProject
-$ otc-auth cce check-kube-certs
+$ otc-auth cce check-kube-certs --renew --renew-within 48h --days-valid 14
 `
 	cceGetKubeConfigCmdHelp    = "Get remote kube config and merge it with existing local config file"
 	cceGetKubeConfigCmdExample = `$ otc-auth cce get-kube-config --cluster MyCluster --target-location /path/to/config
@@ -730,7 +1701,34 @@ $ otc-auth cce get-kube-config --days-valid 14
 $ export CLUSTER_NAME=MyCluster
 $ export OS_DOMAIN_NAME=MyDomain
 $ export OS_PROJECT_NAME=MyProject
-$ otc-auth cce get-kube-config`
+$ otc-auth cce get-kube-config
+
+$ otc-auth cce get-kube-config --cluster MyCluster --exec-credential
+
+$ otc-auth cce get-kube-config --all --project-filter 'eu-de_*' --cluster-filter 'prod-*,stage-*'
+
+$ otc-auth cce get-kube-config --all --layout split
+
+$ otc-auth cce get-kube-config --cluster MyCluster --endpoints internal --keep-current-context --namespace my-team
+
+$ otc-auth cce get-kube-config --all --context-template '{domain}-{project}-{cluster}' \
+    --user-template '{user}@{project}-{cluster}'`
+	ccePruneKubeConfigCmdHelp    = "Remove kube config entries of deleted clusters"
+	ccePruneKubeConfigCmdExample = `$ otc-auth cce prune-kube-config --dry-run
+
+$ otc-auth cce prune-kube-config --os-domain-name MyDomain --target-location /path/to/config --yes`
+	cceRemoveKubeConfigCmdHelp    = "Remove the kube config entries of a cluster written by get-kube-config"
+	cceRemoveKubeConfigCmdExample = `$ otc-auth cce remove-kube-config --cluster MyCluster
+
+$ otc-auth cce remove-kube-config --alias MyAlias --dry-run`
+	cceKubeConfigPathCmdHelp = "Print a KUBECONFIG value covering the kube config files otc-auth wrote with --layout " +
+		"split"
+	cceKubeConfigPathCmdExample = `$ export KUBECONFIG="$(otc-auth cce kube-config-path)"
+
+$ otc-auth cce kube-config-path --os-domain-name MyDomain --include-default=false`
+	cceExecCredentialCmdHelp    = "Print a client certificate for kubectl (client.authentication.k8s.io exec plugin)"
+	cceExecCredentialCmdExample = `$ otc-auth cce exec-credential --os-domain-name MyDomain --os-project-name MyProject \
+    --cluster MyCluster`
 
 	//nolint:gosec // This is not a hardcoded credential but a help message containing "ak/sk"
 	accessTokenCmdHelp = "Manage AK/SK"
@@ -742,10 +1740,48 @@ $ otc-auth cce get-kube-config`
 
 $ otc-auth access-token create
 
+$ otc-auth access-token create --format aws --profile otc
+
+$ otc-auth access-token create --format fish --path ~/.config/fish/conf.d/otc.fish
+
+$ otc-auth access-token create --user-name ci-bot --format json --output
+
 $ export OS_DOMAIN_NAME=MyDomain
 $ otc-auth access-token create`
-	accessTokenListCmdHelp   = "List existing AK/SKs"
-	accessTokenDeleteCmdHelp = "Delete existing AK/SKs"
+	accessTokenRotateCmdHelp = "Replace a permanent AK/SK by a new one"
+	accessTokenRotateCmdLong = `Replace a permanent AK/SK by a new one. The new AK/SK is created, written in the
+given formats and checked with the verification command. Only then the old AK/SK is deleted or deactivated,
+a failure on the way leaves it working.
+
+Without --token the only AK/SK created by otc-auth is replaced. AK/SKs with another description than the
+default one weren't created by otc-auth and are only replaced with --force. A deactivated AK/SK is described as
+retired, the next rotation deletes it to make room.`
+	//nolint:gosec // This is not a hardcoded credential but a help message containing "ak/sk"
+	accessTokenRotateCmdExample = `$ otc-auth access-token rotate
+
+$ otc-auth access-token rotate --format aws,rclone --verify-command 'aws --profile otc s3 ls'
+
+$ otc-auth access-token rotate --token YourToken --force --retire deactivate
+
+$ for user in ci-bot deploy-bot; do
+    otc-auth access-token rotate --user-name "$user" --format json --path "$user.json"
+done`
+	accessTokenListCmdHelp = "List existing AK/SKs"
+	//nolint:gosec // This is not a hardcoded credential but a help message containing "ak/sk"
+	accessTokenListCmdExample = `$ otc-auth access-token list
+
+$ otc-auth access-token list --status active --unused-for 2160h # active AK/SKs unused for 90 days
+
+$ otc-auth access-token list --description 'ci-*' --format json
+
+$ otc-auth access-token list --user-name ci-bot`
+	accessTokenEnableCmdHelp     = "Activate an AK/SK again"
+	accessTokenEnableCmdExample  = `$ otc-auth access-token enable --token YourToken`
+	accessTokenDisableCmdHelp    = "Deactivate an AK/SK without deleting it, e.g. when it leaked"
+	accessTokenDisableCmdExample = `$ otc-auth access-token disable --token YourToken`
+	accessTokenUpdateCmdHelp     = "Change the description of an AK/SK"
+	accessTokenUpdateCmdExample  = `$ otc-auth access-token update --token YourToken --description "ci pipeline"`
+	accessTokenDeleteCmdHelp     = "Delete existing AK/SKs"
 	//nolint:gosec // This is not a hardcoded credential but a help message containing "ak/sk"
 	accessTokenDeleteCmdExample = `$ otc-auth access-token delete --token YourToken
 
@@ -753,50 +1789,118 @@ $ export OS_DOMAIN_NAME=YourDomain
 $ export AK_SK_TOKEN=YourToken
 $ otc-auth access-token delete
 
-$ otc-auth access-token delete --token YourToken --os-domain-name YourDomain`
+$ otc-auth access-token delete --token YourToken --os-domain-name YourDomain
+
+$ otc-auth access-token delete --token YourToken --user-id TheUsersID`
 	//nolint:gosec // This example code does not actually contain credentials
-	tempAccessTokenCreateCmdExample = `$ otc-auth temp-access-token create -t 900 -d YourDomainName # this creates a temp AK/SK which is 15 minutes valid (15 * 60 = 900)
+	tempAccessTokenCreateCmdExample = `$ # this creates a temp AK/SK which is 15 minutes valid (15 * 60 = 900)
+$ otc-auth temp-access-token create -t 900 -d YourDomainName
 	
-	$ otc-auth temp-access-token create --duration-seconds 1800`
+	$ otc-auth temp-access-token create --duration-seconds 1800
+
+	$ otc-auth temp-access-token create --format rclone --profile obs
+
+	$ otc-auth temp-access-token create --os-project-name eu-de_myproject --force-new
+
+	$ otc-auth temp-access-token create --policy-file upload-only.json --format dotenv
+
+	$ otc-auth temp-access-token create --method agency --agency-name ci-uploads --agency-domain-name OtherDomainName`
+	tempAccessTokenPurgeCmdHelp    = "Remove the cached temporary AK/SKs"
+	tempAccessTokenPurgeCmdExample = `$ otc-auth temp-access-token purge --os-domain-name YourDomainName
+
+$ otc-auth temp-access-token purge --all`
+	tempAccessTokenCredentialProcessCmdExample = `$ cat >> ~/.aws/config <<EOF
+[profile otc]
+credential_process = otc-auth temp-access-token credential-process --os-domain-name YourDomainName
+endpoint_url = https://obs.eu-de.otc.t-systems.com
+EOF
+$ aws --profile otc s3 ls`
+	serveCredentialsCmdHelp = "Serve temporary AK/SKs and IAM tokens on a local endpoint"
+	serveCredentialsCmdLong = "Run an HTTP server on a loopback address which hands out temporary AK/SKs in the format " +
+		"of the ECS metadata service and of the AWS container credentials endpoint, and the IAM token. Every request has " +
+		"to send the auth token printed at start in its Authorization header. The AK/SK is cached and renewed 15 minutes " +
+		"before it expires (a quarter of shorter lifetimes), as long as the login is valid."
+	serveCredentialsCmdExample = `$ otc-auth serve-credentials --os-domain-name YourDomainName --listen 127.0.0.1:8090 \
+    --auth-token-file ~/.otc-auth-serve-token &
+$ export AWS_CONTAINER_CREDENTIALS_FULL_URI=http://127.0.0.1:8090/credentials
+$ export AWS_CONTAINER_AUTHORIZATION_TOKEN=$(cat ~/.otc-auth-serve-token)
+$ aws --endpoint-url https://obs.eu-de.otc.t-systems.com s3 ls
+
+$ otc-auth serve-credentials --os-domain-name YourDomainName --os-project-name eu-de_project
+export AWS_CONTAINER_CREDENTIALS_FULL_URI=http://127.0.0.1:41321/credentials
+export AWS_CONTAINER_AUTHORIZATION_TOKEN=...
+# ECS metadata format: http://127.0.0.1:41321/openstack/latest/securitykey
+# IAM token: http://127.0.0.1:41321/token`
+	openstackConfigCreateCmdExample = `$ otc-auth openstack config-create
+
+$ otc-auth openstack config-create --secure-file # keep the tokens in ~/.config/openstack/secure.yaml
+
+$ otc-auth openstack config-create --auth-type password --password-file ~/.otc-password
+
+$ otc-auth openstack config-create --auth-type aksk --access-key YourAccessKey --endpoint-overrides --secure-file`
 	openstackCmdHelp             = "Manage Openstack Integration"
-	openstackConfigCreateCmdHelp = "Creates new clouds.yaml"
+	openstackConfigCreateCmdHelp = "Creates or updates the clouds.yaml"
 	usernameFlag                 = "os-username"
 	skipTLSFlag                  = "skip-tls-verification"
 
-	usernameShortFlag       = "u"
-	skipTLSShortFlag        = ""
-	usernameEnv             = "OS_USERNAME"
-	usernameUsage           = "Username for the OTC IAM system. Either provide this argument or set the environment variable " + usernameEnv
-	skipTLSUsage            = "Skip TLS Verification. This is insecure. Either provide this argument or set the environment variable " + skipTLSEnv
-	passwordFlag            = "os-password"
-	passwordShortFlag       = "p"
-	passwordEnv             = "OS_PASSWORD"
-	passwordUsage           = "Password for the OTC IAM system. Either provide this argument or set the environment variable " + passwordEnv
-	domainNameFlag          = "os-domain-name"
-	domainNameShortFlag     = "d"
-	domainNameEnv           = "OS_DOMAIN_NAME"
-	domainNameUsage         = "OTC domain name. Either provide this argument or set the environment variable " + domainNameEnv
+	usernameShortFlag = "u"
+	skipTLSShortFlag  = ""
+	usernameEnv       = "OS_USERNAME"
+	usernameUsage     = "Username for the OTC IAM system. Either provide this argument or set the environment variable " +
+		usernameEnv
+	skipTLSUsage = "Skip TLS Verification. This is insecure. Either provide this argument or set the environment " +
+		"variable " + skipTLSEnv
+	passwordFlag      = "os-password"
+	passwordShortFlag = "p"
+	passwordEnv       = "OS_PASSWORD"
+	passwordUsage     = "Password for the OTC IAM system. Either provide this argument or set the environment variable " +
+		passwordEnv + ". If no password is given, it is prompted for on the terminal"
+	accessKeyFlag  = "access-key"
+	accessKeyEnv   = "OS_ACCESS_KEY"
+	accessKeyUsage = "Permanent access key (AK). Either provide this argument or set the environment variable " +
+		accessKeyEnv
+	secretKeyFlag  = "secret-key"
+	secretKeyEnv   = "OS_SECRET_KEY"
+	secretKeyUsage = "Secret key (SK) of the access key. Either provide this argument or set the environment variable " +
+		secretKeyEnv + ". If omitted, it is prompted for on the terminal"
+	loginAkSkDomainNameUsage = "OTC domain name. Optional, the domain is discovered from the access key. If given, the " +
+		"login fails when the key belongs to another domain"
+	passwordStdinFlag  = "password-stdin"
+	passwordStdinUsage = "Read the password from stdin"
+	passwordFileFlag   = "password-file"
+	passwordFileEnv    = "OS_PASSWORD_FILE"
+	passwordFileUsage  = "Read the password from a file. Either provide this argument or set the environment variable " +
+		passwordFileEnv
+	domainNameFlag      = "os-domain-name"
+	domainNameShortFlag = "d"
+	domainNameEnv       = "OS_DOMAIN_NAME"
+	domainNameUsage     = "OTC domain name. Either provide this argument or set the environment variable " +
+		domainNameEnv
 	overwriteTokenFlag      = "overwrite-token"
 	overwriteTokenShortFlag = "o"
 	//nolint:gosec // This is not a hardcoded credential but a help message with a filename inside
-	overwriteTokenUsage       = "Overrides .otc-info file"
-	idpNameFlag               = "idp-name"
-	idpNameShortFlag          = "i"
-	idpNameEnv                = "IDP_NAME"
-	idpNameUsage              = "Required for authentication with IdP"
-	idpURLFlag                = "idp-url"
-	idpURLEnv                 = "IDP_URL"
-	idpURLUsage               = "Required for authentication with IdP"
-	totpFlag                  = "totp"
-	totpShortFlag             = "t"
-	totpUsage                 = "6-digit time-based one-time password (TOTP) used for the MFA login flow. Needs to be used in conjunction with the " + userIDFlag + " flag or the " + userIDEnv + " environment variable"
-	userIDFlag                = "os-user-id"
-	userIDEnv                 = "OS_USER_ID"
-	userIDUsage               = "User Id number, can be obtained on the \"My Credentials page\" on the OTC. Required if --totp is provided.  Either provide this argument or set the environment variable " + userIDEnv
-	regionFlag                = "region"
-	aliasFlag                 = "alias"
-	aliasShortFlag            = "a"
-	aliasUsage                = "Setting this changes the naming scheme for clusters in the Kube Config from {project name}/{cluster name} to the alias set"
+	overwriteTokenUsage = "Overrides .otc-info file"
+	idpNameFlag         = "idp-name"
+	idpNameShortFlag    = "i"
+	idpNameEnv          = "IDP_NAME"
+	idpNameUsage        = "Required for authentication with IdP"
+	idpURLFlag          = "idp-url"
+	idpURLEnv           = "IDP_URL"
+	idpURLUsage         = "Required for authentication with IdP"
+	totpFlag            = "totp"
+	totpShortFlag       = "t"
+	totpUsage           = "6-digit time-based one-time password (TOTP) used for the MFA login flow. Needs to be used in " +
+		"conjunction with the " + userIDFlag + " flag or the " + userIDEnv + " environment variable. If omitted, it is " +
+		"prompted for on the terminal"
+	userIDFlag  = "os-user-id"
+	userIDEnv   = "OS_USER_ID"
+	userIDUsage = "User Id number, can be obtained on the \"My Credentials page\" on the OTC. Required if --totp is " +
+		"provided.  Either provide this argument or set the environment variable " + userIDEnv
+	regionFlag     = "region"
+	aliasFlag      = "alias"
+	aliasShortFlag = "a"
+	aliasUsage     = "Setting this changes the naming scheme for clusters in the Kube Config from {project " +
+		"name}/{cluster name} to the alias set. Use --context-template for names built from the project and cluster"
 	skipKubeTLSFlag           = "skip-kube-tls"
 	skipKubeTLSUsage          = "Setting this adds the insecure-skip-tls-verify rule to the config for every cluster"
 	regionShortFlag           = "r"
@@ -808,54 +1912,234 @@ $ otc-auth access-token delete --token YourToken --os-domain-name YourDomain`
 	isServiceAccountFlag      = "service-account"
 	isServiceAccountShortFlag = ""
 	isServiceAccountUsage     = "Flag to be set when using a service account"
-	oidcScopesUsage           = "Flag to set the scopes which are expected from the OIDC request. Either provide this argument or set the environment variable " + oidcScopesEnv
-
-	clientIDEnv                                  = "CLIENT_ID"
-	clientIDFlag                                 = "client-id"
-	clientIDShortFlag                            = "c"
-	clientIDUsage                                = "Client ID as set on the IdP. Either provide this argument or set the environment variable " + clientIDEnv
-	clientSecretEnv                              = "CLIENT_SECRET"
-	clientSecretFlag                             = "client-secret"
-	clientSecretShortFlag                        = "s"
-	clientSecretUsage                            = "Secret ID as set on the IdP. Either provide this argument or set the environment variable " + clientSecretEnv
-	regionUsage                                  = "OTC region code. Either provide this argument or set the environment variable " + regionEnv
-	projectNameFlag                              = "os-project-name"
-	projectNameShortFlag                         = "p"
-	projectNameEnv                               = "OS_PROJECT_NAME"
-	projectNameUsage                             = "Name of the project you want to access. Either provide this argument or set the environment variable " + projectNameEnv
-	printKubeConfigFlag                          = "output"
-	printKubeConfigShortFlag                     = "o"
-	printKubeConfigUsage                         = "Output fetched kube config to stdout instead of merging it with your existing kube config"
-	printAkSkFlag                                = "output"
-	printAkSkShortFlag                           = "o"
-	printAkSkUsage                               = "Output contents of what would be written to ak-sk-env.sh to stdout instead"
-	clusterNameFlag                              = "cluster"
-	clusterNameShortFlag                         = "c"
-	clusterNameEnv                               = "CLUSTER_NAME"
-	clusterNameUsage                             = "Name of the clusterArg you want to access. Either provide this argument or set the environment variable " + clusterNameEnv
-	daysValidFlag                                = "days-valid"
-	daysValidDefaultValue                        = 7
-	daysValidUsage                               = "Period (in days) that the config will be valid"
-	serverFlag                                   = "server"
-	serverShortFlag                              = "s"
-	serverUsage                                  = "Override the server attribute in the kube config with the specified value"
-	targetLocationFlag                           = "target-location"
-	targetLocationShortFlag                      = "l"
-	targetLocationUsage                          = "Where the kube config should be saved"
-	accessTokenDescriptionFlag                   = "description"
-	accessTokenDescriptionShortFlag              = "s"
-	accessTokenDescriptionUsage                  = "Description of the token"
-	accessTokenTokenFlag                         = "token"
-	accessTokenTokenShortFlag                    = "t"
-	tempAccessTokenCreateCmdHelp                 = "Manage temporary AK/SK"
+	oidcScopesUsage           = "Flag to set the scopes which are expected from the OIDC request. Either provide this " +
+		"argument or set the environment variable " + oidcScopesEnv
+	samlBrowserFlag  = "browser"
+	samlBrowserUsage = "Sign in interactively in the browser (SAML redirect/POST bindings) instead of sending username " +
+		"and password to the IdP's ECP endpoint. Works with IdPs enforcing MFA or without ECP support. --idp-url has to be " +
+		"the single sign-on URL of the IdP, which must accept the /saml/acs path of the --listen address (localhost:8089 " +
+		"by default) as assertion consumer service"
+	samlListenFlag  = "listen"
+	samlListenUsage = "With --browser, the address the assertion consumer service listens on. The IdP must accept " +
+		"http://<host>:<port>/saml/acs of it"
+	idpTypeFlag  = "idp-type"
+	idpTypeEnv   = "IDP_TYPE"
+	idpTypeUsage = "How to talk to the IdP: \"generic\" or \"shibboleth\" (ECP with basic auth), \"keycloak\" (ECP, a " +
+		"one-time code is appended to the password) or \"adfs\" (forms login, --idp-url has to be the IdP-initiated " +
+		"sign-on page). Either provide this argument or set the environment variable " + idpTypeEnv
+	samlTotpUsage = "One-time code for IdPs enforcing a second factor. If omitted and the IdP asks for one, it is " +
+		"prompted for on the terminal"
+	idTokenFileFlag  = "id-token-file"
+	idTokenFileEnv   = "ID_TOKEN_FILE"
+	idTokenFileUsage = "Path to a file holding an externally issued OIDC ID token (e.g. a projected Kubernetes service " +
+		"account token). The file is re-read on every login, so rotated tokens are picked up. Either provide this argument " +
+		"or set the environment variable " + idTokenFileEnv
+	idTokenEnvFlag  = "id-token-env"
+	idTokenEnvEnv   = "ID_TOKEN_ENV"
+	idTokenEnvUsage = "Name of an environment variable holding an externally issued OIDC ID token (e.g. a CI job " +
+		"token). Either provide this argument or set the environment variable " + idTokenEnvEnv
+	idTokenCommandFlag  = "id-token-command"
+	idTokenCommandEnv   = "ID_TOKEN_COMMAND"
+	idTokenCommandUsage = "Command printing an externally issued OIDC ID token to stdout. Either provide this argument " +
+		"or set the environment variable " + idTokenCommandEnv
+
+	clientIDEnv       = "CLIENT_ID"
+	clientIDFlag      = "client-id"
+	clientIDShortFlag = "c"
+	clientIDUsage     = "Client ID as set on the IdP. Either provide this argument or set the environment variable " +
+		clientIDEnv
+	clientSecretEnv       = "CLIENT_SECRET"
+	clientSecretFlag      = "client-secret"
+	clientSecretShortFlag = "s"
+	clientSecretUsage     = "Secret ID as set on the IdP. Either provide this argument or set the environment variable " +
+		clientSecretEnv
+	regionUsage          = "OTC region code. Either provide this argument or set the environment variable " + regionEnv
+	projectNameFlag      = "os-project-name"
+	projectNameShortFlag = "p"
+	projectNameEnv       = "OS_PROJECT_NAME"
+	projectNameUsage     = "Name of the project you want to access. Either provide this argument or set the environment " +
+		"variable " + projectNameEnv
+	printKubeConfigFlag      = "output"
+	printKubeConfigShortFlag = "o"
+	printKubeConfigUsage     = "Output fetched kube config to stdout instead of merging it with your existing kube config"
+	printAkSkFlag            = "output"
+	printAkSkShortFlag       = "o"
+	printAkSkUsage           = "Output contents of what would be written to the file to stdout instead"
+	akSkFormatFlag           = "format"
+	akSkFormatUsage          = "How to write the AK/SK: shell, fish, powershell, dotenv, json, aws (shared credentials " +
+		"profile), s3cmd, rclone (remote) or terraform (provider environment)"
+	akSkPathFlag  = "path"
+	akSkPathUsage = "File to write the AK/SK to, instead of the default of the format (e.g. ./ak-sk-env.sh or " +
+		"~/.aws/credentials). Existing files are merged where the format allows"
+	akSkProfileFlag      = "profile"
+	akSkProfileUsage     = "Profile of the aws format and remote of the rclone format"
+	clusterNameFlag      = "cluster"
+	clusterNameShortFlag = "c"
+	clusterNameEnv       = "CLUSTER_NAME"
+	clusterNameUsage     = "Name of the clusterArg you want to access. Either provide this argument or set the " +
+		"environment variable " + clusterNameEnv
+	daysValidFlag           = "days-valid"
+	daysValidDefaultValue   = 7
+	daysValidUsage          = "Period (in days) that the config will be valid"
+	serverFlag              = "server"
+	serverShortFlag         = "s"
+	serverUsage             = "Override the server attribute in the kube config with the specified value"
+	targetLocationFlag      = "target-location"
+	targetLocationShortFlag = "l"
+	targetLocationUsage     = "Where the kube config should be saved"
+	outputFormatFlag        = "format"
+	clusterListFormatUsage  = "Output format: name (one cluster name per line, the default to keep scripts reading the " +
+		"names working), table (status, version, flavor, endpoints and cert expiry), json or yaml"
+	dryRunFlag                = "dry-run"
+	pruneDryRunUsage          = "Only show which entries would be removed"
+	assumeYesFlag             = "yes"
+	assumeYesShortFlag        = "y"
+	assumeYesUsage            = "Don't ask for confirmation"
+	pruneTargetLocationUsage  = "The kube config to prune"
+	removeAliasUsage          = "The alias the kube config was fetched with, if any"
+	removeDryRunUsage         = "Only show which entries would be removed"
+	removeTargetLocationUsage = "The kube config to remove the entries from"
+	renewKubeCertsFlag        = "renew"
+	renewKubeCertsUsage       = "Fetch new client certificates for the otc-auth entries of the kube config which expire " +
+		"within --renew-within and rewrite them in place"
+	certReportFormatUsage      = "Output format: table, json or yaml"
+	expiringWithinFlag         = "expiring-within"
+	expiringWithinDefaultValue = 7 * 24 * time.Hour
+	expiringWithinUsage        = "Report certificates expiring within this duration as expiring"
+	renewWithinFlag            = "renew-within"
+	renewWithinDefaultValue    = 72 * time.Hour
+	renewWithinUsage           = "With --renew, renew client certificates expiring within this duration"
+	renewTargetLocationUsage   = "With --renew, the kube config to renew the client certificates in"
+	renewPathTemplateUsage     = "With --renew, the files of --layout split are renewed as well, found like " +
+		"kube-config-path does with this template"
+	refreshClustersFlag  = "refresh"
+	refreshClustersUsage = "Fetch the clusters of the project from CCE instead of resolving the cluster name with the " +
+		"cached ones"
+	allClustersFlag  = "all"
+	allClustersUsage = "Fetch the kube configs of all clusters in all projects of the active cloud instead of a single " +
+		"one. The contexts are named <project>/<cluster>"
+	projectFilterFlag     = "project-filter"
+	projectFilterUsage    = "With --all, only walk projects matching one of these shell patterns (e.g. 'eu-de_*')"
+	clusterFilterFlag     = "cluster-filter"
+	kubeConfigLayoutFlag  = "layout"
+	kubeConfigLayoutEnv   = "KUBE_CONFIG_LAYOUT"
+	kubeConfigLayoutUsage = "How to store the kube config: merged into --target-location or split into one file per " +
+		"cluster at --path-template. Either provide this argument or set the environment variable " + kubeConfigLayoutEnv
+	kubeConfigPathTemplateFlag  = "path-template"
+	kubeConfigPathTemplateEnv   = "KUBE_CONFIG_PATH_TEMPLATE"
+	kubeConfigPathTemplateUsage = "With --layout split, the file of each cluster. {domain}, {project} and {cluster} are " +
+		"replaced. Either provide this argument or set the environment variable " + kubeConfigPathTemplateEnv
+	kubeEndpointsFlag  = "endpoints"
+	kubeEndpointsUsage = "Which contexts to write: both, external (public API endpoint) or internal (the " +
+		"-intranet one)"
+	keepCurrentContextFlag   = "keep-current-context"
+	keepCurrentContextUsage  = "Don't switch the current context of the kube config to the fetched cluster"
+	kubeNamespaceFlag        = "namespace"
+	kubeNamespaceUsage       = "Default namespace of the written contexts"
+	kubeContextTemplateFlag  = "context-template"
+	kubeContextTemplateUsage = "Name of the contexts and clusters, the internal ones get an -intranet suffix. {domain}, " +
+		"{project}, {cluster} and {user} are replaced, with --all {project} and {cluster} are required"
+	kubeUserTemplateFlag  = "user-template"
+	kubeUserTemplateUsage = "Name of the users. {domain}, {project}, {cluster} and {user} are replaced, {cluster} is " +
+		"required and {project} too with --all"
+	includeDefaultKubeConfigFlag  = "include-default"
+	includeDefaultKubeConfigUsage = "Put ~/.kube/config first, so kubectl keeps its contexts and writes the current " +
+		"context there"
+	clusterFilterUsage      = "With --all, only fetch clusters matching one of these shell patterns (e.g. 'prod-*')"
+	kubeExecCredentialFlag  = "exec-credential"
+	kubeExecCredentialUsage = "Write a user entry which fetches the client certificate with \"otc-auth cce " +
+		"exec-credential\" when needed instead of static certificates"
+	accessTokenDescriptionFlag              = "description"
+	accessTokenDescriptionShortFlag         = "s"
+	accessTokenDescriptionUsage             = "Description of the token"
+	accessTokenTokenFlag                    = "token"
+	accessTokenTokenShortFlag               = "t"
+	tempAccessTokenCreateCmdHelp            = "Manage temporary AK/SK"
+	tempAccessTokenCredentialProcessCmdHelp = "Print a temporary AK/SK for the credential_process of the AWS CLI and " +
+		"SDKs"
+	tempAccessTokenCredentialProcessCmdLong = "Print a temporary AK/SK as the JSON the credential_process setting of " +
+		"the AWS CLI and SDKs expects. The AK/SK is cached and reused until 15 minutes before it expires (a quarter of " +
+		"shorter lifetimes), so tools using OBS refresh it on their own as long as the login is valid."
 	temporaryAccessTokenDurationSecondsFlag      = "duration-seconds"
 	temporaryAccessTokenDurationSecondsShortFlag = "t"
-	temporaryAccessTokenDurationSecondsUsage     = "The token's lifetime, in seconds. Valid times are between 900 and 86400 seconds"
+	temporaryAccessTokenDurationSecondsUsage     = "The token's lifetime, in seconds. Valid times are between 900 and " +
+		"86400 seconds"
+	akSkFormatsUsage = "Formats to write the new AK/SK in, repeat or separate by commas for several. See --format of " +
+		"access-token create"
+	rotateVerifyCommandFlag  = "verify-command"
+	rotateVerifyCommandUsage = "Shell command checking the new AK/SK, e.g. by listing buckets. It runs after the AK/SK " +
+		"is written, with it exported like the shell format does. The old AK/SK is kept if it fails"
+	rotateRetireFlag                = "retire"
+	rotateRetireUsage               = "What to do with the old AK/SK: delete or deactivate"
+	accessTokenListFormatUsage      = "Output format: table, json or yaml"
+	tempAccessTokenProjectNameUsage = "Scope the temporary AK/SK to this project, it has the permissions of the " +
+		"unscoped token if empty"
+	forceNewAccessTokenFlag  = "force-new"
+	forceNewAccessTokenUsage = "Create a new temporary AK/SK even if a cached one is still valid"
+	temporaryMethodFlag      = "method"
+	temporaryMethodUsage     = "How the temporary AK/SK is issued: token (with the permissions of the login) or agency " +
+		"(with those the agency grants)"
+	agencyNameFlag        = "agency-name"
+	agencyNameUsage       = "Agency to assume with --method agency"
+	agencyDomainNameFlag  = "agency-domain-name"
+	agencyDomainNameUsage = "Domain which created the agency, the logged in domain if empty"
+	sessionPolicyFlag     = "policy"
+	sessionPolicyUsage    = "IAM policy document (JSON) restricting the temporary AK/SK further, it can't grant more " +
+		"than the token or agency has"
+	sessionPolicyFileFlag              = "policy-file"
+	sessionPolicyFileUsage             = "File holding the IAM policy document, see --policy"
+	purgeAllDomainsFlag                = "all"
+	purgeAllDomainsUsage               = "Remove the cached AK/SKs of all domains"
+	accessTokenStatusFlag              = "status"
+	accessTokenStatusUsage             = "Only list AK/SKs with this status: active or inactive"
+	accessTokenDescriptionPatternUsage = "Only list AK/SKs whose description matches this shell pattern (e.g. 'ci-*')"
+	accessTokenUnusedForFlag           = "unused-for"
+	accessTokenUnusedForUsage          = "Only list AK/SKs not used for this long (e.g. 720h), including those never used"
+	accessTokenUpdateDescriptionUsage  = "The new description of the AK/SK"
+	rotateForceFlag                    = "force"
+	rotateForceUsage                   = "Replace the AK/SK even if it wasn't created by otc-auth"
+	accessTokenUserNameFlag            = "user-name"
+	accessTokenUserNameUsage           = "Manage the AK/SKs of this IAM user instead of the logged in one, needs the " +
+		"permissions of a domain admin"
+	accessTokenUserIDFlag            = "user-id"
+	accessTokenUserIDUsage           = "Manage the AK/SKs of the IAM user with this ID, see --user-name"
+	serveCredentialsProjectNameUsage = "Serve AK/SKs and the token scoped to this project, the unscoped ones if empty"
+	serveAddressFlag                 = "listen"
+	serveAddressUsage                = "Loopback host and port to serve on, a free port on 127.0.0.1 by default"
+	serveAuthTokenFileFlag           = "auth-token-file"
+	serveAuthTokenFileUsage          = "Read the auth token requests have to send from a file, a random one is " +
+		"generated if empty"
+	//nolint:gosec // This is not a hardcoded credential but a help message containing ak/sk
+	accessTokenTokenUsage = "The AK/SK token to delete"
+	//nolint:gosec // This is not a hardcoded credential but a help message containing ak/sk
+	accessTokenRotateTokenUsage = "The AK/SK token to replace, the only one created by otc-auth if empty"
+	//nolint:gosec // This is not a hardcoded credential but a help message containing ak/sk
+	accessTokenStatusTokenUsage = "The AK/SK token to change the status of"
 	//nolint:gosec // This is not a hardcoded credential but a help message containing ak/sk
-	accessTokenTokenUsage                        = "The AK/SK token to delete"
+	accessTokenUpdateTokenUsage                  = "The AK/SK token to update"
 	openstackConfigCreateConfigLocationFlag      = "config-location"
 	openstackConfigCreateConfigLocationShortFlag = "l"
 	openstackConfigCreateConfigLocationUsage     = "Where the config should be saved"
+	openstackConfigCreateCmdLong                 = "Write an entry named <domain>_<project> for every project of the " +
+		"login to the clouds.yaml. Entries otc-auth didn't write, comments and the order of the file are kept, the " +
+		"previous file is saved with the .bak suffix."
+	openstackConfigCreateSecureFileFlag  = "secure-file"
+	openstackConfigCreateSecureFileUsage = "Write the tokens, passwords and secret keys to the secure.yaml next to the " +
+		"clouds.yaml, so the clouds.yaml can be shared"
+	openstackConfigCreateAuthTypeFlag  = "auth-type"
+	openstackConfigCreateAuthTypeUsage = "How the entries authenticate: token (the scoped tokens, they expire with the " +
+		"login), password or aksk (a permanent AK/SK, needs the otcextensions of the OpenStack SDK). Defaults to token, or " +
+		"aksk after logging in with an AK/SK"
+	openstackConfigCreateEndpointOverridesFlag  = "endpoint-overrides"
+	openstackConfigCreateEndpointOverridesUsage = "Write the endpoints of the OTC compute, block storage, network, " +
+		"image and DNS services into the entries, for clients signing with an AK/SK"
+	openstackConfigCreatePasswordUsage = "Password written with --auth-type password. Either provide this argument or " +
+		"set the environment variable " + passwordEnv + ". If no password is given, it is prompted for on the terminal"
+	openstackConfigCreateAccessKeyUsage = "Permanent access key (AK) written with --auth-type aksk, the one of the " +
+		"login if empty. Either provide this argument or set the environment variable " + accessKeyEnv
 
 	tempAccessTokenLifetime = 15 * 60 // 15 minutes
+	// credentialProcessLifetime leaves the AWS SDKs, which refresh 15 minutes
+	// before the expiry, 45 minutes of each cached AK/SK
+	credentialProcessLifetime = 60 * 60
 )
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"otc-auth/common"
+)
+
+// secretReader gets passwords and one-time codes from somewhere other than the
+// command line, so they don't end up in shell history or process listings.
+type secretReader struct {
+	stdin       io.Reader
+	readFile    func(name string) ([]byte, error)
+	interactive func() bool
+	prompt      func(prompt string) (string, error)
+}
+
+func newSecretReader() secretReader {
+	return secretReader{
+		stdin:       os.Stdin,
+		readFile:    os.ReadFile,
+		interactive: common.IsInteractive,
+		prompt:      common.PromptSecret,
+	}
+}
+
+// password takes the password from exactly one of --os-password (or
+// OS_PASSWORD), --password-stdin and --password-file. Without any of them it
+// is prompted for on a terminal.
+func (s secretReader) password(fromFlag string, fromStdin bool, file string) (string, error) {
+	sources := 0
+	for _, set := range []bool{fromFlag != "", fromStdin, file != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", fmt.Errorf("fatal: only one of --%s (or %s), --%s and --%s can be used",
+			passwordFlag, passwordEnv, passwordStdinFlag, passwordFileFlag)
+	}
+
+	switch {
+	case fromFlag != "":
+		return fromFlag, nil
+	case fromStdin:
+		content, err := io.ReadAll(s.stdin)
+		if err != nil {
+			return "", fmt.Errorf("fatal: error reading password from stdin\ntrace: %w", err)
+		}
+		return nonEmptySecret(content, "stdin")
+	case file != "":
+		content, err := s.readFile(file)
+		if err != nil {
+			return "", fmt.Errorf("fatal: error reading password file\ntrace: %w", err)
+		}
+		return nonEmptySecret(content, file)
+	case s.interactive():
+		return s.prompt("Password: ")
+	default:
+		return "", fmt.Errorf("fatal: no password given.\n\nPlease provide it with --%s, %s, --%s or --%s",
+			passwordFlag, passwordEnv, passwordStdinFlag, passwordFileFlag)
+	}
+}
+
+// totp asks for the one-time code of an MFA login on a terminal, unless it
+// was passed with --totp. An empty answer logs in without MFA.
+func (s secretReader) totp(fromFlag string) (string, error) {
+	if fromFlag != "" || !s.interactive() {
+		return fromFlag, nil
+	}
+	return s.prompt("One-time code (leave empty if MFA is not enabled): ")
+}
+
+// secret prompts for a secret that can only come from a flag or its
+// environment variable, unless it was given already.
+func (s secretReader) secret(fromFlag string, prompt string, flag string, env string) (string, error) {
+	if fromFlag != "" {
+		return fromFlag, nil
+	}
+	if !s.interactive() {
+		return "", fmt.Errorf("fatal: --%s (or %s) is required", flag, env)
+	}
+	return s.prompt(prompt)
+}
+
+// nonEmptySecret strips the trailing newline that files and pipes usually have.
+func nonEmptySecret(content []byte, source string) (string, error) {
+	secret := strings.TrimRight(string(content), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("fatal: no password found in %s", source)
+	}
+	return secret, nil
+}
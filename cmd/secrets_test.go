@@ -0,0 +1,91 @@
+//nolint:testpackage // whitebox testing
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSecretReader_password(t *testing.T) {
+	tests := []struct {
+		name        string
+		fromFlag    string
+		fromStdin   bool
+		file        string
+		stdin       string
+		files       map[string]string
+		interactive bool
+		want        string
+		wantPrompt  bool
+		wantErrMsg  string
+	}{
+		{name: "flag", fromFlag: "secret", interactive: true, want: "secret"},
+		{name: "stdin strips the trailing newline", fromStdin: true, stdin: "secret\n", want: "secret"},
+		{name: "stdin keeps inner whitespace", fromStdin: true, stdin: " sec ret \r\n", want: " sec ret "},
+		{
+			name: "file", file: "/run/secrets/pw", files: map[string]string{"/run/secrets/pw": "secret\n"},
+			want: "secret",
+		},
+		{name: "prompt on a terminal", interactive: true, want: "prompted", wantPrompt: true},
+		{name: "nothing and no terminal", wantErrMsg: "no password given"},
+		{name: "empty stdin", fromStdin: true, wantErrMsg: "no password found in stdin"},
+		{name: "missing file", file: "/missing", wantErrMsg: "error reading password file"},
+		{name: "flag and stdin", fromFlag: "secret", fromStdin: true, wantErrMsg: "only one of"},
+		{name: "stdin and file", fromStdin: true, file: "/pw", wantErrMsg: "only one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompted := false
+			s := secretReader{
+				stdin: strings.NewReader(tt.stdin),
+				readFile: func(name string) ([]byte, error) {
+					content, ok := tt.files[name]
+					if !ok {
+						return nil, errors.New("no such file")
+					}
+					return []byte(content), nil
+				},
+				interactive: func() bool { return tt.interactive },
+				prompt: func(string) (string, error) {
+					prompted = true
+					return "prompted", nil
+				},
+			}
+
+			got, err := s.password(tt.fromFlag, tt.fromStdin, tt.file)
+			if tt.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("password() error = %v, want it to contain %q", err, tt.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("password() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("password() = %q, want %q", got, tt.want)
+			}
+			if prompted != tt.wantPrompt {
+				t.Errorf("prompted = %v, want %v", prompted, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestSecretReader_totp(t *testing.T) {
+	prompt := func(string) (string, error) { return "123456", nil }
+
+	terminal := secretReader{interactive: func() bool { return true }, prompt: prompt}
+	if got, _ := terminal.totp(""); got != "123456" {
+		t.Errorf("totp() on a terminal = %q, want the prompted code", got)
+	}
+	if got, _ := terminal.totp("654321"); got != "654321" {
+		t.Errorf("totp() with --totp = %q, want the flag value", got)
+	}
+
+	noTerminal := secretReader{interactive: func() bool { return false }, prompt: prompt}
+	if got, _ := noTerminal.totp(""); got != "" {
+		t.Errorf("totp() without a terminal = %q, want no code", got)
+	}
+}
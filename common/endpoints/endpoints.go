@@ -24,6 +24,42 @@ func BaseURLIam(region string) string {
 	}
 }
 
+// OBS is the host of the object storage, it speaks the S3 protocol.
+func OBS(region string) string {
+	return ServiceHost("obs", region)
+}
+
+// ServiceHost is the host of an OTC service in the region, e.g. ecs for the
+// compute API.
+func ServiceHost(service string, region string) string {
+	switch region {
+	case "eu-ch2":
+		return fmt.Sprintf("%s.eu-ch2.sc.otc.t-systems.com", service)
+	default:
+		return fmt.Sprintf("%s.%s.otc.t-systems.com", service, region)
+	}
+}
+
+// SamlServiceProvider is the entity ID of the OTC in SAML, the issuer of the
+// AuthnRequests it sends to IdPs.
+func SamlServiceProvider(region string) string {
+	switch region {
+	case "eu-ch2":
+		return "https://auth.eu-ch2.sc.otc.t-systems.com/"
+	default:
+		return "https://auth.otc.t-systems.com/"
+	}
+}
+
+// BaseURLIamV30 points at the OTC-specific "v3.0" extensions of the IAM API.
+func BaseURLIamV30(region string) string {
+	return BaseURLIam(region) + ".0"
+}
+
+func FederationTokens(region string) string {
+	return fmt.Sprintf("%s/OS-FEDERATION/tokens", BaseURLIamV30(region))
+}
+
 func IdentityProviders(identityProvider string, protocol string, region string) string {
 	identityProviders := fmt.Sprintf("%s/OS-FEDERATION/identity_providers", BaseURLIam(region))
 	return fmt.Sprintf("%s/%s/%s/%s/%s", identityProviders, identityProvider, protocols, protocol, auth)
@@ -2,6 +2,7 @@ package headervalues
 
 const (
 	TextXML         = "text/xml"
+	FormURLEncoded  = "application/x-www-form-urlencoded"
 	ApplicationPaos = "application/vnd.paos+xml"
 	Paos            = `ver="urn:liberty:paos:2003-08";"urn:oasis:names:tc:SAML:2.0:profiles:SSO:ecp"`
 )
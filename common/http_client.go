@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
 )
 
 type HTTPClient interface {
@@ -30,6 +31,21 @@ func NewHTTPClient(skipTLS bool) HTTPClient {
 	return &HTTPClientImpl{client: client}
 }
 
+// WithCookieJar returns a copy of the client which keeps cookies across
+// requests and redirects, for form based IdP logins (ADFS) holding their state
+// in cookies. The client passed in stays stateless, other implementations are
+// returned as they are.
+func WithCookieJar(client HTTPClient) HTTPClient {
+	impl, ok := client.(*HTTPClientImpl)
+	if !ok {
+		return client
+	}
+	jar, _ := cookiejar.New(nil) // only fails for a broken PublicSuffixList, we don't pass one
+	withJar := *impl.client
+	withJar.Jar = jar
+	return &HTTPClientImpl{client: &withJar}
+}
+
 func (c HTTPClientImpl) MakeRequest(request *http.Request) (*http.Response, error) {
 	defer c.client.CloseIdleConnections()
 	return c.client.Do(request) //nolint:gosec // URLs are made from user's cloud configuration not untrusted input
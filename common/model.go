@@ -42,6 +42,14 @@ type AuthInfo struct {
 	IsServiceAccount bool
 	OidcScopes       []string
 	SkipTLS          bool
+	IDTokenFile      string
+	IDTokenEnv       string
+	IDTokenCommand   string
+	SamlBrowser      bool
+	SamlIdpType      string
+	SamlListen       string
+	AccessKey        string
+	SecretKey        string
 }
 type SamlAssertionResponse struct {
 	Name   xml.Name
@@ -97,7 +105,7 @@ const SuccessPageHTML = `
     <div class="col-4">
         <h1 class="text-center">Success!</h1><br/>
         <div class="text-center" style="background-color: rgba(148, 240, 169, 0.2); padding: 1.25rem 1.25rem .25rem;border: 0.075rem solid #94F0A9;">
-            <i class="bi bi-check-circle-fill text-success"></i> <strong class="text-success">Signed in via your OIDC
+            <i class="bi bi-check-circle-fill text-success"></i> <strong class="text-success">Signed in via your identity
             provider</strong>
             <p style="margin-top: .75rem">You can now close this window.</p>
         </div>
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// IsInteractive reports whether stdin is a terminal we can prompt on.
+func IsInteractive() bool {
+	return term.IsTerminal(stdinFd())
+}
+
+// PromptSecret asks for a secret on stderr and reads it from the terminal
+// without echoing it. It refuses to run without a terminal so that scripts
+// fail instead of hanging.
+func PromptSecret(prompt string) (string, error) {
+	if !IsInteractive() {
+		return "", errors.New("fatal: can't prompt, stdin is not a terminal")
+	}
+	if _, err := fmt.Fprint(os.Stderr, prompt); err != nil {
+		return "", err
+	}
+	secret, err := term.ReadPassword(stdinFd())
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("fatal: error reading from terminal\ntrace: %w", err)
+	}
+	// only the line ending, spaces may be part of the secret
+	return strings.TrimRight(string(secret), "\r\n"), nil
+}
+
+// Confirm asks a yes/no question on stderr, only "y" and "yes" count as yes.
+func Confirm(prompt string) (bool, error) {
+	if !IsInteractive() {
+		return false, errors.New("fatal: can't ask for confirmation, stdin is not a terminal")
+	}
+	if _, err := fmt.Fprint(os.Stderr, prompt+" [y/N] "); err != nil {
+		return false, err
+	}
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("fatal: error reading from terminal\ntrace: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+func stdinFd() int {
+	return int(os.Stdin.Fd()) //nolint:gosec // file descriptors fit into an int
+}
@@ -4,4 +4,5 @@ const (
 	Paos = "PAOS"
 
 	XSubjectToken = "X-Subject-Token"
+	XIdpID        = "X-Idp-Id"
 )
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"otc-auth/common/endpoints"
+
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
+)
+
+// AuthOptions returns what the SDK needs to authenticate requests of the
+// cloud, scoped to the project if one is given. Token based clouds use the
+// unscoped or the project's scoped token, AK/SK clouds sign every request.
+func (cloud *Cloud) AuthOptions(project *Project) golangsdk.AuthOptionsProvider {
+	if cloud.AccessKey != nil {
+		options := golangsdk.AKSKAuthOptions{
+			IdentityEndpoint: endpoints.BaseURLIam(cloud.Region),
+			AccessKey:        cloud.AccessKey.AccessKey,
+			SecretKey:        cloud.AccessKey.SecretKey,
+			Region:           cloud.Region,
+			DomainID:         cloud.Domain.ID,
+		}
+		if project != nil {
+			options.ProjectId = project.ID
+		}
+		return options
+	}
+
+	if project != nil {
+		return golangsdk.AuthOptions{
+			IdentityEndpoint: endpoints.BaseURLIam(cloud.Region),
+			DomainID:         cloud.Domain.ID,
+			TokenID:          project.ScopedToken.Secret,
+			TenantID:         project.ID,
+		}
+	}
+	return golangsdk.AuthOptions{
+		IdentityEndpoint: endpoints.BaseURLIam(cloud.Region),
+		DomainID:         cloud.Domain.ID,
+		TokenID:          cloud.UnscopedToken.Secret,
+	}
+}
@@ -0,0 +1,72 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+
+	"otc-auth/config"
+
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
+)
+
+func TestCloud_AuthOptions(t *testing.T) {
+	const iamURL = "https://iam.eu-de.otc.t-systems.com:443/v3"
+	project := &config.Project{
+		NameAndIDResource: config.NameAndIDResource{Name: "eu-de_project", ID: "project-id"},
+		ScopedToken:       config.Token{Secret: "scoped"},
+	}
+	tokenCloud := config.Cloud{
+		Region:        "eu-de",
+		Domain:        config.NameAndIDResource{Name: "OTC-EU-DE-000", ID: "domain-id"},
+		UnscopedToken: config.Token{Secret: "unscoped"},
+	}
+	keyCloud := tokenCloud
+	keyCloud.UnscopedToken = config.Token{}
+	keyCloud.AccessKey = &config.AccessKeyPair{AccessKey: "AK", SecretKey: "SK"}
+
+	tests := []struct {
+		name    string
+		cloud   config.Cloud
+		project *config.Project
+		want    golangsdk.AuthOptionsProvider
+	}{
+		{
+			name:  "unscoped token",
+			cloud: tokenCloud,
+			want: golangsdk.AuthOptions{
+				IdentityEndpoint: iamURL, DomainID: "domain-id", TokenID: "unscoped",
+			},
+		},
+		{
+			name:    "scoped token",
+			cloud:   tokenCloud,
+			project: project,
+			want: golangsdk.AuthOptions{
+				IdentityEndpoint: iamURL, DomainID: "domain-id", TokenID: "scoped", TenantID: "project-id",
+			},
+		},
+		{
+			name:  "access key for the domain",
+			cloud: keyCloud,
+			want: golangsdk.AKSKAuthOptions{
+				IdentityEndpoint: iamURL, AccessKey: "AK", SecretKey: "SK", Region: "eu-de", DomainID: "domain-id",
+			},
+		},
+		{
+			name:    "access key for a project",
+			cloud:   keyCloud,
+			project: project,
+			want: golangsdk.AKSKAuthOptions{
+				IdentityEndpoint: iamURL, AccessKey: "AK", SecretKey: "SK", Region: "eu-de", DomainID: "domain-id",
+				ProjectId: "project-id",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cloud.AuthOptions(tt.project); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuthOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
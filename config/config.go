@@ -86,6 +86,12 @@ func IsAuthenticationValid() bool {
 		common.ThrowError(err)
 	}
 
+	if cloud.AccessKey != nil {
+		glog.V(common.InfoLogLevel).Infof("info: using access key %s, it does not expire",
+			cloud.AccessKey.AccessKey)
+		return true
+	}
+
 	if !cloud.UnscopedToken.IsTokenValid() {
 		return false
 	}
@@ -126,7 +132,9 @@ func RemoveCloudConfig(domainName string) {
 	}
 }
 
-func UpdateClusters(clusters Clusters) {
+// UpdateClusterCache stores the clusters of a project of the active cloud,
+// the caches of other projects are kept.
+func UpdateClusterCache(projectName string, clusters Clusters) {
 	otcConfig, err := getOtcConfig()
 	if err != nil {
 		common.ThrowError(err)
@@ -135,7 +143,13 @@ func UpdateClusters(clusters Clusters) {
 	if err != nil {
 		common.ThrowError(err)
 	}
-	otcConfig.Clouds[*cloudIndex].Clusters = clusters
+	cloud := &otcConfig.Clouds[*cloudIndex]
+	cloud.ClusterCaches.SetClusterCache(ClusterCache{
+		Project:   projectName,
+		Region:    cloud.Region,
+		FetchedAt: time.Now().UTC(),
+		Clusters:  clusters,
+	})
 	err = writeOtcConfigContentToFile(*otcConfig)
 	if err != nil {
 		common.ThrowError(err)
@@ -281,7 +295,8 @@ func readFileContent() (*string, error) {
 }
 
 func WriteConfigFile(content string, configPath string) error {
-	file, err := os.Create(configPath)
+	// the files hold tokens and possibly secret keys
+	file, err := os.OpenFile(configPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
 	if err != nil {
 		return fmt.Errorf("fatal: error reading config file.\ntrace: %w", err)
 	}
@@ -295,6 +310,11 @@ func WriteConfigFile(content string, configPath string) error {
 	if err != nil {
 		return fmt.Errorf("fatal: error saving config file.\ntrace: %w", err)
 	}
+	// OpenFile keeps the mode of an existing file
+	err = os.Chmod(configPath, 0o600)
+	if err != nil {
+		return fmt.Errorf("fatal: error restricting permissions of config file.\ntrace: %w", err)
+	}
 	return nil
 }
 
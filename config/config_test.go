@@ -0,0 +1,33 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"otc-auth/config"
+)
+
+func TestWriteConfigFile_RestrictsExistingFile(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no unix permissions")
+	}
+	path := filepath.Join(t.TempDir(), ".otc-auth-config")
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil { //nolint:gosec // the old, too open mode
+		t.Fatal(err)
+	}
+
+	if err := config.WriteConfigFile(`{"clouds":[]}`, path); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode = %o, want 600", mode)
+	}
+}
@@ -82,9 +82,20 @@ type Cloud struct {
 	Domain        NameAndIDResource `json:"domain"`
 	UnscopedToken Token             `json:"unscopedToken"`
 	Projects      Projects          `json:"projects"`
-	Clusters      Clusters          `json:"clusters"`
+	ClusterCaches ClusterCaches     `json:"clusterCaches,omitempty"`
 	Username      string            `json:"username"`
 	Active        bool              `json:"active"`
+	// AccessKey is set for clouds logged in to with a permanent AK/SK pair.
+	// Those have no tokens, every request is signed with the key pair instead.
+	AccessKey *AccessKeyPair `json:"accessKey,omitempty"`
+	// OpenstackClouds are the clouds.yaml entries otc-auth wrote last, the
+	// ones of projects which are gone are removed on the next write.
+	OpenstackClouds []string `json:"openstackClouds,omitempty"`
+}
+
+type AccessKeyPair struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
 }
 
 type Project struct {
@@ -164,6 +175,45 @@ func (clusters Clusters) ContainsClusterByName(name string) bool {
 	return clusters.FindClusterByName(name) != nil
 }
 
+// ClusterCacheTTL is how long cached clusters are used to resolve cluster
+// names before they are fetched again.
+const ClusterCacheTTL = 24 * time.Hour
+
+// ClusterCache holds the clusters of a single project in a region as they
+// were at FetchedAt.
+type ClusterCache struct {
+	Project   string    `json:"project"`
+	Region    string    `json:"region"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Clusters  Clusters  `json:"clusters"`
+}
+
+type ClusterCaches []ClusterCache
+
+func (caches ClusterCaches) FindClusterCache(projectName string, region string) *ClusterCache {
+	for _, cache := range caches {
+		if cache.Project == projectName && cache.Region == region {
+			return &cache
+		}
+	}
+	return nil
+}
+
+// SetClusterCache replaces the cache of the project in the region.
+func (caches *ClusterCaches) SetClusterCache(cache ClusterCache) {
+	for index, existing := range *caches {
+		if existing.Project == cache.Project && existing.Region == cache.Region {
+			(*caches)[index] = cache
+			return
+		}
+	}
+	*caches = append(*caches, cache)
+}
+
+func (cache ClusterCache) IsFresh(now time.Time) bool {
+	return now.Before(cache.FetchedAt.Add(ClusterCacheTTL))
+}
+
 type NameAndIDResource struct {
 	Name string `json:"name"`
 	ID   string `json:"id"`
@@ -764,6 +764,57 @@ func TestClusters_ContainsClusterByName(t *testing.T) {
 	}
 }
 
+func TestClusterCaches(t *testing.T) {
+	fetchedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var caches config.ClusterCaches
+	caches.SetClusterCache(config.ClusterCache{
+		Project: "eu-de_a", Region: "eu-de", FetchedAt: fetchedAt, Clusters: config.Clusters{{Name: "c", ID: "1"}},
+	})
+	caches.SetClusterCache(config.ClusterCache{
+		Project: "eu-de_b", Region: "eu-de", FetchedAt: fetchedAt, Clusters: config.Clusters{{Name: "c", ID: "2"}},
+	})
+	caches.SetClusterCache(config.ClusterCache{
+		Project: "eu-de_a", Region: "eu-de", FetchedAt: fetchedAt, Clusters: config.Clusters{{Name: "c", ID: "3"}},
+	})
+
+	if len(caches) != 2 {
+		t.Fatalf("SetClusterCache() kept %d caches, want 2", len(caches))
+	}
+	tests := []struct {
+		name    string
+		project string
+		region  string
+		wantID  string
+	}{
+		{name: "replaced cache", project: "eu-de_a", region: "eu-de", wantID: "3"},
+		{name: "other project", project: "eu-de_b", region: "eu-de", wantID: "2"},
+		{name: "other region", project: "eu-de_a", region: "eu-nl", wantID: ""},
+		{name: "unknown project", project: "eu-de_c", region: "eu-de", wantID: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := caches.FindClusterCache(tt.project, tt.region)
+			if tt.wantID == "" {
+				if cache != nil {
+					t.Errorf("FindClusterCache() = %+v, want nil", cache)
+				}
+				return
+			}
+			if cache == nil || cache.Clusters[0].ID != tt.wantID {
+				t.Errorf("FindClusterCache() = %+v, want cluster id %s", cache, tt.wantID)
+			}
+		})
+	}
+
+	cache := caches.FindClusterCache("eu-de_a", "eu-de")
+	if !cache.IsFresh(fetchedAt.Add(config.ClusterCacheTTL - time.Minute)) {
+		t.Error("IsFresh() = false within the TTL")
+	}
+	if cache.IsFresh(fetchedAt.Add(config.ClusterCacheTTL)) {
+		t.Error("IsFresh() = true after the TTL")
+	}
+}
+
 func TestToken_IsTokenValid(t *testing.T) {
 	now := time.Now()
 	type fields struct {
@@ -0,0 +1,83 @@
+package iam
+
+import (
+	"errors"
+	"fmt"
+
+	"otc-auth/common"
+	"otc-auth/common/endpoints"
+	"otc-auth/config"
+
+	"github.com/golang/glog"
+	golangsdk "github.com/opentelekomcloud/gophertelekomcloud"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/credentials"
+	"github.com/opentelekomcloud/gophertelekomcloud/openstack/identity/v3/users"
+)
+
+// AccessKeyIdentity is who a permanent AK/SK pair belongs to.
+type AccessKeyIdentity struct {
+	Domain   config.NameAndIDResource
+	UserID   string
+	Username string
+}
+
+// DiscoverAccessKeyIdentity looks up the domain and the user of an access key.
+// All requests are signed with the key pair (SDK-HMAC-SHA256), no token is
+// involved.
+func DiscoverAccessKeyIdentity(region string, keyPair config.AccessKeyPair) (*AccessKeyIdentity, error) {
+	return discoverAccessKeyIdentity(endpoints.BaseURLIam(region), region, keyPair)
+}
+
+func discoverAccessKeyIdentity(identityEndpoint string, region string,
+	keyPair config.AccessKeyPair,
+) (*AccessKeyIdentity, error) {
+	provider, err := openstack.AuthenticatedClient(golangsdk.AKSKAuthOptions{
+		IdentityEndpoint: identityEndpoint,
+		AccessKey:        keyPair.AccessKey,
+		SecretKey:        keyPair.SecretKey,
+		Region:           region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't authenticate with access key %s: %w", keyPair.AccessKey, err)
+	}
+	client, err := openstack.NewIdentityV3(provider, golangsdk.EndpointOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get identity client: %w", err)
+	}
+
+	var domainsResponse struct {
+		Domains []config.NameAndIDResource `json:"domains"`
+	}
+	//nolint:bodyclose // SDK's client.Get drains the body via extract.Into
+	if _, err = client.Get(client.ServiceURL("auth", "domains"), &domainsResponse, nil); err != nil {
+		return nil, fmt.Errorf("couldn't get domain of access key: %w", err)
+	}
+	if len(domainsResponse.Domains) != 1 {
+		return nil, fmt.Errorf("fatal: expected the access key to belong to exactly one domain, got %d",
+			len(domainsResponse.Domains))
+	}
+
+	credential, err := credentials.Get(client, keyPair.AccessKey).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get user of access key: %w", err)
+	}
+	if credential.UserID == "" {
+		return nil, errors.New("fatal: IAM returned no user for the access key")
+	}
+
+	identity := &AccessKeyIdentity{
+		Domain:   domainsResponse.Domains[0],
+		UserID:   credential.UserID,
+		Username: credential.UserID,
+	}
+	// reading the user needs IAM read permissions, the id is good enough without
+	user, err := users.Get(client, credential.UserID).Extract()
+	if err != nil {
+		glog.V(common.InfoLogLevel).Infof("info: couldn't read name of user %s, using the id instead: %s",
+			credential.UserID, err)
+	} else if user.Name != "" {
+		identity.Username = user.Name
+	}
+	return identity, nil
+}
@@ -0,0 +1,71 @@
+//nolint:testpackage // whitebox testing
+package iam
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otc-auth/config"
+)
+
+func newFakeIam(t *testing.T, canReadUser bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/auth/catalog", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"catalog": []}`)
+	})
+	mux.HandleFunc("/v3/auth/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domains": [{"id": "domain-id", "name": "OTC-EU-DE-000"}]}`)
+	})
+	mux.HandleFunc("/v3.0/OS-CREDENTIAL/credentials/AKEXAMPLE", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"credential": {"user_id": "user-id", "access": "AKEXAMPLE", "status": "active"}}`)
+	})
+	mux.HandleFunc("/v3/users/user-id", func(w http.ResponseWriter, r *http.Request) {
+		if !canReadUser {
+			http.Error(w, `{"error": {"code": 403}}`, http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"user": {"id": "user-id", "name": "ci-deployer"}}`)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "SDK-HMAC-SHA256 Credential=AKEXAMPLE/") {
+			t.Errorf("%s %s not signed with the access key, Authorization = %q", r.Method, r.URL.Path, auth)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func Test_discoverAccessKeyIdentity(t *testing.T) {
+	tests := []struct {
+		name         string
+		canReadUser  bool
+		wantUsername string
+	}{
+		{name: "user name readable", canReadUser: true, wantUsername: "ci-deployer"},
+		{name: "falls back to the user id", canReadUser: false, wantUsername: "user-id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeIam(t, tt.canReadUser)
+			defer server.Close()
+
+			got, err := discoverAccessKeyIdentity(server.URL+"/v3", "eu-de",
+				config.AccessKeyPair{AccessKey: "AKEXAMPLE", SecretKey: "secret"})
+			if err != nil {
+				t.Fatalf("discoverAccessKeyIdentity() error = %v", err)
+			}
+			want := AccessKeyIdentity{
+				Domain:   config.NameAndIDResource{Name: "OTC-EU-DE-000", ID: "domain-id"},
+				UserID:   "user-id",
+				Username: tt.wantUsername,
+			}
+			if *got != want {
+				t.Errorf("discoverAccessKeyIdentity() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
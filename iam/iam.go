@@ -52,50 +52,61 @@ func AuthenticateAndGetUnscopedToken(authInfo common.AuthInfo) (*common.TokenRes
 }
 
 func GetScopedToken(projectName string) config.Token {
-	activeCloud, err := config.GetActiveCloudConfig()
+	token, err := ScopedToken(projectName)
 	if err != nil {
 		common.ThrowError(err)
 	}
+	return token
+}
+
+// ScopedToken returns the cached scoped token of the project, or requests and
+// caches a new one if it expired. Unlike GetScopedToken it returns errors, so
+// long running commands survive a failed renewal.
+func ScopedToken(projectName string) (config.Token, error) {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return config.Token{}, err
+	}
 	project, err := activeCloud.Projects.GetProjectByName(projectName)
 	if err != nil {
-		common.ThrowError(err)
+		return config.Token{}, err
 	}
 	if project.ScopedToken.IsTokenValid() {
 		token := project.ScopedToken
 
 		tokenExpirationDate, parseErr := common.ParseTime(token.ExpiresAt)
 		if parseErr != nil {
-			common.ThrowError(parseErr)
+			return config.Token{}, parseErr
 		}
 		if tokenExpirationDate.After(time.Now()) {
 			glog.V(common.InfoLogLevel).Infof("info: scoped token is valid until %s \n",
 				tokenExpirationDate.Format(common.PrintTimeFormat))
-			return token
+			return token, nil
 		}
 	}
 
 	glog.V(common.InfoLogLevel).Infof("info: attempting to request a scoped token for %s\n", projectName)
 	cloud, err := getCloudWithScopedTokenFromServiceProvider(projectName)
 	if err != nil {
-		common.ThrowError(err)
+		return config.Token{}, err
 	}
 	config.UpdateCloudConfig(*cloud)
 	glog.V(common.InfoLogLevel).Info("info: scoped token acquired successfully")
-	project, err = activeCloud.Projects.GetProjectByName(projectName)
+	project, err = cloud.Projects.GetProjectByName(projectName)
 	if err != nil {
-		common.ThrowError(err)
+		return config.Token{}, err
 	}
-	return project.ScopedToken
+	return project.ScopedToken, nil
 }
 
 func getCloudWithScopedTokenFromServiceProvider(projectName string) (*config.Cloud, error) {
 	activeCloud, err := config.GetActiveCloudConfig()
 	if err != nil {
-		common.ThrowError(err)
+		return nil, err
 	}
 	project, err := activeCloud.Projects.GetProjectByName(projectName)
 	if err != nil {
-		common.ThrowError(err)
+		return nil, err
 	}
 
 	authOpts := golangsdk.AuthOptions{
@@ -107,16 +118,16 @@ func getCloudWithScopedTokenFromServiceProvider(projectName string) (*config.Clo
 
 	provider, err := openstack.AuthenticatedClient(authOpts)
 	if err != nil {
-		common.ThrowError(err)
+		return nil, err
 	}
 	client, err := openstack.NewIdentityV3(provider, golangsdk.EndpointOpts{})
 	if err != nil {
-		common.ThrowError(err)
+		return nil, err
 	}
 
 	scopedToken, err := tokens.Create(client, &authOpts).ExtractToken()
 	if err != nil {
-		common.ThrowError(err)
+		return nil, err
 	}
 
 	token := config.Token{
@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"otc-auth/common"
-	"otc-auth/common/endpoints"
 	"otc-auth/config"
 
 	"github.com/golang/glog"
@@ -60,11 +59,7 @@ func getProjectsFromServiceProvider() (projectsResponse common.ProjectsResponse)
 	}
 	glog.V(common.InfoLogLevel).Infof("info: fetching projects for cloud %s \n", activeCloud.Domain.Name)
 
-	provider, err := openstack.AuthenticatedClient(golangsdk.AuthOptions{
-		IdentityEndpoint: endpoints.BaseURLIam(activeCloud.Region),
-		DomainID:         activeCloud.Domain.ID,
-		TokenID:          activeCloud.UnscopedToken.Secret,
-	})
+	provider, err := openstack.AuthenticatedClient(activeCloud.AuthOptions(nil))
 	if err != nil {
 		common.ThrowError(err)
 	}
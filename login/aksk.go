@@ -0,0 +1,50 @@
+package login
+
+import (
+	"fmt"
+
+	"otc-auth/common"
+	"otc-auth/config"
+	"otc-auth/iam"
+
+	"github.com/golang/glog"
+)
+
+// AuthenticateWithAccessKey registers the cloud a permanent AK/SK pair belongs
+// to and fetches its projects. Such clouds have no tokens, every request is
+// signed with the key pair instead (see config.Cloud.AuthOptions).
+func AuthenticateWithAccessKey(authInfo common.AuthInfo) error {
+	keyPair := config.AccessKeyPair{AccessKey: authInfo.AccessKey, SecretKey: authInfo.SecretKey}
+	identity, err := iam.DiscoverAccessKeyIdentity(authInfo.Region, keyPair)
+	if err != nil {
+		return err
+	}
+	if authInfo.DomainName != "" && authInfo.DomainName != identity.Domain.Name {
+		return fmt.Errorf("fatal: access key %s belongs to domain %s, not to %s",
+			keyPair.AccessKey, identity.Domain.Name, authInfo.DomainName)
+	}
+
+	err = config.LoadCloudConfig(identity.Domain.Name)
+	if err != nil {
+		return fmt.Errorf("couldn't load config: %w", err)
+	}
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return err
+	}
+	// scoped tokens of a previous login are of no use anymore
+	for i := range activeCloud.Projects {
+		activeCloud.Projects[i].ScopedToken = config.Token{}
+	}
+	activeCloud.Domain = identity.Domain
+	activeCloud.Region = authInfo.Region
+	activeCloud.Username = identity.Username
+	activeCloud.UnscopedToken = config.Token{}
+	activeCloud.AccessKey = &keyPair
+	config.UpdateCloudConfig(*activeCloud)
+
+	iam.GetProjectsInActiveCloud()
+	glog.V(common.InfoLogLevel).Infof("info: logged in to %s as %s with access key %s",
+		identity.Domain.Name, identity.Username, keyPair.AccessKey)
+	return nil
+}
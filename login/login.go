@@ -20,7 +20,12 @@ func AuthenticateAndGetUnscopedToken(loginCtx context.Context, authInfo common.A
 		return fmt.Errorf("couldn't load config: %w", err)
 	}
 
-	if config.IsAuthenticationValid() && !authInfo.OverwriteFile {
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		return err
+	}
+	// a cloud logged in to with an access key is switched over to token based auth
+	if activeCloud.AccessKey == nil && config.IsAuthenticationValid() && !authInfo.OverwriteFile {
 		glog.V(common.InfoLogLevel).Info(
 			"info: will not retrieve unscoped token, because the current one is still valid.\n" +
 				"To overwrite the existing unscoped token, pass the \"--overwrite-token\" argument")
@@ -100,5 +105,6 @@ func updateOTCInfoFile(tokenResponse common.TokenResponse, regionCode string) {
 	}
 	activeCloud.Region = regionCode
 	activeCloud.UnscopedToken = token
+	activeCloud.AccessKey = nil
 	config.UpdateCloudConfig(*activeCloud)
 }
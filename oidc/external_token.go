@@ -0,0 +1,198 @@
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"otc-auth/common"
+
+	"github.com/golang/glog"
+)
+
+const (
+	jwtSegments = 3
+
+	// CI runners and the kubelet rotate projected tokens some time before they
+	// expire; polling every few seconds picks up the new one quickly enough.
+	tokenFilePollInterval = 5 * time.Second
+
+	externalTokenUsername = "WorkloadIdentity"
+)
+
+type externalTokenClaims struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+	ExpiresAt         int64  `json:"exp"`
+}
+
+type externalTokenReader struct {
+	readFile     func(name string) ([]byte, error)
+	lookupEnv    func(key string) (string, bool)
+	runCommand   func(ctx context.Context, command string) ([]byte, error)
+	now          func() time.Time
+	pollInterval time.Duration
+}
+
+func newExternalTokenReader() *externalTokenReader {
+	return &externalTokenReader{
+		readFile:     os.ReadFile,
+		lookupEnv:    os.LookupEnv,
+		runCommand:   runTokenCommand,
+		now:          time.Now,
+		pollInterval: tokenFilePollInterval,
+	}
+}
+
+// HasExternalIDToken reports whether the login should skip the IdP and use an
+// ID token handed to us by the environment (CI job, projected service account
+// token, ...).
+func HasExternalIDToken(authInfo common.AuthInfo) bool {
+	return authInfo.IDTokenFile != "" || authInfo.IDTokenEnv != "" || authInfo.IDTokenCommand != ""
+}
+
+func authenticateWithExternalIDToken(ctx context.Context,
+	authInfo common.AuthInfo,
+) (*common.OidcCredentialsResponse, error) {
+	return newExternalTokenReader().credentials(ctx, authInfo)
+}
+
+func (r *externalTokenReader) credentials(ctx context.Context,
+	authInfo common.AuthInfo,
+) (*common.OidcCredentialsResponse, error) {
+	rawToken, claims, err := r.read(ctx, authInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rawToken) > maxIDTokenLength {
+		glog.Warningf(
+			"warning: id token longer than %d characters – consider removing some groups or roles",
+			maxIDTokenLength,
+		)
+	}
+
+	creds := common.OidcCredentialsResponse{BearerToken: rawToken}
+	switch {
+	case claims.PreferredUsername != "":
+		creds.Claims.PreferredUsername = claims.PreferredUsername
+	case claims.Subject != "":
+		creds.Claims.PreferredUsername = claims.Subject
+	default:
+		creds.Claims.PreferredUsername = externalTokenUsername
+	}
+	return &creds, nil
+}
+
+func (r *externalTokenReader) read(ctx context.Context,
+	authInfo common.AuthInfo,
+) (string, *externalTokenClaims, error) {
+	switch {
+	case authInfo.IDTokenFile != "":
+		return r.readFromFile(ctx, authInfo.IDTokenFile)
+	case authInfo.IDTokenEnv != "":
+		value, ok := r.lookupEnv(authInfo.IDTokenEnv)
+		if !ok || strings.TrimSpace(value) == "" {
+			return "", nil, fmt.Errorf("fatal: environment variable %s holds no id token", authInfo.IDTokenEnv)
+		}
+		return r.parseFresh(value)
+	case authInfo.IDTokenCommand != "":
+		output, err := r.runCommand(ctx, authInfo.IDTokenCommand)
+		if err != nil {
+			return "", nil, fmt.Errorf("fatal: id token command failed: %w", err)
+		}
+		return r.parseFresh(string(output))
+	default:
+		return "", nil, errors.New("fatal: no external id token source configured")
+	}
+}
+
+// readFromFile reads the token from disk on every call. A token that is
+// already expired is not rejected straight away: the file is re-read until
+// its owner (CI runner, kubelet) has rotated it or the login context runs out.
+func (r *externalTokenReader) readFromFile(ctx context.Context, path string) (string, *externalTokenClaims, error) {
+	for {
+		content, err := r.readFile(path)
+		if err != nil {
+			return "", nil, fmt.Errorf("fatal: couldn't read id token file %s: %w", path, err)
+		}
+		rawToken := strings.TrimSpace(string(content))
+		claims, err := parseExternalTokenClaims(rawToken)
+		if err != nil {
+			return "", nil, fmt.Errorf("fatal: invalid id token in %s: %w", path, err)
+		}
+		if !claims.isExpired(r.now()) {
+			return rawToken, claims, nil
+		}
+
+		glog.V(common.InfoLogLevel).Infof(
+			"info: id token in %s expired at %s, waiting for it to be rotated...",
+			path, time.Unix(claims.ExpiresAt, 0).Format(common.PrintTimeFormat))
+		select {
+		case <-ctx.Done():
+			return "", nil, fmt.Errorf("fatal: id token in %s expired and was not rotated in time: %w",
+				path, ctx.Err())
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+func (r *externalTokenReader) parseFresh(value string) (string, *externalTokenClaims, error) {
+	rawToken := strings.TrimSpace(value)
+	claims, err := parseExternalTokenClaims(rawToken)
+	if err != nil {
+		return "", nil, fmt.Errorf("fatal: invalid id token: %w", err)
+	}
+	if claims.isExpired(r.now()) {
+		return "", nil, fmt.Errorf("fatal: id token expired at %s",
+			time.Unix(claims.ExpiresAt, 0).Format(common.PrintTimeFormat))
+	}
+	return rawToken, claims, nil
+}
+
+// parseExternalTokenClaims only decodes the payload. Verifying the signature
+// is the job of the OTC federation endpoint, which knows the trusted issuer.
+func parseExternalTokenClaims(rawToken string) (*externalTokenClaims, error) {
+	rawToken = strings.TrimPrefix(rawToken, "Bearer ")
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != jwtSegments {
+		return nil, fmt.Errorf("expected a JWT with %d segments, got %d", jwtSegments, len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode jwt payload: %w", err)
+	}
+	var claims externalTokenClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("couldn't parse jwt claims: %w", err)
+	}
+	return &claims, nil
+}
+
+func (c *externalTokenClaims) isExpired(now time.Time) bool {
+	return c.ExpiresAt != 0 && !time.Unix(c.ExpiresAt, 0).After(now)
+}
+
+func runTokenCommand(ctx context.Context, command string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command) //nolint:gosec // command is supplied by the invoking user
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec // command is supplied by the invoking user
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
@@ -0,0 +1,205 @@
+//nolint:testpackage // whitebox testing
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"otc-auth/common"
+)
+
+func makeTestJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("couldn't marshal claims: %v", err)
+	}
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func Test_externalTokenReader_credentials(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	validToken := makeTestJWT(t, map[string]any{
+		"sub": "project_path:group/repo:ref_type:branch:ref:main", "exp": now.Add(time.Hour).Unix(),
+	})
+	namedToken := makeTestJWT(t, map[string]any{
+		"sub": "system:serviceaccount:ci:deployer", "preferred_username": "deployer", "exp": now.Add(time.Hour).Unix(),
+	})
+	expiredToken := makeTestJWT(t, map[string]any{"sub": "old", "exp": now.Add(-time.Minute).Unix()})
+	anonymousToken := makeTestJWT(t, map[string]any{"aud": "otc"})
+
+	tests := []struct {
+		name         string
+		authInfo     common.AuthInfo
+		files        map[string]string
+		env          map[string]string
+		commandOut   string
+		commandErr   error
+		wantToken    string
+		wantUsername string
+		wantErrMsg   string
+	}{
+		{
+			name:         "token from file, subject used as username",
+			authInfo:     common.AuthInfo{IDTokenFile: "/token"},
+			files:        map[string]string{"/token": validToken + "\n"},
+			wantToken:    validToken,
+			wantUsername: "project_path:group/repo:ref_type:branch:ref:main",
+		},
+		{
+			name:         "preferred_username wins over subject",
+			authInfo:     common.AuthInfo{IDTokenEnv: "CI_JOB_JWT"},
+			env:          map[string]string{"CI_JOB_JWT": namedToken},
+			wantToken:    namedToken,
+			wantUsername: "deployer",
+		},
+		{
+			name:         "token from command without identity claims",
+			authInfo:     common.AuthInfo{IDTokenCommand: "print-token"},
+			commandOut:   anonymousToken + "\n",
+			wantToken:    anonymousToken,
+			wantUsername: externalTokenUsername,
+		},
+		{
+			name:       "missing env var",
+			authInfo:   common.AuthInfo{IDTokenEnv: "UNSET"},
+			wantErrMsg: "environment variable UNSET holds no id token",
+		},
+		{
+			name:       "expired token from env is rejected",
+			authInfo:   common.AuthInfo{IDTokenEnv: "CI_JOB_JWT"},
+			env:        map[string]string{"CI_JOB_JWT": expiredToken},
+			wantErrMsg: "id token expired",
+		},
+		{
+			name:       "failing command",
+			authInfo:   common.AuthInfo{IDTokenCommand: "print-token"},
+			commandErr: errors.New("exit status 1"),
+			wantErrMsg: "id token command failed",
+		},
+		{
+			name:       "file does not hold a JWT",
+			authInfo:   common.AuthInfo{IDTokenFile: "/token"},
+			files:      map[string]string{"/token": "not-a-jwt"},
+			wantErrMsg: "expected a JWT",
+		},
+		{
+			name:       "unreadable file",
+			authInfo:   common.AuthInfo{IDTokenFile: "/missing"},
+			wantErrMsg: "couldn't read id token file /missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &externalTokenReader{
+				readFile: func(name string) ([]byte, error) {
+					content, ok := tt.files[name]
+					if !ok {
+						return nil, errors.New("no such file")
+					}
+					return []byte(content), nil
+				},
+				lookupEnv: func(key string) (string, bool) {
+					value, ok := tt.env[key]
+					return value, ok
+				},
+				runCommand: func(context.Context, string) ([]byte, error) {
+					return []byte(tt.commandOut), tt.commandErr
+				},
+				now:          func() time.Time { return now },
+				pollInterval: time.Millisecond,
+			}
+
+			got, err := r.credentials(context.Background(), tt.authInfo)
+			if tt.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("credentials() error = %v, want it to contain %q", err, tt.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("credentials() unexpected error = %v", err)
+			}
+			if got.BearerToken != tt.wantToken {
+				t.Errorf("BearerToken = %q, want %q", got.BearerToken, tt.wantToken)
+			}
+			if got.Claims.PreferredUsername != tt.wantUsername {
+				t.Errorf("PreferredUsername = %q, want %q", got.Claims.PreferredUsername, tt.wantUsername)
+			}
+		})
+	}
+}
+
+func Test_externalTokenReader_readFromFile_waitsForRotation(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiredToken := makeTestJWT(t, map[string]any{"sub": "ci", "exp": now.Add(-time.Minute).Unix()})
+	rotatedToken := makeTestJWT(t, map[string]any{"sub": "ci", "exp": now.Add(time.Hour).Unix()})
+
+	reads := 0
+	r := &externalTokenReader{
+		readFile: func(string) ([]byte, error) {
+			reads++
+			if reads < 3 {
+				return []byte(expiredToken), nil
+			}
+			return []byte(rotatedToken), nil
+		},
+		now:          func() time.Time { return now },
+		pollInterval: time.Millisecond,
+	}
+
+	got, _, err := r.readFromFile(context.Background(), "/token")
+	if err != nil {
+		t.Fatalf("readFromFile() unexpected error = %v", err)
+	}
+	if got != rotatedToken {
+		t.Errorf("readFromFile() = %q, want the rotated token", got)
+	}
+	if reads != 3 {
+		t.Errorf("file read %d times, want 3", reads)
+	}
+}
+
+func Test_externalTokenReader_readFromFile_givesUpWithContext(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiredToken := makeTestJWT(t, map[string]any{"sub": "ci", "exp": now.Add(-time.Minute).Unix()})
+
+	r := &externalTokenReader{
+		readFile:     func(string) ([]byte, error) { return []byte(expiredToken), nil },
+		now:          func() time.Time { return now },
+		pollInterval: time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _, err := r.readFromFile(ctx, "/token")
+	if err == nil || !strings.Contains(err.Error(), "was not rotated in time") {
+		t.Fatalf("readFromFile() error = %v, want a rotation timeout", err)
+	}
+}
+
+func TestHasExternalIDToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		authInfo common.AuthInfo
+		want     bool
+	}{
+		{name: "none", authInfo: common.AuthInfo{ClientID: "client"}, want: false},
+		{name: "file", authInfo: common.AuthInfo{IDTokenFile: "/token"}, want: true},
+		{name: "env", authInfo: common.AuthInfo{IDTokenEnv: "TOKEN"}, want: true},
+		{name: "command", authInfo: common.AuthInfo{IDTokenCommand: "cat /token"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasExternalIDToken(tt.authInfo); got != tt.want {
+				t.Errorf("HasExternalIDToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 type AuthService struct {
 	authUserFn           func(common.AuthInfo, context.Context) (*common.OidcCredentialsResponse, error)
 	authServiceAccountFn func(context.Context, common.AuthInfo, common.HTTPClient) (*common.OidcCredentialsResponse, error)
+	authExternalTokenFn  func(context.Context, common.AuthInfo) (*common.OidcCredentialsResponse, error)
 	authTokenExchangeFn  func(context.Context, common.OidcCredentialsResponse,
 		common.AuthInfo, common.HTTPClient) (*common.TokenResponse, error)
 }
@@ -23,6 +24,7 @@ func newAuthService() *AuthService {
 	return &AuthService{
 		authUserFn:           authenticateWithIdp,
 		authServiceAccountFn: authenticateServiceAccountWithIdp,
+		authExternalTokenFn:  authenticateWithExternalIDToken,
 		authTokenExchangeFn:  authenticateWithServiceProvider,
 	}
 }
@@ -34,9 +36,12 @@ func (s *AuthService) authenticate(ctx context.Context,
 	var err error
 	httpClient := common.NewHTTPClient(authInfo.SkipTLS)
 
-	if authInfo.IsServiceAccount {
+	switch {
+	case HasExternalIDToken(authInfo):
+		oidcCredentials, err = s.authExternalTokenFn(ctx, authInfo)
+	case authInfo.IsServiceAccount:
 		oidcCredentials, err = s.authServiceAccountFn(ctx, authInfo, httpClient)
-	} else {
+	default:
 		oidcCredentials, err = s.authUserFn(authInfo, ctx)
 	}
 
@@ -247,6 +247,22 @@ func TestAuthenticateAndGetUnscopedToken(t *testing.T) {
 			want:       expectedTokenResponse,
 			wantErrMsg: "",
 		},
+		{
+			name:     "External id token skips the IdP",
+			authInfo: common.AuthInfo{IDTokenFile: "/var/run/secrets/token", IsServiceAccount: true},
+			authService: &AuthService{
+				authExternalTokenFn: func(context.Context, common.AuthInfo) (*common.OidcCredentialsResponse, error) {
+					return mockOidcCreds, nil
+				},
+				authTokenExchangeFn: func(context.Context,
+					common.OidcCredentialsResponse, common.AuthInfo, common.HTTPClient,
+				) (*common.TokenResponse, error) {
+					return expectedTokenResponse, nil
+				},
+			},
+			want:       expectedTokenResponse,
+			wantErrMsg: "",
+		},
 		{
 			name:     "Failure on user authentication step",
 			authInfo: common.AuthInfo{IsServiceAccount: false},
@@ -0,0 +1,165 @@
+package openstack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"otc-auth/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	cloudsKey = "clouds"
+	// secureFileName is read by the OpenStack SDKs next to the clouds.yaml
+	// and merged into its entries
+	secureFileName = "secure.yaml"
+	backupSuffix   = ".bak"
+	yamlIndent     = 2
+)
+
+// secureCloud holds the secrets of a clouds.yaml entry in the secure.yaml.
+type secureCloud struct {
+	AuthInfo  *secureAuthInfo `yaml:"auth,omitempty"`
+	AccessKey string          `yaml:"ak,omitempty"`
+	SecretKey string          `yaml:"sk,omitempty"`
+}
+
+type secureAuthInfo struct {
+	Token    string `yaml:"token,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// splitSecrets moves the token, the password and the AK/SK of the entry to
+// its secure.yaml counterpart.
+func splitSecrets(cloud otcCloud) (otcCloud, secureCloud) {
+	secret := secureCloud{AccessKey: cloud.AccessKey, SecretKey: cloud.SecretKey}
+	cloud.AccessKey, cloud.SecretKey = "", ""
+	if cloud.AuthInfo != nil && (cloud.AuthInfo.Token != "" || cloud.AuthInfo.Password != "") {
+		// the auth info may be shared, change a copy
+		authInfo := *cloud.AuthInfo
+		secret.AuthInfo = &secureAuthInfo{Token: authInfo.Token, Password: authInfo.Password}
+		authInfo.Token, authInfo.Password = "", ""
+		cloud.AuthInfo = &authInfo
+	}
+	return cloud, secret
+}
+
+// mergeCloudsFile replaces the entries in the clouds of the file, or adds
+// them, and removes the clouds named in remove. Everything else is left as it
+// is. The previous file is kept with the .bak suffix.
+func mergeCloudsFile(path string, entries map[string]any, remove []string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("fatal: couldn't read %s\ntrace: %w", path, err)
+	}
+	merged, err := mergeClouds(existing, entries, remove)
+	if err != nil {
+		return fmt.Errorf("fatal: couldn't update %s\ntrace: %w", path, err)
+	}
+	if bytes.Equal(existing, merged) {
+		return nil
+	}
+	if len(existing) > 0 {
+		if err = config.WriteConfigFile(string(existing), path+backupSuffix); err != nil {
+			return err
+		}
+	}
+	return config.WriteConfigFile(string(merged), path)
+}
+
+// mergeClouds works on the yaml nodes, so comments and the order of the
+// entries otc-auth doesn't manage survive.
+func mergeClouds(existing []byte, entries map[string]any, remove []string) ([]byte, error) {
+	var document yaml.Node
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := yaml.Unmarshal(existing, &document); err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+	}
+	if document.Kind == 0 {
+		document.Kind = yaml.DocumentNode
+	}
+	if len(document.Content) == 0 {
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("the file isn't a yaml mapping")
+	}
+
+	clouds := mappingValue(root, cloudsKey)
+	switch {
+	case clouds == nil:
+		clouds = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		root.Content = append(root.Content, scalarNode(cloudsKey), clouds)
+	case clouds.Tag == "!!null":
+		// "clouds:" without entries
+		clouds.Kind, clouds.Tag, clouds.Value = yaml.MappingNode, "!!map", ""
+	case clouds.Kind != yaml.MappingNode:
+		return nil, fmt.Errorf("%s isn't a mapping", cloudsKey)
+	}
+
+	removeClouds(clouds, remove)
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var value yaml.Node
+		if err := value.Encode(entries[name]); err != nil {
+			return nil, fmt.Errorf("couldn't encode %s: %w", name, err)
+		}
+		if current := mappingValue(clouds, name); current != nil {
+			value.HeadComment, value.LineComment, value.FootComment =
+				current.HeadComment, current.LineComment, current.FootComment
+			*current = value
+			continue
+		}
+		clouds.Content = append(clouds.Content, scalarNode(name), &value)
+	}
+
+	var merged bytes.Buffer
+	encoder := yaml.NewEncoder(&merged)
+	encoder.SetIndent(yamlIndent)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return merged.Bytes(), nil
+}
+
+// removeClouds removes the named clouds, names which aren't there are
+// skipped.
+func removeClouds(clouds *yaml.Node, names []string) {
+	kept := clouds.Content[:0]
+	for index := 0; index+1 < len(clouds.Content); index += 2 {
+		if slices.Contains(names, clouds.Content[index].Value) {
+			continue
+		}
+		kept = append(kept, clouds.Content[index], clouds.Content[index+1])
+	}
+	clouds.Content = kept
+}
+
+// mappingValue returns the value of the key in the mapping, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for index := 0; index+1 < len(mapping.Content); index += 2 {
+		if mapping.Content[index].Value == key {
+			return mapping.Content[index+1]
+		}
+	}
+	return nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,149 @@
+//nolint:testpackage // whitebox testing
+package openstack
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/utils/openstack/clientconfig"
+	"gopkg.in/yaml.v3"
+)
+
+const handWrittenClouds = `# clouds of the team
+clouds:
+  # the lab, don't touch
+  lab:
+    auth:
+      auth_url: https://keystone.lab.example/v3
+    region_name: lab-1
+  demo_projectA: # written by otc-auth
+    auth:
+      token: old
+  zzz:
+    auth_type: password
+`
+
+func TestMergeClouds(t *testing.T) {
+	t.Parallel()
+	entries := map[string]any{
+		"demo_projectA": otcCloud{Cloud: clientconfig.Cloud{
+			AuthInfo: &clientconfig.AuthInfo{Token: "new"}, AuthType: "token",
+		}},
+		"demo_projectB": otcCloud{Cloud: clientconfig.Cloud{AuthType: "token"}},
+	}
+	tests := []struct {
+		name     string
+		existing string
+		remove   []string
+		want     []string
+		notWant  []string
+		wantErr  bool
+	}{
+		{
+			name:     "hand written",
+			existing: handWrittenClouds,
+			want: []string{
+				"# clouds of the team\nclouds:\n  # the lab, don't touch\n  lab:\n",
+				"region_name: lab-1\n  demo_projectA: # written by otc-auth\n",
+				"token: new",
+				"zzz:\n    auth_type: password\n  demo_projectB:\n",
+			},
+		},
+		{name: "missing", want: []string{"clouds:\n  demo_projectA:\n"}},
+		{name: "only comments", existing: "# nothing yet\n", want: []string{"demo_projectB:"}},
+		{name: "empty clouds", existing: "clouds:\n", want: []string{"clouds:\n  demo_projectA:\n"}},
+		{name: "other keys", existing: "client:\n  force_ipv4: true\n", want: []string{"force_ipv4: true\nclouds:\n"}},
+		{
+			name:     "stale entries",
+			existing: "clouds:\n  demo_gone:\n    auth_type: token\n  demo_admin:\n    region_name: lab-1\n",
+			remove:   []string{"demo_gone"},
+			want:     []string{"demo_admin:\n    region_name: lab-1\n", "demo_projectA:", "demo_projectB:"},
+			notWant:  []string{"demo_gone"},
+		},
+		{name: "list", existing: "- lab\n", wantErr: true},
+		{name: "clouds list", existing: "clouds:\n  - lab\n", wantErr: true},
+		{name: "invalid", existing: "clouds: [\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			merged, err := mergeClouds([]byte(tt.existing), entries, tt.remove)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("mergeClouds() succeeded:\n%s", merged)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(merged), want) {
+					t.Errorf("merged =\n%s\nwant it to contain\n%s", merged, want)
+				}
+			}
+			for _, notWant := range append(tt.notWant, "old") {
+				if strings.Contains(string(merged), notWant) {
+					t.Errorf("merged =\n%s\nstill holds %s", merged, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeCloudsFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	if err := os.WriteFile(path, []byte(handWrittenClouds), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]any{"demo_projectA": otcCloud{Cloud: clientconfig.Cloud{AuthType: "token"}}}
+
+	if err := mergeCloudsFile(path, entries, nil); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := os.ReadFile(path + backupSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != handWrittenClouds {
+		t.Errorf("backup =\n%s\nwant the previous file", backup)
+	}
+
+	// an unchanged file is neither written nor backed up again
+	if err = os.Remove(path + backupSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if err = mergeCloudsFile(path, entries, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path + backupSuffix); !os.IsNotExist(err) {
+		t.Errorf("unchanged file was backed up: %v", err)
+	}
+}
+
+func TestSplitSecrets(t *testing.T) {
+	t.Parallel()
+	authInfo := &clientconfig.AuthInfo{AuthURL: "https://iam", Token: "token"}
+	cloud, secret := splitSecrets(otcCloud{
+		Cloud:     clientconfig.Cloud{AuthInfo: authInfo},
+		AccessKey: "AK",
+		SecretKey: "SK",
+	})
+	if cloud.AccessKey != "" || cloud.SecretKey != "" || cloud.AuthInfo.Token != "" ||
+		cloud.AuthInfo.AuthURL != "https://iam" {
+		t.Errorf("cloud = %+v %+v, want no secrets but the auth URL", cloud, cloud.AuthInfo)
+	}
+	if authInfo.Token != "token" {
+		t.Error("splitSecrets() changed the auth info of its argument")
+	}
+	encoded, err := yaml.Marshal(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "auth:\n    token: token\nak: AK\nsk: SK\n"; string(encoded) != want {
+		t.Errorf("secret =\n%s\nwant\n%s", encoded, want)
+	}
+}
@@ -1,10 +1,15 @@
 package openstack
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 
 	"otc-auth/common"
 	"otc-auth/common/endpoints"
@@ -12,67 +17,255 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/gophercloud/utils/openstack/clientconfig"
-	"gopkg.in/yaml.v3"
 )
 
-func WriteOpenStackCloudsYaml(openStackConfigFileLocation string) {
+// Auth types of the clouds.yaml entries.
+const (
+	// AuthTypeToken authenticates with the scoped token of the project, it
+	// expires like the login
+	AuthTypeToken = "token"
+	// AuthTypePassword authenticates with the username and password
+	AuthTypePassword = "password"
+	// AuthTypeAkSk signs requests with a permanent AK/SK, it needs the OTC
+	// extensions for the OpenStack SDK (otcextensions)
+	AuthTypeAkSk = "aksk"
+)
+
+// AuthTypes returns all auth types WriteOpenStackCloudsYaml can write.
+func AuthTypes() []string {
+	return []string{AuthTypeToken, AuthTypePassword, AuthTypeAkSk}
+}
+
+// Options configures WriteOpenStackCloudsYaml.
+type Options struct {
+	// Location of the clouds.yaml, ~/.config/openstack/clouds.yaml if empty
+	Location string
+	// SecureFile moves tokens and secret keys to the secure.yaml next to the
+	// clouds.yaml
+	SecureFile bool
+	// AuthType is one of AuthTypes, the one matching the login if empty
+	AuthType string
+	// Password is written with AuthTypePassword
+	Password string
+	// AccessKey is written with AuthTypeAkSk, the AK/SK of the login if nil
+	AccessKey *config.AccessKeyPair
+	// EndpointOverrides pins the endpoints of the OTC services. Clients
+	// signing with an AK/SK get no service catalog to look them up in.
+	EndpointOverrides bool
+}
+
+// WriteOpenStackCloudsYaml writes a clouds.yaml entry named <domain>_<project>
+// for every project. The entries otc-auth wrote last time for projects which
+// are gone are removed, all other entries of an existing clouds.yaml are
+// kept.
+func WriteOpenStackCloudsYaml(options Options) {
 	cloudConfig, err := config.GetActiveCloudConfig()
 	if err != nil {
 		common.ThrowError(err)
 	}
-	domainName := cloudConfig.Domain.Name
-	clouds := make(map[string]clientconfig.Cloud)
+	clouds, err := createOpenstackClouds(cloudConfig, options)
+	if err != nil {
+		common.ThrowError(err)
+	}
+	names := make([]string, 0, len(clouds))
+	for name := range clouds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var stale []string
+	for _, name := range cloudConfig.OpenstackClouds {
+		if _, ok := clouds[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	if err = createOpenstackCloudsYAML(clouds, stale, options); err != nil {
+		common.ThrowError(err)
+	}
+	cloudConfig.OpenstackClouds = names
+	config.UpdateCloudConfig(*cloudConfig)
+	glog.V(common.InfoLogLevel).Info("info: openstack clouds.yaml was updated")
+}
+
+func createOpenstackClouds(cloudConfig *config.Cloud, options Options) (map[string]otcCloud, error) {
+	authType := options.AuthType
+	if authType == "" {
+		authType = AuthTypeToken
+		if cloudConfig.AccessKey != nil {
+			authType = AuthTypeAkSk
+		}
+	}
+	keyPair := options.AccessKey
+	if keyPair == nil {
+		keyPair = cloudConfig.AccessKey
+	}
+	switch authType {
+	case AuthTypeToken:
+		if cloudConfig.AccessKey != nil {
+			return nil, errors.New("fatal: logged in with an AK/SK there are no tokens, use the aksk auth type")
+		}
+	case AuthTypePassword:
+		if options.Password == "" {
+			return nil, errors.New("fatal: the password auth type needs the password")
+		}
+		if cloudConfig.Username == "" {
+			return nil, errors.New("fatal: the password auth type needs an IAM login with a username")
+		}
+	case AuthTypeAkSk:
+		if keyPair == nil {
+			return nil, errors.New("fatal: the aksk auth type needs an AK/SK, log in with one or pass it")
+		}
+	default:
+		return nil, fmt.Errorf("fatal: unknown auth type %s, use one of %s", authType, strings.Join(AuthTypes(), ", "))
+	}
+
+	clouds := make(map[string]otcCloud, len(cloudConfig.Projects))
 	for _, project := range cloudConfig.Projects {
-		cloudName := domainName + "_" + project.Name
-		clouds[cloudName] = createOpenstackCloudConfig(project, domainName, cloudConfig.Region)
+		var cloud otcCloud
+		switch authType {
+		case AuthTypeToken:
+			cloud = otcCloud{Cloud: createOpenstackCloudConfig(project, cloudConfig.Domain, cloudConfig.Region)}
+		case AuthTypePassword:
+			cloud = otcCloud{Cloud: createOpenstackPasswordCloudConfig(project, cloudConfig.Domain,
+				cloudConfig.Region, cloudConfig.Username, options.Password)}
+		case AuthTypeAkSk:
+			cloud = createOpenstackAccessKeyCloudConfig(project, cloudConfig.Domain, cloudConfig.Region, *keyPair)
+		}
+		if options.EndpointOverrides {
+			cloud.EndpointOverrides = endpointOverrides(cloud.RegionName, project.ID)
+		}
+		clouds[cloudConfig.Domain.Name+"_"+project.Name] = cloud
 	}
+	return clouds, nil
+}
 
-	createOpenstackCloudsYAML(clientconfig.Clouds{Clouds: clouds}, openStackConfigFileLocation)
+// otcCloud is a clouds.yaml entry with the AK/SK fields of the OTC extensions
+// for the OpenStack SDK (otcextensions) and the endpoint overrides of the
+// OpenStack SDK, e.g. compute_endpoint_override.
+type otcCloud struct {
+	clientconfig.Cloud `yaml:",inline"`
+	AccessKey          string            `yaml:"ak,omitempty"`
+	SecretKey          string            `yaml:"sk,omitempty"`
+	EndpointOverrides  map[string]string `yaml:",inline"`
 }
 
-func createOpenstackCloudConfig(project config.Project, domainName string, regionCode string) clientconfig.Cloud {
-	projectName := project.Name
-	cloudName := domainName + "_" + projectName
+// projectRegionPattern matches the region prefix of OTC project names, e.g.
+// eu-de in eu-de_projectA.
+var projectRegionPattern = regexp.MustCompile(`^[a-z]{2}-[a-z]{2}[0-9]*$`)
 
-	authInfo := clientconfig.AuthInfo{
-		AuthURL:           endpoints.BaseURLIam(regionCode),
-		Token:             project.ScopedToken.Secret,
-		ProjectDomainName: projectName,
+// projectRegion returns the region of the project, the region of the login if
+// the project name doesn't start with one.
+func projectRegion(projectName string, loginRegion string) string {
+	region, _, _ := strings.Cut(projectName, "_")
+	if projectRegionPattern.MatchString(region) {
+		return region
 	}
+	return loginRegion
+}
 
-	openstackCloudConfig := clientconfig.Cloud{
-		Cloud:              cloudName,
-		Profile:            cloudName,
+// openstackCloudConfig has what all auth types share. The entry has neither
+// cloud nor profile set, those name a vendor profile which clients would try
+// to load.
+func openstackCloudConfig(project config.Project, regionCode string, authInfo clientconfig.AuthInfo,
+	authType clientconfig.AuthType,
+) clientconfig.Cloud {
+	authInfo.AuthURL = endpoints.BaseURLIam(regionCode)
+	authInfo.ProjectName = project.Name
+	authInfo.ProjectID = project.ID
+	return clientconfig.Cloud{
 		AuthInfo:           &authInfo,
-		AuthType:           "token",
+		AuthType:           authType,
+		RegionName:         projectRegion(project.Name, regionCode),
 		Interface:          "public",
 		IdentityAPIVersion: "3",
 	}
-	return openstackCloudConfig
 }
 
-func createOpenstackCloudsYAML(clouds clientconfig.Clouds, openStackConfigFileLocation string) {
-	contentAsBytes, err := yaml.Marshal(clouds)
-	if err != nil {
-		common.ThrowError(fmt.Errorf("fatal: error encoding json.\ntrace: %w", err))
+func createOpenstackCloudConfig(project config.Project, domain config.NameAndIDResource, regionCode string,
+) clientconfig.Cloud {
+	return openstackCloudConfig(project, regionCode, clientconfig.AuthInfo{
+		Token:             project.ScopedToken.Secret,
+		ProjectDomainName: domain.Name,
+		ProjectDomainID:   domain.ID,
+	}, AuthTypeToken)
+}
+
+func createOpenstackPasswordCloudConfig(project config.Project, domain config.NameAndIDResource,
+	regionCode string, username string, password string,
+) clientconfig.Cloud {
+	return openstackCloudConfig(project, regionCode, clientconfig.AuthInfo{
+		Username:       username,
+		Password:       password,
+		UserDomainName: domain.Name,
+		UserDomainID:   domain.ID,
+	}, AuthTypePassword)
+}
+
+func createOpenstackAccessKeyCloudConfig(project config.Project, domain config.NameAndIDResource, regionCode string,
+	keyPair config.AccessKeyPair,
+) otcCloud {
+	return otcCloud{
+		Cloud: openstackCloudConfig(project, regionCode, clientconfig.AuthInfo{
+			DomainName: domain.Name,
+			DomainID:   domain.ID,
+		}, AuthTypeAkSk),
+		AccessKey: keyPair.AccessKey,
+		SecretKey: keyPair.SecretKey,
 	}
+}
 
-	dir, err := os.UserHomeDir()
-	if err != nil {
-		common.ThrowError(fmt.Errorf("couldn't get user home dir: %w", err))
+// endpointOverrides points the OpenStack SDK at the OTC services of the
+// region. The project scoped APIs need the project ID in their path.
+func endpointOverrides(region string, projectID string) map[string]string {
+	overrides := map[string]string{
+		"network_endpoint_override": "https://" + endpoints.ServiceHost("vpc", region) + "/v2.0",
+		"image_endpoint_override":   "https://" + endpoints.ServiceHost("ims", region),
+		"dns_endpoint_override":     "https://" + endpoints.ServiceHost("dns", region),
+	}
+	if projectID != "" {
+		overrides["compute_endpoint_override"] = "https://" + endpoints.ServiceHost("ecs", region) +
+			"/v2.1/" + projectID
+		overrides["block_storage_endpoint_override"] = "https://" + endpoints.ServiceHost("evs", region) +
+			"/v3/" + projectID
 	}
+	return overrides
+}
 
+// createOpenstackCloudsYAML merges the clouds into the clouds.yaml. The
+// secure.yaml gets their secrets with SecureFile, without it the clouds are
+// removed from an existing one so it doesn't override the new clouds.yaml
+// entries. The stale clouds otc-auth wrote before are removed from both files.
+func createOpenstackCloudsYAML(clouds map[string]otcCloud, stale []string, options Options) error {
+	openStackConfigFileLocation := options.Location
 	if openStackConfigFileLocation == "" {
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("couldn't get user home dir: %w", err)
+		}
 		openStackConfigFileLocation = path.Join(dir, ".config", "openstack", "clouds.yaml")
 	}
-	mkDirError := os.MkdirAll(filepath.Dir(openStackConfigFileLocation), os.ModePerm)
-	if mkDirError != nil {
-		common.ThrowError(err)
-	}
-	err = config.WriteConfigFile(string(contentAsBytes), openStackConfigFileLocation)
-	if err != nil {
-		common.ThrowError(err)
+	if err := os.MkdirAll(filepath.Dir(openStackConfigFileLocation), os.ModePerm); err != nil {
+		return fmt.Errorf("fatal: couldn't create the directory of %s\ntrace: %w", openStackConfigFileLocation, err)
 	}
 
-	glog.V(common.InfoLogLevel).Info("info: openstack clouds.yaml was updated")
+	entries := make(map[string]any, len(clouds))
+	secrets := make(map[string]any, len(clouds))
+	managed := slices.Clone(stale)
+	for name, cloud := range clouds {
+		managed = append(managed, name)
+		if options.SecureFile {
+			cloud, secrets[name] = splitSecrets(cloud)
+		}
+		entries[name] = cloud
+	}
+	securePath := filepath.Join(filepath.Dir(openStackConfigFileLocation), secureFileName)
+	if options.SecureFile {
+		if err := mergeCloudsFile(securePath, secrets, stale); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(securePath); err == nil {
+		if err = mergeCloudsFile(securePath, map[string]any{}, managed); err != nil {
+			return err
+		}
+	}
+	return mergeCloudsFile(openStackConfigFileLocation, entries, stale)
 }
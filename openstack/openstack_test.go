@@ -8,6 +8,9 @@ import (
 	"testing"
 
 	"otc-auth/config"
+
+	"github.com/gophercloud/utils/openstack/clientconfig"
+	"gopkg.in/yaml.v3"
 )
 
 func TestWriteOpenStackCloudsYaml(t *testing.T) {
@@ -51,7 +54,7 @@ func TestWriteOpenStackCloudsYaml(t *testing.T) {
 			_ = os.WriteFile(filepath.Join(tempdir, ".otc-auth-config"), content, 0o644)
 			defer os.Remove(filepath.Join(tempdir, ".otc-auth-config"))
 
-			WriteOpenStackCloudsYaml(tt.outputFile)
+			WriteOpenStackCloudsYaml(Options{Location: tt.outputFile})
 			defer os.Remove(tt.outputFile)
 
 			if _, err = os.Stat(tt.outputFile); (err == nil) != tt.expectFileExists {
@@ -63,12 +66,12 @@ func TestWriteOpenStackCloudsYaml(t *testing.T) {
 
 func TestCreateOpenstackCloudConfig(t *testing.T) {
 	tests := []struct {
-		name         string
-		project      config.Project
-		domain       string
-		region       string
-		expectedName string
-		expectedURL  string
+		name           string
+		project        config.Project
+		domain         config.NameAndIDResource
+		region         string
+		expectedRegion string
+		expectedURL    string
 	}{
 		{
 			name: "Valid project config",
@@ -80,10 +83,21 @@ func TestCreateOpenstackCloudConfig(t *testing.T) {
 					Secret: "token123",
 				},
 			},
-			domain:       "testdomain",
-			region:       "eu-de",
-			expectedName: "testdomain_projectA",
-			expectedURL:  "https://iam.eu-de.otc.t-systems.com:443/v3",
+			domain:         config.NameAndIDResource{Name: "testdomain"},
+			region:         "eu-de",
+			expectedRegion: "eu-de",
+			expectedURL:    "https://iam.eu-de.otc.t-systems.com:443/v3",
+		},
+		{
+			name: "Project of another region",
+			project: config.Project{
+				NameAndIDResource: config.NameAndIDResource{Name: "eu-nl_projectB", ID: "project-id"},
+				ScopedToken:       config.Token{Secret: "token456"},
+			},
+			domain:         config.NameAndIDResource{Name: "testdomain", ID: "domain-id"},
+			region:         "eu-de",
+			expectedRegion: "eu-nl",
+			expectedURL:    "https://iam.eu-de.otc.t-systems.com:443/v3",
 		},
 	}
 
@@ -91,17 +105,295 @@ func TestCreateOpenstackCloudConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			result := createOpenstackCloudConfig(tt.project, tt.domain, tt.region)
 
-			if result.Cloud != tt.expectedName {
-				t.Errorf("unexpected Cloud name: got %q, want %q", result.Cloud, tt.expectedName)
+			// both name a vendor profile, clientconfig fails if it doesn't exist
+			if result.Cloud != "" || result.Profile != "" {
+				t.Errorf("unexpected cloud/profile: got %q/%q, want none", result.Cloud, result.Profile)
+			}
+
+			if result.RegionName != tt.expectedRegion {
+				t.Errorf("unexpected region: got %q, want %q", result.RegionName, tt.expectedRegion)
 			}
 
 			if result.AuthInfo == nil || result.AuthInfo.Token != tt.project.ScopedToken.Secret {
 				t.Errorf("unexpected Auth token: got %v", result.AuthInfo)
 			}
 
+			if result.AuthInfo.ProjectName != tt.project.Name || result.AuthInfo.ProjectID != tt.project.ID ||
+				result.AuthInfo.ProjectDomainName != tt.domain.Name || result.AuthInfo.ProjectDomainID != tt.domain.ID {
+				t.Errorf("unexpected project scope: got %+v", result.AuthInfo)
+			}
+
 			if result.AuthInfo.AuthURL != tt.expectedURL {
 				t.Errorf("unexpected AuthURL: got %q, want %q", result.AuthInfo.AuthURL, tt.expectedURL)
 			}
 		})
 	}
 }
+
+func TestWriteOpenStackCloudsYaml_accessKey(t *testing.T) {
+	tempdir := t.TempDir()
+	config.SetCustomConfigFilePath(tempdir)
+	defer config.SetCustomConfigFilePath("")
+	content, _ := json.Marshal(config.OtcConfigContent{Clouds: config.Clouds{{
+		Domain:    config.NameAndIDResource{Name: "demo", ID: "domain-id"},
+		Region:    "eu-de",
+		Active:    true,
+		AccessKey: &config.AccessKeyPair{AccessKey: "AKEXAMPLE", SecretKey: "secret"},
+		Projects: config.Projects{
+			{NameAndIDResource: config.NameAndIDResource{Name: "eu-de_projectA", ID: "project-id"}},
+		},
+	}}})
+	if err := os.WriteFile(filepath.Join(tempdir, ".otc-auth-config"), content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(tempdir, "clouds.yaml")
+
+	WriteOpenStackCloudsYaml(Options{Location: outputFile})
+
+	written, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Clouds map[string]struct {
+			AuthType string `yaml:"auth_type"`
+			AK       string `yaml:"ak"`
+			SK       string `yaml:"sk"`
+			Auth     struct {
+				AuthURL     string `yaml:"auth_url"`
+				ProjectName string `yaml:"project_name"`
+				Token       string `yaml:"token"`
+			} `yaml:"auth"`
+		} `yaml:"clouds"`
+	}
+	if err = yaml.Unmarshal(written, &got); err != nil {
+		t.Fatalf("clouds.yaml is not valid yaml: %v", err)
+	}
+	cloud, ok := got.Clouds["demo_eu-de_projectA"]
+	if !ok {
+		t.Fatalf("no entry for the project in:\n%s", written)
+	}
+	if cloud.AuthType != "aksk" || cloud.AK != "AKEXAMPLE" || cloud.SK != "secret" {
+		t.Errorf("auth_type/ak/sk = %q/%q/%q, want aksk/AKEXAMPLE/secret", cloud.AuthType, cloud.AK, cloud.SK)
+	}
+	if cloud.Auth.ProjectName != "eu-de_projectA" || cloud.Auth.Token != "" {
+		t.Errorf("auth = %+v, want the project name and no token", cloud.Auth)
+	}
+	activeCloud, err := config.GetActiveCloudConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activeCloud.OpenstackClouds) != 1 || activeCloud.OpenstackClouds[0] != "demo_eu-de_projectA" {
+		t.Errorf("recorded clouds = %v, want demo_eu-de_projectA", activeCloud.OpenstackClouds)
+	}
+}
+
+func TestCreateOpenstackClouds(t *testing.T) {
+	t.Parallel()
+	projects := config.Projects{{
+		NameAndIDResource: config.NameAndIDResource{Name: "eu-nl_projectA", ID: "project-id"},
+		ScopedToken:       config.Token{Secret: "token123"},
+	}}
+	tokenLogin := &config.Cloud{
+		Domain:   config.NameAndIDResource{Name: "demo", ID: "domain-id"},
+		Region:   "eu-de",
+		Username: "user",
+		Projects: projects,
+	}
+	keyLogin := &config.Cloud{
+		Domain:    tokenLogin.Domain,
+		Region:    "eu-de",
+		AccessKey: &config.AccessKeyPair{AccessKey: "AKLOGIN", SecretKey: "login-secret"},
+		Projects:  projects,
+	}
+	tests := []struct {
+		name         string
+		cloudConfig  *config.Cloud
+		options      Options
+		wantAuthType string
+		wantErr      bool
+	}{
+		{name: "token by default", cloudConfig: tokenLogin, wantAuthType: AuthTypeToken},
+		{name: "aksk by default", cloudConfig: keyLogin, wantAuthType: AuthTypeAkSk},
+		{
+			name: "password", cloudConfig: tokenLogin, wantAuthType: AuthTypePassword,
+			options: Options{AuthType: AuthTypePassword, Password: "pw"},
+		},
+		{
+			name: "passed AK/SK", cloudConfig: tokenLogin, wantAuthType: AuthTypeAkSk,
+			options: Options{AuthType: AuthTypeAkSk, AccessKey: &config.AccessKeyPair{AccessKey: "AK", SecretKey: "SK"}},
+		},
+		{name: "token of an AK/SK login", cloudConfig: keyLogin, options: Options{AuthType: AuthTypeToken}, wantErr: true},
+		{name: "no password", cloudConfig: tokenLogin, options: Options{AuthType: AuthTypePassword}, wantErr: true},
+		{
+			name: "password of a key login", cloudConfig: keyLogin, wantErr: true,
+			options: Options{AuthType: AuthTypePassword, Password: "pw"},
+		},
+		{name: "no AK/SK", cloudConfig: tokenLogin, options: Options{AuthType: AuthTypeAkSk}, wantErr: true},
+		{name: "unknown", cloudConfig: tokenLogin, options: Options{AuthType: "v3oidc"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clouds, err := createOpenstackClouds(tt.cloudConfig, tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("createOpenstackClouds() succeeded: %+v", clouds)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			cloud, ok := clouds["demo_eu-nl_projectA"]
+			if !ok {
+				t.Fatalf("no entry for the project in %+v", clouds)
+			}
+			if string(cloud.AuthType) != tt.wantAuthType || cloud.RegionName != "eu-nl" || cloud.Interface != "public" {
+				t.Errorf("auth_type/region/interface = %s/%s/%s, want %s/eu-nl/public",
+					cloud.AuthType, cloud.RegionName, cloud.Interface, tt.wantAuthType)
+			}
+			switch tt.wantAuthType {
+			case AuthTypeToken:
+				if cloud.AuthInfo.Token != "token123" || cloud.AuthInfo.ProjectDomainID != "domain-id" {
+					t.Errorf("auth = %+v, want the token and the project domain", cloud.AuthInfo)
+				}
+			case AuthTypePassword:
+				if cloud.AuthInfo.Username != "user" || cloud.AuthInfo.Password != "pw" ||
+					cloud.AuthInfo.UserDomainName != "demo" || cloud.AuthInfo.Token != "" {
+					t.Errorf("auth = %+v, want the user, its password and domain", cloud.AuthInfo)
+				}
+			case AuthTypeAkSk:
+				if cloud.AccessKey == "" || cloud.SecretKey == "" || cloud.AuthInfo.DomainID != "domain-id" {
+					t.Errorf("cloud = %+v %+v, want the AK/SK and the domain", cloud, cloud.AuthInfo)
+				}
+			}
+			if cloud.EndpointOverrides != nil {
+				t.Errorf("endpoint overrides = %v, want none", cloud.EndpointOverrides)
+			}
+		})
+	}
+}
+
+func TestCreateOpenstackClouds_endpointOverrides(t *testing.T) {
+	t.Parallel()
+	clouds, err := createOpenstackClouds(&config.Cloud{
+		Domain: config.NameAndIDResource{Name: "demo"},
+		Region: "eu-ch2",
+		Projects: config.Projects{{
+			NameAndIDResource: config.NameAndIDResource{Name: "projectA", ID: "project-id"},
+			ScopedToken:       config.Token{Secret: "token123"},
+		}},
+	}, Options{EndpointOverrides: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := yaml.Marshal(clouds["demo_projectA"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err = yaml.Unmarshal(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"region_name":                     "eu-ch2",
+		"compute_endpoint_override":       "https://ecs.eu-ch2.sc.otc.t-systems.com/v2.1/project-id",
+		"block_storage_endpoint_override": "https://evs.eu-ch2.sc.otc.t-systems.com/v3/project-id",
+		"network_endpoint_override":       "https://vpc.eu-ch2.sc.otc.t-systems.com/v2.0",
+		"image_endpoint_override":         "https://ims.eu-ch2.sc.otc.t-systems.com",
+		"dns_endpoint_override":           "https://dns.eu-ch2.sc.otc.t-systems.com",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %s in\n%s", key, got[key], value, encoded)
+		}
+	}
+}
+
+func TestProjectRegion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		projectName string
+		want        string
+	}{
+		{projectName: "eu-nl_projectA", want: "eu-nl"},
+		{projectName: "eu-ch2_projectA", want: "eu-ch2"},
+		{projectName: "eu-de", want: "eu-de"},
+		{projectName: "projectA", want: "eu-de"},
+		{projectName: "my-team_projectA", want: "eu-de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.projectName, func(t *testing.T) {
+			t.Parallel()
+			if got := projectRegion(tt.projectName, "eu-de"); got != tt.want {
+				t.Errorf("projectRegion(%s) = %s, want %s", tt.projectName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOpenstackCloudsYAML_secureFile(t *testing.T) {
+	dir := t.TempDir()
+	location := filepath.Join(dir, "clouds.yaml")
+	securePath := filepath.Join(dir, secureFileName)
+	existing := "clouds:\n  demo_admin:\n    auth:\n      password: admin-secret\n" +
+		"  demo_gone:\n    auth:\n      token: gone\n"
+	if err := os.WriteFile(securePath, []byte(existing), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clouds := map[string]otcCloud{
+		"demo_projectA": {Cloud: clientconfig.Cloud{
+			AuthType: AuthTypeToken,
+			AuthInfo: &clientconfig.AuthInfo{ProjectName: "projectA", Token: "token123"},
+		}},
+	}
+	type entry struct {
+		Auth struct {
+			Token    string `yaml:"token"`
+			Password string `yaml:"password"`
+		} `yaml:"auth"`
+	}
+	readClouds := func(path string) map[string]entry {
+		t.Helper()
+		var got struct {
+			Clouds map[string]entry `yaml:"clouds"`
+		}
+		written, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = yaml.Unmarshal(written, &got); err != nil {
+			t.Fatalf("%s is not valid yaml: %v", path, err)
+		}
+		return got.Clouds
+	}
+
+	if err := createOpenstackCloudsYAML(clouds, []string{"demo_gone"}, Options{Location: location, SecureFile: true}); err != nil {
+		t.Fatal(err)
+	}
+	secure := readClouds(securePath)
+	if secure["demo_projectA"].Auth.Token != "token123" || secure["demo_admin"].Auth.Password != "admin-secret" {
+		t.Errorf("secure.yaml = %+v, want the token of projectA and the hand-written entry", secure)
+	}
+	if _, ok := secure["demo_gone"]; ok {
+		t.Error("secure.yaml still holds the entry of a project which is gone")
+	}
+	if token := readClouds(location)["demo_projectA"].Auth.Token; token != "" {
+		t.Errorf("clouds.yaml holds the token %q with --secure-file", token)
+	}
+
+	if err := createOpenstackCloudsYAML(clouds, nil, Options{Location: location}); err != nil {
+		t.Fatal(err)
+	}
+	secure = readClouds(securePath)
+	if _, ok := secure["demo_projectA"]; ok {
+		t.Error("secure.yaml still holds the entry of projectA, it would override the clouds.yaml")
+	}
+	if secure["demo_admin"].Auth.Password != "admin-secret" {
+		t.Errorf("secure.yaml = %+v, want the hand-written entry kept", secure)
+	}
+	if token := readClouds(location)["demo_projectA"].Auth.Token; token != "token123" {
+		t.Errorf("clouds.yaml token = %q, want token123 without --secure-file", token)
+	}
+}
@@ -0,0 +1,147 @@
+package saml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"otc-auth/common"
+	"otc-auth/common/headervalues"
+
+	"github.com/go-http-utils/headers"
+)
+
+type IdpType string
+
+const (
+	IdpTypeGeneric    IdpType = "generic"
+	IdpTypeKeycloak   IdpType = "keycloak"
+	IdpTypeADFS       IdpType = "adfs"
+	IdpTypeShibboleth IdpType = "shibboleth"
+)
+
+// IdpAdapter hides the differences between IdPs in how they want to receive
+// credentials (and a second factor) and in what they answer with.
+type IdpAdapter interface {
+	Authenticate(ctx context.Context, session IdpSession) (*IdpResult, error)
+}
+
+// IdpSession is everything an adapter gets to work with.
+type IdpSession struct {
+	Client   common.HTTPClient
+	AuthInfo common.AuthInfo
+	// ECPRequest is the PAOS request issued by the OTC. Adapters not speaking
+	// ECP may ignore it.
+	ECPRequest []byte
+	// PromptSecondFactor asks the user for a one-time code. It is nil when no
+	// interactive prompt is available.
+	PromptSecondFactor func(prompt string) (string, error)
+}
+
+// IdpResult carries exactly one of the two ways an assertion can come back.
+type IdpResult struct {
+	// Envelope is the ECP SOAP envelope to be relayed to the assertion
+	// consumer service.
+	Envelope []byte
+	// SamlResponse is a base64 encoded SAMLResponse obtained through the
+	// HTTP-POST binding.
+	SamlResponse string
+}
+
+func NewIdpAdapter(idpType IdpType) (IdpAdapter, error) {
+	switch idpType {
+	case "", IdpTypeGeneric, IdpTypeShibboleth:
+		return &ecpBasicAuthAdapter{}, nil
+	case IdpTypeKeycloak:
+		return &keycloakAdapter{}, nil
+	case IdpTypeADFS:
+		return &adfsAdapter{}, nil
+	default:
+		return nil, fmt.Errorf(
+			"fatal: unsupported idp type %q.\n\nAllowed values are %q, %q, %q or %q",
+			idpType, IdpTypeGeneric, IdpTypeKeycloak, IdpTypeADFS, IdpTypeShibboleth)
+	}
+}
+
+// ecpBasicAuthAdapter is the plain ECP profile: the PAOS request is posted to
+// the IdP with HTTP basic auth. Shibboleth and most other IdPs work this way.
+type ecpBasicAuthAdapter struct{}
+
+func (e *ecpBasicAuthAdapter) Authenticate(ctx context.Context, session IdpSession) (*IdpResult, error) {
+	envelope, err := postECPRequest(ctx, session, session.AuthInfo.Password)
+	if err != nil {
+		return nil, err
+	}
+	return &IdpResult{Envelope: envelope}, nil
+}
+
+// keycloakAdapter talks to Keycloak's ECP endpoint (<realm>/protocol/saml).
+// A second factor is sent the way the "Basic Auth Password+OTP" authenticator
+// of the realm's http challenge flow expects it: appended to the password.
+type keycloakAdapter struct{}
+
+func (k *keycloakAdapter) Authenticate(ctx context.Context, session IdpSession) (*IdpResult, error) {
+	otp := session.AuthInfo.Otp
+	envelope, err := postECPRequest(ctx, session, session.AuthInfo.Password+otp)
+
+	var idpErr *IdpError
+	if otp == "" && session.PromptSecondFactor != nil &&
+		errors.As(err, &idpErr) && idpErr.StatusCode == http.StatusUnauthorized {
+		otp, err = session.PromptSecondFactor("Keycloak rejected the password alone, enter your one-time code: ")
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read one-time code: %w", err)
+		}
+		envelope, err = postECPRequest(ctx, session, session.AuthInfo.Password+otp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &IdpResult{Envelope: envelope}, nil
+}
+
+func postECPRequest(ctx context.Context, session IdpSession, password string) ([]byte, error) {
+	request, err := common.NewRequest(ctx, http.MethodPost, session.AuthInfo.IdpURL,
+		bytes.NewReader(session.ECPRequest))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add(headers.ContentType, headervalues.TextXML)
+	request.SetBasicAuth(session.AuthInfo.Username, password)
+
+	response, err := session.Client.MakeRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fatal: error reading response body\n%w", err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, newIdpError(response.StatusCode, bodyBytes)
+	}
+	if fault := parseSoapFault(bodyBytes); fault != nil {
+		return nil, fault
+	}
+	return bodyBytes, nil
+}
+
+// describeUnexpectedIdpResponse is used when the IdP answered with 2xx but not
+// with an ECP envelope, which usually means we got a login page.
+func describeUnexpectedIdpResponse(body []byte, cause error) error {
+	if title := htmlTitle(body); title != "" {
+		return fmt.Errorf("fatal: the IdP returned an HTML page (%q) instead of an ECP response. "+
+			"Check --idp-url and --idp-type, or try --browser", title)
+	}
+	snippet := strings.TrimSpace(string(body))
+	const maxSnippet = 200
+	if len(snippet) > maxSnippet {
+		snippet = snippet[:maxSnippet] + "..."
+	}
+	return fmt.Errorf("fatal: the IdP's response is not a valid ECP envelope: %w\nresponse: %s", cause, snippet)
+}
@@ -0,0 +1,299 @@
+//nolint:testpackage // whitebox testing
+package saml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otc-auth/common"
+)
+
+const testEnvelope = `<S:Envelope xmlns:S="http://schemas.xmlsoap.org/soap/envelope/"><S:Header>` +
+	`<ecp:Response xmlns:ecp="urn:oasis:names:tc:SAML:2.0:profiles:SSO:ecp" ` +
+	`AssertionConsumerServiceURL="https://acs.example.com"/></S:Header><S:Body></S:Body></S:Envelope>`
+
+func TestNewIdpAdapter(t *testing.T) {
+	tests := []struct {
+		idpType IdpType
+		want    IdpAdapter
+		wantErr bool
+	}{
+		{idpType: "", want: &ecpBasicAuthAdapter{}},
+		{idpType: IdpTypeGeneric, want: &ecpBasicAuthAdapter{}},
+		{idpType: IdpTypeShibboleth, want: &ecpBasicAuthAdapter{}},
+		{idpType: IdpTypeKeycloak, want: &keycloakAdapter{}},
+		{idpType: IdpTypeADFS, want: &adfsAdapter{}},
+		{idpType: "okta", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.idpType), func(t *testing.T) {
+			got, err := NewIdpAdapter(tt.idpType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewIdpAdapter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprintf("%T", got) != fmt.Sprintf("%T", tt.want) {
+				t.Errorf("NewIdpAdapter() = %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
+
+// passwordCheckingClient answers like an ECP endpoint accepting a single password.
+type passwordCheckingClient struct {
+	validPassword string
+	passwords     []string
+}
+
+func (p *passwordCheckingClient) MakeRequest(req *http.Request) (*http.Response, error) {
+	_, password, _ := req.BasicAuth()
+	p.passwords = append(p.passwords, password)
+	if password != p.validPassword {
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(testEnvelope))}, nil
+}
+
+func Test_keycloakAdapter_Authenticate(t *testing.T) {
+	tests := []struct {
+		name          string
+		otp           string
+		prompt        func(string) (string, error)
+		wantPasswords []string
+		wantErr       string
+	}{
+		{
+			name:          "otp from flag is appended to the password",
+			otp:           "123456",
+			wantPasswords: []string{"secret123456"},
+		},
+		{
+			name:          "prompts for the otp after a rejected password",
+			prompt:        func(string) (string, error) { return "123456", nil },
+			wantPasswords: []string{"secret", "secret123456"},
+		},
+		{
+			name:          "no prompt available",
+			wantPasswords: []string{"secret"},
+			wantErr:       "Please check your username and password",
+		},
+		{
+			name:          "prompt fails",
+			prompt:        func(string) (string, error) { return "", errors.New("no tty") },
+			wantPasswords: []string{"secret"},
+			wantErr:       "no tty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &passwordCheckingClient{validPassword: "secret123456"}
+			session := IdpSession{
+				Client: client,
+				AuthInfo: common.AuthInfo{
+					IdpURL: "https://kc.example.com/realms/otc/protocol/saml", Username: "jane",
+					Password: "secret", Otp: tt.otp,
+				},
+				ECPRequest:         []byte("<ecp/>"),
+				PromptSecondFactor: tt.prompt,
+			}
+
+			got, err := (&keycloakAdapter{}).Authenticate(context.Background(), session)
+			if strings.Join(client.passwords, ",") != strings.Join(tt.wantPasswords, ",") {
+				t.Errorf("passwords sent = %v, want %v", client.passwords, tt.wantPasswords)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() unexpected error = %v", err)
+			}
+			if string(got.Envelope) != testEnvelope {
+				t.Errorf("Authenticate() envelope = %s", got.Envelope)
+			}
+		})
+	}
+}
+
+func Test_ecpBasicAuthAdapter_errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode string
+		wantMsg  string
+	}{
+		{
+			name:   "SOAP 1.1 fault",
+			status: http.StatusInternalServerError,
+			body: `<S:Envelope xmlns:S="http://schemas.xmlsoap.org/soap/envelope/"><S:Body><S:Fault>` +
+				`<faultcode>S:Client</faultcode><faultstring>Authentication failed</faultstring>` +
+				`</S:Fault></S:Body></S:Envelope>`,
+			wantCode: "S:Client",
+			wantMsg:  "Authentication failed",
+		},
+		{
+			name:   "SOAP 1.2 fault with status 200",
+			status: http.StatusOK,
+			body: `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>` +
+				`<env:Code><env:Value>env:Sender</env:Value></env:Code>` +
+				`<env:Reason><env:Text xml:lang="en">Unknown principal</env:Text></env:Reason>` +
+				`</env:Fault></env:Body></env:Envelope>`,
+			wantCode: "env:Sender",
+			wantMsg:  "Unknown principal",
+		},
+		{
+			name:   "SAML status without success",
+			status: http.StatusOK,
+			body: `<S:Envelope xmlns:S="http://schemas.xmlsoap.org/soap/envelope/"><S:Body>` +
+				`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"><samlp:Status>` +
+				`<samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Responder">` +
+				`<samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:AuthnFailed"/></samlp:StatusCode>` +
+				`<samlp:StatusMessage>Account locked</samlp:StatusMessage>` +
+				`</samlp:Status></samlp:Response></S:Body></S:Envelope>`,
+			wantCode: "urn:oasis:names:tc:SAML:2.0:status:AuthnFailed",
+			wantMsg:  "Account locked",
+		},
+		{
+			name:    "HTML error page",
+			status:  http.StatusForbidden,
+			body:    `<html><head><title>Sign In</title></head><body><span id="errorText">Incorrect user ID or password.</span></body></html>`,
+			wantMsg: "Incorrect user ID or password.",
+		},
+		{
+			name:    "HTML page without error element",
+			status:  http.StatusBadGateway,
+			body:    `<html><head><title>Bad Gateway</title></head><body></body></html>`,
+			wantMsg: "Bad Gateway",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := IdpSession{
+				Client: &mockHTTPClient{
+					T:                t,
+					ResponseToReturn: &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))},
+				},
+				AuthInfo: common.AuthInfo{IdpURL: "https://idp.example.com/ecp"},
+			}
+			_, err := (&ecpBasicAuthAdapter{}).Authenticate(context.Background(), session)
+
+			var idpErr *IdpError
+			if !errors.As(err, &idpErr) {
+				t.Fatalf("Authenticate() error = %v, want an IdpError", err)
+			}
+			if idpErr.Code != tt.wantCode || idpErr.Message != tt.wantMsg {
+				t.Errorf("IdpError = {Code: %q, Message: %q}, want {Code: %q, Message: %q}",
+					idpErr.Code, idpErr.Message, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func Test_describeUnexpectedIdpResponse(t *testing.T) {
+	err := describeUnexpectedIdpResponse([]byte(`<html><head><title>Keycloak Login</title></head></html>`),
+		errors.New("EOF"))
+	if !strings.Contains(err.Error(), `"Keycloak Login"`) || !strings.Contains(err.Error(), "--idp-type") {
+		t.Errorf("describeUnexpectedIdpResponse() = %v", err)
+	}
+	err = describeUnexpectedIdpResponse([]byte("not xml"), errors.New("EOF"))
+	if !strings.Contains(err.Error(), "not a valid ECP envelope") {
+		t.Errorf("describeUnexpectedIdpResponse() = %v", err)
+	}
+}
+
+func newTestADFS(t *testing.T, withMFA bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/adfs/ls/IdpInitiatedSignOn.aspx", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.SetCookie(w, &http.Cookie{Name: "MSISContext", Value: "ctx"})
+			fmt.Fprint(w, `<html><body><form method="post" action="?client-request-id=1">`+
+				`<input name="UserName"/><input name="Password" type="password"/>`+
+				`<input type="hidden" name="AuthMethod" value="FormsAuthentication"/>`+
+				`</form></body></html>`)
+			return
+		}
+		if _, err := r.Cookie("MSISContext"); err != nil {
+			t.Errorf("login form posted without the AD FS cookie")
+		}
+		_ = r.ParseForm()
+		if r.PostForm.Get("UserName") != "jane@example.com" || r.PostForm.Get("Password") != "secret" {
+			fmt.Fprint(w, `<html><body><form action="?x"><input name="UserName"/><input name="Password"/></form>`+
+				`<span id="errorText">Incorrect user ID or password.</span></body></html>`)
+			return
+		}
+		if withMFA {
+			fmt.Fprint(w, `<html><body><form method="post" action="/adfs/ls/mfa">`+
+				`<input name="VerificationCode"/></form></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><form method="post" action="https://auth.otc.t-systems.com/">`+
+			`<input type="hidden" name="SAMLResponse" value="c2FtbA=="/></form></body></html>`)
+	})
+	mux.HandleFunc("/adfs/ls/mfa", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("VerificationCode") != "654321" {
+			fmt.Fprint(w, `<html><body><form action="/adfs/ls/mfa"><input name="VerificationCode"/></form>`+
+				`<span id="errorText">The code is invalid.</span></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><form method="post" action="https://auth.otc.t-systems.com/">`+
+			`<input type="hidden" name="SAMLResponse" value="bWZh"/></form></body></html>`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func Test_adfsAdapter_Authenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		withMFA  bool
+		password string
+		otp      string
+		want     string
+		wantErr  string
+	}{
+		{name: "forms login", password: "secret", want: "c2FtbA=="},
+		{name: "wrong password", password: "wrong", wantErr: "Incorrect user ID or password."},
+		{name: "verification code", withMFA: true, password: "secret", otp: "654321", want: "bWZh"},
+		{name: "wrong verification code", withMFA: true, password: "secret", otp: "000000", wantErr: "The code is invalid."},
+		{name: "verification code missing", withMFA: true, password: "secret", wantErr: "--totp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestADFS(t, tt.withMFA)
+			defer server.Close()
+
+			session := IdpSession{
+				Client: common.NewHTTPClient(false),
+				AuthInfo: common.AuthInfo{
+					IdpURL:   server.URL + "/adfs/ls/IdpInitiatedSignOn.aspx?loginToRp=https://auth.otc.t-systems.com/",
+					Username: "jane@example.com", Password: tt.password, Otp: tt.otp,
+				},
+			}
+			got, err := (&adfsAdapter{}).Authenticate(context.Background(), session)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() unexpected error = %v", err)
+			}
+			if got.SamlResponse != tt.want {
+				t.Errorf("Authenticate() SamlResponse = %q, want %q", got.SamlResponse, tt.want)
+			}
+		})
+	}
+}
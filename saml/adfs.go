@@ -0,0 +1,191 @@
+package saml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"otc-auth/common"
+	"otc-auth/common/headervalues"
+
+	"github.com/go-http-utils/headers"
+	"golang.org/x/net/html"
+)
+
+const (
+	adfsUserNameField     = "UserName"
+	adfsPasswordField     = "Password"
+	adfsAuthMethodField   = "AuthMethod"
+	adfsFormsAuthMethod   = "FormsAuthentication"
+	adfsVerificationField = "VerificationCode"
+	// login, second factor and maybe one interstitial page
+	adfsMaxFormSteps = 4
+)
+
+// adfsAdapter signs in through the forms login of AD FS, which doesn't speak
+// ECP. --idp-url has to be the IdP-initiated sign-on page for the OTC relying
+// party, e.g. https://adfs.example.com/adfs/ls/IdpInitiatedSignOn.aspx?loginToRp=https://auth.otc.t-systems.com/
+// The ECP request of the OTC is not used, the SAMLResponse that AD FS renders
+// into its auto-submit form is exchanged directly.
+type adfsAdapter struct{}
+
+type htmlForm struct {
+	action string
+	fields url.Values
+}
+
+func (a *adfsAdapter) Authenticate(ctx context.Context, session IdpSession) (*IdpResult, error) {
+	// AD FS keeps the state of the login in cookies
+	client := common.WithCookieJar(session.Client)
+	page, pageURL, err := fetchPage(ctx, client, http.MethodGet, session.AuthInfo.IdpURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// a form we already filled coming back means AD FS rejected our input
+	filled := map[string]bool{}
+	for range adfsMaxFormSteps {
+		if samlResponse := findInputValue(page, samlResponseField); samlResponse != "" {
+			return &IdpResult{SamlResponse: samlResponse}, nil
+		}
+		form := findForm(page)
+		if form == nil {
+			break
+		}
+		step, err := fillAdfsForm(form, session)
+		if err != nil {
+			return nil, err
+		}
+		if step != "" && filled[step] {
+			break
+		}
+		filled[step] = true
+		page, pageURL, err = submitForm(ctx, client, pageURL, form)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idpErr := &IdpError{Message: pageErrorText(page)}
+	if idpErr.Message == "" {
+		idpErr.Message = "AD FS didn't return a SAMLResponse. Check --idp-url, it has to point to the " +
+			"IdP-initiated sign-on page of the OTC relying party"
+	}
+	return nil, idpErr
+}
+
+// fillAdfsForm returns the field that identifies the step the form belongs to,
+// or "" for interstitial pages that are just submitted as they are.
+func fillAdfsForm(form *htmlForm, session IdpSession) (string, error) {
+	switch {
+	case form.fields.Has(adfsPasswordField):
+		form.fields.Set(adfsUserNameField, session.AuthInfo.Username)
+		form.fields.Set(adfsPasswordField, session.AuthInfo.Password)
+		if form.fields.Get(adfsAuthMethodField) == "" {
+			form.fields.Set(adfsAuthMethodField, adfsFormsAuthMethod)
+		}
+		return adfsPasswordField, nil
+	case form.fields.Has(adfsVerificationField):
+		otp := session.AuthInfo.Otp
+		if otp == "" {
+			if session.PromptSecondFactor == nil {
+				return "", errors.New("fatal: AD FS asks for a second factor, please provide it with --totp")
+			}
+			var err error
+			otp, err = session.PromptSecondFactor("AD FS verification code: ")
+			if err != nil {
+				return "", fmt.Errorf("couldn't read one-time code: %w", err)
+			}
+		}
+		form.fields.Set(adfsVerificationField, otp)
+		return adfsVerificationField, nil
+	}
+	return "", nil
+}
+
+func fetchPage(ctx context.Context, client common.HTTPClient, method string, pageURL string,
+	form url.Values,
+) (*html.Node, string, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	request, err := common.NewRequest(ctx, method, pageURL, body)
+	if err != nil {
+		return nil, "", err
+	}
+	if form != nil {
+		request.Header.Add(headers.ContentType, headervalues.FormURLEncoded)
+	}
+
+	response, err := client.MakeRequest(request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("fatal: error reading response body\n%w", err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, "", newIdpError(response.StatusCode, bodyBytes)
+	}
+	page, err := html.Parse(bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, "", fmt.Errorf("fatal: couldn't parse AD FS page\ntrace: %w", err)
+	}
+
+	// redirects were followed, relative form actions resolve against the final URL
+	finalURL := pageURL
+	if response.Request != nil && response.Request.URL != nil {
+		finalURL = response.Request.URL.String()
+	}
+	return page, finalURL, nil
+}
+
+func submitForm(ctx context.Context, client common.HTTPClient, pageURL string,
+	form *htmlForm,
+) (*html.Node, string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("fatal: invalid page url %s\ntrace: %w", pageURL, err)
+	}
+	action, err := base.Parse(form.action)
+	if err != nil {
+		return nil, "", fmt.Errorf("fatal: invalid form action %s\ntrace: %w", form.action, err)
+	}
+	return fetchPage(ctx, client, http.MethodPost, action.String(), form.fields)
+}
+
+func findForm(page *html.Node) *htmlForm {
+	formNode := findNode(page, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "form"
+	})
+	if formNode == nil {
+		return nil
+	}
+	form := &htmlForm{action: attr(formNode, "action"), fields: url.Values{}}
+	findNode(formNode, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "input" && attr(n, "name") != "" {
+			form.fields.Set(attr(n, "name"), attr(n, "value"))
+		}
+		return false
+	})
+	return form
+}
+
+func findInputValue(page *html.Node, name string) string {
+	input := findNode(page, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "input" && attr(n, "name") == name
+	})
+	if input == nil {
+		return ""
+	}
+	return attr(input, "value")
+}
@@ -0,0 +1,326 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"otc-auth/common"
+	"otc-auth/common/endpoints"
+	"otc-auth/common/headervalues"
+	header "otc-auth/common/xheaders"
+
+	"github.com/go-http-utils/headers"
+	"github.com/golang/glog"
+	"github.com/pkg/browser"
+)
+
+const (
+	// DefaultBrowserListenAddress is where the assertion consumer service
+	// listens unless told otherwise.
+	DefaultBrowserListenAddress = "localhost:8089"
+	// AssertionConsumerPath is where the IdP has to POST its SAMLResponse to.
+	// http://localhost:8089/saml/acs (or the host and port of the listen
+	// address) must be registered as an allowed assertion consumer service for
+	// the OTC relying party on the IdP.
+	AssertionConsumerPath = "/saml/acs"
+
+	samlResponseField = "SAMLResponse"
+	samlRequestField  = "SAMLRequest"
+	relayStateField   = "RelayState"
+	relayStateBytes   = 16
+	requestIDBytes    = 16
+
+	samlVersion         = "2.0"
+	httpPostBindingName = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	rwTimeout       = 1 * time.Minute
+	idleTimeout     = 2 * time.Minute
+	shutdownTimeout = 5 * time.Second
+)
+
+type browserFlow struct {
+	openURL func(url string) error
+	listen  func(ctx context.Context, address string) (net.Listener, error)
+}
+
+func newBrowserFlow() *browserFlow {
+	return &browserFlow{
+		openURL: browser.OpenURL,
+		listen: func(ctx context.Context, address string) (net.Listener, error) {
+			listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", address)
+			if err != nil {
+				return nil, fmt.Errorf("can't listen on %s, something might already be using this port: %w",
+					address, err)
+			}
+			return listener, nil
+		},
+	}
+}
+
+// AuthenticateInBrowser runs the interactive SAML flow: the user signs in at
+// the IdP in their browser (including whatever MFA the IdP enforces), the IdP
+// POSTs the SAMLResponse to a local listener and the response is handed to the
+// OTC federation endpoint in exchange for an unscoped token.
+func (a *Authenticator) AuthenticateInBrowser(ctx context.Context,
+	authInfo common.AuthInfo,
+) (*common.TokenResponse, error) {
+	if authInfo.IdpURL == "" {
+		return nil, errors.New("the single sign-on URL of the IdP is needed to send it the login request")
+	}
+	listenAddress := authInfo.SamlListen
+	if listenAddress == "" {
+		listenAddress = DefaultBrowserListenAddress
+	}
+	samlResponse, err := a.browser.captureSamlResponse(ctx, listenAddress, authInfo)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't capture saml response: %w", err)
+	}
+
+	response, err := a.exchangeSamlResponse(ctx, authInfo, samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't exchange saml response with service provider: %w", err)
+	}
+	defer response.Body.Close()
+
+	tokenResponse, err := a.parser.Parse(response)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get cloud creds from response: %w", err)
+	}
+	if tokenResponse.Token.User.Name == "" {
+		tokenResponse.Token.User.Name = nameIDFromSamlResponse(samlResponse)
+	}
+
+	return tokenResponse, nil
+}
+
+// authnRequest is the SP-initiated login request, sent with the HTTP-Redirect
+// binding. It asks the IdP to POST its response to the local listener.
+type authnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      issuer
+}
+
+type issuer struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Value   string   `xml:",chardata"`
+}
+
+// browserLoginURL is the single sign-on URL of the IdP with the AuthnRequest
+// and the RelayState the response has to come back with.
+func browserLoginURL(authInfo common.AuthInfo, acsURL string, relayState string) (string, error) {
+	requestID, err := randomHex(requestIDBytes)
+	if err != nil {
+		return "", err
+	}
+	request, err := xml.Marshal(authnRequest{
+		ID:                          "_" + requestID,
+		Version:                     samlVersion,
+		IssueInstant:                time.Now().UTC().Format(time.RFC3339),
+		Destination:                 authInfo.IdpURL,
+		AssertionConsumerServiceURL: acsURL,
+		ProtocolBinding:             httpPostBindingName,
+		Issuer:                      issuer{Value: endpoints.SamlServiceProvider(authInfo.Region)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("couldn't encode the AuthnRequest: %w", err)
+	}
+	var deflated bytes.Buffer
+	writer, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err = writer.Write(request); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+
+	loginURL, err := url.Parse(authInfo.IdpURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid IdP URL %s: %w", authInfo.IdpURL, err)
+	}
+	query := loginURL.Query()
+	query.Set(samlRequestField, base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	query.Set(relayStateField, relayState)
+	loginURL.RawQuery = query.Encode()
+	return loginURL.String(), nil
+}
+
+// assertionConsumerURL keeps the host of the listen address, the IdP only
+// accepts the URL as registered, e.g. localhost rather than 127.0.0.1.
+func assertionConsumerURL(listenAddress string, listener net.Listener) string {
+	host, _, err := net.SplitHostPort(listenAddress)
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		return "http://" + listener.Addr().String() + AssertionConsumerPath
+	}
+	return "http://" + net.JoinHostPort(host, port) + AssertionConsumerPath
+}
+
+func randomHex(length int) (string, error) {
+	random := make([]byte, length)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("couldn't generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(random), nil
+}
+
+func (b *browserFlow) captureSamlResponse(ctx context.Context, listenAddress string,
+	authInfo common.AuthInfo,
+) (string, error) {
+	listener, err := b.listen(ctx, listenAddress)
+	if err != nil {
+		return "", err
+	}
+	acsURL := assertionConsumerURL(listenAddress, listener)
+	relayState, err := randomHex(relayStateBytes)
+	if err != nil {
+		_ = listener.Close()
+		return "", err
+	}
+	loginURL, err := browserLoginURL(authInfo, acsURL, relayState)
+	if err != nil {
+		_ = listener.Close()
+		return "", err
+	}
+
+	respChan := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, loginURL, http.StatusFound)
+	})
+	mux.HandleFunc(AssertionConsumerPath, func(w http.ResponseWriter, r *http.Request) {
+		handleSamlAssertion(w, r, relayState, respChan)
+	})
+	server := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  rwTimeout,
+		WriteTimeout: rwTimeout,
+		IdleTimeout:  idleTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		if serveErr := server.Serve(listener); !errors.Is(serveErr, http.ErrServerClosed) {
+			errChan <- serveErr
+		}
+	}()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	startURL := fmt.Sprintf("http://%s", listener.Addr().String())
+	glog.V(common.InfoLogLevel).Infof(
+		"info: opening %s in your browser, waiting for the IdP to post the SAML response to %s",
+		startURL, acsURL)
+	if err = b.openURL(startURL); err != nil {
+		return "", err
+	}
+
+	select {
+	case samlResponse := <-respChan:
+		return samlResponse, nil
+	case serveErr := <-errChan:
+		return "", serveErr
+	case <-ctx.Done():
+		return "", fmt.Errorf("no saml response received: %w", ctx.Err())
+	}
+}
+
+// handleSamlAssertion only accepts the response to the login otc-auth started,
+// anything else posting to the listener is turned away by the RelayState.
+func handleSamlAssertion(w http.ResponseWriter, r *http.Request, relayState string, respChan chan<- string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "the SAMLResponse has to be sent with the HTTP-POST binding", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "couldn't parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	samlResponse := r.PostForm.Get(samlResponseField)
+	if samlResponse == "" {
+		http.Error(w, "no SAMLResponse in request", http.StatusBadRequest)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.PostForm.Get(relayStateField)), []byte(relayState)) != 1 {
+		http.Error(w, "the RelayState doesn't match the login started by otc-auth", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := w.Write([]byte(common.SuccessPageHTML)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case respChan <- samlResponse:
+	default:
+		// a response was already captured, the browser probably re-submitted the form
+	}
+}
+
+func (a *Authenticator) exchangeSamlResponse(ctx context.Context, authInfo common.AuthInfo,
+	samlResponse string,
+) (*http.Response, error) {
+	form := url.Values{samlResponseField: []string{samlResponse}}
+	request, err := common.NewRequest(ctx, http.MethodPost, endpoints.FederationTokens(authInfo.Region),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add(headers.ContentType, headervalues.FormURLEncoded)
+	request.Header.Add(header.XIdpID, authInfo.IdpName)
+
+	return a.client.MakeRequest(request)
+}
+
+type samlResponseSubject struct {
+	Assertion struct {
+		Subject struct {
+			NameID string `xml:"NameID"`
+		} `xml:"Subject"`
+	} `xml:"Assertion"`
+}
+
+// nameIDFromSamlResponse is best effort: the user name is only used for
+// display and for naming kube config users.
+func nameIDFromSamlResponse(samlResponse string) string {
+	decoded, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return ""
+	}
+	var subject samlResponseSubject
+	if err = xml.Unmarshal(decoded, &subject); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(subject.Assertion.Subject.NameID)
+}
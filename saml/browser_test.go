@@ -0,0 +1,290 @@
+//nolint:testpackage // whitebox testing
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"otc-auth/common"
+	"otc-auth/common/endpoints"
+	"otc-auth/common/headervalues"
+	header "otc-auth/common/xheaders"
+
+	"github.com/go-http-utils/headers"
+)
+
+const testSamlAssertion = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ` +
+	`xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"><saml:Assertion><saml:Subject>` +
+	`<saml:NameID>jane.doe@example.com</saml:NameID></saml:Subject></saml:Assertion></samlp:Response>`
+
+func Test_handleSamlAssertion(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantValue  string
+	}{
+		{
+			name:       "POST binding delivers the response",
+			method:     http.MethodPost,
+			body:       url.Values{samlResponseField: []string{"c2FtbA=="}, relayStateField: []string{"state"}}.Encode(),
+			wantStatus: http.StatusOK,
+			wantValue:  "c2FtbA==",
+		},
+		{
+			name:       "missing SAMLResponse",
+			method:     http.MethodPost,
+			body:       url.Values{relayStateField: []string{"state"}}.Encode(),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "foreign RelayState",
+			method:     http.MethodPost,
+			body:       url.Values{samlResponseField: []string{"c2FtbA=="}, relayStateField: []string{"other"}}.Encode(),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing RelayState, e.g. IdP-initiated",
+			method:     http.MethodPost,
+			body:       url.Values{samlResponseField: []string{"c2FtbA=="}}.Encode(),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "redirect binding is rejected",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, AssertionConsumerPath, strings.NewReader(tt.body))
+			req.Header.Set(headers.ContentType, headervalues.FormURLEncoded)
+			rec := httptest.NewRecorder()
+			respChan := make(chan string, 1)
+
+			handleSamlAssertion(rec, req, "state", respChan)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			select {
+			case got := <-respChan:
+				if got != tt.wantValue {
+					t.Errorf("captured %q, want %q", got, tt.wantValue)
+				}
+			default:
+				if tt.wantValue != "" {
+					t.Errorf("nothing captured, want %q", tt.wantValue)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticator_exchangeSamlResponse(t *testing.T) {
+	authInfo := common.AuthInfo{IdpName: "my-idp", Region: "eu-de"}
+	wantBody := []byte(url.Values{samlResponseField: []string{"c2FtbA=="}}.Encode())
+	successResponse := &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(""))}
+
+	var gotIdpHeader string
+	client := &mockHTTPClient{
+		T:                t,
+		ResponseToReturn: successResponse,
+		ExpectedURL:      endpoints.FederationTokens(authInfo.Region),
+		ExpectedMethod:   http.MethodPost,
+		ExpectedHeader:   http.Header{headers.ContentType: []string{headervalues.FormURLEncoded}},
+		ExpectedBody:     wantBody,
+	}
+	a := &Authenticator{client: headerRecorder{next: client, key: header.XIdpID, value: &gotIdpHeader}}
+
+	got, err := a.exchangeSamlResponse(context.Background(), authInfo, "c2FtbA==")
+	if err != nil {
+		t.Fatalf("exchangeSamlResponse() error = %v", err)
+	}
+	if got != successResponse {
+		t.Errorf("exchangeSamlResponse() got = %v, want %v", got, successResponse)
+	}
+	if gotIdpHeader != authInfo.IdpName {
+		t.Errorf("%s header = %q, want %q", header.XIdpID, gotIdpHeader, authInfo.IdpName)
+	}
+	if want := "https://iam.eu-de.otc.t-systems.com:443/v3.0/OS-FEDERATION/tokens"; endpoints.FederationTokens("eu-de") != want {
+		t.Errorf("federation token URL = %q, want %q", endpoints.FederationTokens("eu-de"), want)
+	}
+}
+
+func TestAuthenticator_AuthenticateInBrowser(t *testing.T) {
+	encodedAssertion := base64.StdEncoding.EncodeToString([]byte(testSamlAssertion))
+	tokenResponse := &common.TokenResponse{}
+
+	flow := &browserFlow{
+		listen: func(ctx context.Context, _ string) (net.Listener, error) {
+			return (&net.ListenConfig{}).Listen(ctx, "tcp", "127.0.0.1:0")
+		},
+		// stands in for the browser and the IdP: follow the redirect to the IdP,
+		// which posts the SAMLResponse to the requested assertion consumer service
+		openURL: func(startURL string) error {
+			go func() {
+				client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				}}
+				resp, err := client.Get(startURL) //nolint:noctx // test helper
+				if err != nil {
+					t.Errorf("opening start URL: %v", err)
+					return
+				}
+				_ = resp.Body.Close()
+				loginURL, err := url.Parse(resp.Header.Get(headers.Location))
+				if err != nil {
+					t.Errorf("parsing redirect: %v", err)
+					return
+				}
+				request := decodeAuthnRequest(t, loginURL.Query().Get(samlRequestField))
+				form := url.Values{
+					samlResponseField: []string{encodedAssertion},
+					relayStateField:   []string{loginURL.Query().Get(relayStateField)},
+				}
+				resp, err = http.PostForm(request.AssertionConsumerServiceURL, form) //nolint:noctx // test helper
+				if err != nil {
+					t.Errorf("posting assertion: %v", err)
+					return
+				}
+				_ = resp.Body.Close()
+			}()
+			return nil
+		},
+	}
+	a := &Authenticator{
+		client: &mockHTTPClient{
+			T:                t,
+			ResponseToReturn: &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(""))},
+			ExpectedMethod:   http.MethodPost,
+		},
+		parser:  &mockCredentialParser{TokenToReturn: tokenResponse},
+		browser: flow,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := a.AuthenticateInBrowser(ctx, common.AuthInfo{
+		IdpName: "my-idp", IdpURL: "https://idp.example.com/sso", Region: "eu-de", SamlListen: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("AuthenticateInBrowser() error = %v", err)
+	}
+	if got.Token.User.Name != "jane.doe@example.com" {
+		t.Errorf("user name = %q, want the NameID of the assertion", got.Token.User.Name)
+	}
+}
+
+func TestAuthenticator_AuthenticateInBrowser_openFails(t *testing.T) {
+	a := &Authenticator{
+		browser: &browserFlow{
+			listen: func(ctx context.Context, _ string) (net.Listener, error) {
+				return (&net.ListenConfig{}).Listen(ctx, "tcp", "127.0.0.1:0")
+			},
+			openURL: func(string) error { return fmt.Errorf("no browser") },
+		},
+	}
+	_, err := a.AuthenticateInBrowser(context.Background(), common.AuthInfo{IdpURL: "https://idp.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "no browser") {
+		t.Fatalf("AuthenticateInBrowser() error = %v, want the browser error", err)
+	}
+}
+
+func TestAuthenticator_AuthenticateInBrowser_noIdpURL(t *testing.T) {
+	a := &Authenticator{browser: &browserFlow{}}
+	_, err := a.AuthenticateInBrowser(context.Background(), common.AuthInfo{IdpName: "idp", Region: "eu-de"})
+	if err == nil {
+		t.Fatal("AuthenticateInBrowser() succeeded without the IdP URL")
+	}
+}
+
+func Test_browserLoginURL(t *testing.T) {
+	authInfo := common.AuthInfo{IdpURL: "https://idp.example.com/sso?tenant=otc", IdpName: "idp", Region: "eu-de"}
+	got, err := browserLoginURL(authInfo, "http://localhost:8089/saml/acs", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loginURL, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loginURL.Host != "idp.example.com" || loginURL.Path != "/sso" || loginURL.Query().Get("tenant") != "otc" {
+		t.Errorf("browserLoginURL() = %q, want the IdP URL with its query", got)
+	}
+	if loginURL.Query().Get(relayStateField) != "state" {
+		t.Errorf("RelayState = %q, want state", loginURL.Query().Get(relayStateField))
+	}
+	request := decodeAuthnRequest(t, loginURL.Query().Get(samlRequestField))
+	if request.AssertionConsumerServiceURL != "http://localhost:8089/saml/acs" ||
+		request.Destination != authInfo.IdpURL || request.ProtocolBinding != httpPostBindingName ||
+		request.Issuer.Value != endpoints.SamlServiceProvider("eu-de") || request.ID == "" {
+		t.Errorf("AuthnRequest = %+v", request)
+	}
+}
+
+func Test_assertionConsumerURL(t *testing.T) {
+	listener, err := (&net.ListenConfig{}).Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	if got, want := assertionConsumerURL("localhost:0", listener), "http://localhost:"+port+"/saml/acs"; got != want {
+		t.Errorf("assertionConsumerURL() = %q, want %q", got, want)
+	}
+	if got, want := assertionConsumerURL(":0", listener), "http://localhost:"+port+"/saml/acs"; got != want {
+		t.Errorf("assertionConsumerURL() without host = %q, want %q", got, want)
+	}
+}
+
+func decodeAuthnRequest(t *testing.T, encoded string) authnRequest {
+	t.Helper()
+	deflated, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decoding SAMLRequest: %v", err)
+	}
+	var inflated bytes.Buffer
+	if _, err = inflated.ReadFrom(flate.NewReader(bytes.NewReader(deflated))); err != nil {
+		t.Fatalf("inflating SAMLRequest: %v", err)
+	}
+	var request authnRequest
+	if err = xml.Unmarshal(inflated.Bytes(), &request); err != nil {
+		t.Fatalf("parsing AuthnRequest %s: %v", inflated.String(), err)
+	}
+	return request
+}
+
+func Test_nameIDFromSamlResponse(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(testSamlAssertion))
+	if got := nameIDFromSamlResponse(encoded); got != "jane.doe@example.com" {
+		t.Errorf("nameIDFromSamlResponse() = %q", got)
+	}
+	if got := nameIDFromSamlResponse("%%%"); got != "" {
+		t.Errorf("nameIDFromSamlResponse() on garbage = %q, want empty", got)
+	}
+}
+
+type headerRecorder struct {
+	next  common.HTTPClient
+	key   string
+	value *string
+}
+
+func (h headerRecorder) MakeRequest(req *http.Request) (*http.Response, error) {
+	*h.value = req.Header.Get(h.key)
+	return h.next.MakeRequest(req)
+}
@@ -0,0 +1,199 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const samlStatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+// IdpError is a readable version of whatever the IdP answered with instead of
+// an assertion: a SOAP fault, a non-success SAML status or an error page.
+type IdpError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *IdpError) Error() string {
+	var b strings.Builder
+	b.WriteString("fatal: the IdP refused the login")
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (%d %s)", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Code != "" {
+		fmt.Fprintf(&b, ", code %s", e.Code)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.StatusCode == http.StatusUnauthorized {
+		b.WriteString("\n\nPlease check your username and password")
+	}
+	return b.String()
+}
+
+type soapEnvelope struct {
+	Body struct {
+		Fault *struct {
+			// SOAP 1.1
+			FaultCode   string `xml:"faultcode"`
+			FaultString string `xml:"faultstring"`
+			// SOAP 1.2
+			Code struct {
+				Value string `xml:"Value"`
+			} `xml:"Code"`
+			Reason struct {
+				Text string `xml:"Text"`
+			} `xml:"Reason"`
+		} `xml:"Fault"`
+		Response *struct {
+			Status struct {
+				StatusCode struct {
+					Value      string `xml:"Value,attr"`
+					StatusCode struct {
+						Value string `xml:"Value,attr"`
+					} `xml:"StatusCode"`
+				} `xml:"StatusCode"`
+				StatusMessage string `xml:"StatusMessage"`
+			} `xml:"Status"`
+		} `xml:"Response"`
+	} `xml:"Body"`
+}
+
+// parseSoapFault returns nil unless body is a SOAP envelope carrying a fault
+// or a SAML response with a non-success status.
+func parseSoapFault(body []byte) *IdpError {
+	var envelope soapEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+	if fault := envelope.Body.Fault; fault != nil {
+		idpErr := &IdpError{Code: fault.FaultCode, Message: strings.TrimSpace(fault.FaultString)}
+		if idpErr.Code == "" {
+			idpErr.Code = fault.Code.Value
+		}
+		if idpErr.Message == "" {
+			idpErr.Message = strings.TrimSpace(fault.Reason.Text)
+		}
+		return idpErr
+	}
+	if response := envelope.Body.Response; response != nil {
+		status := response.Status.StatusCode
+		if status.Value != "" && status.Value != samlStatusSuccess {
+			code := status.Value
+			if status.StatusCode.Value != "" {
+				code = status.StatusCode.Value
+			}
+			return &IdpError{Code: code, Message: strings.TrimSpace(response.Status.StatusMessage)}
+		}
+	}
+	return nil
+}
+
+func newIdpError(statusCode int, body []byte) *IdpError {
+	if fault := parseSoapFault(body); fault != nil {
+		fault.StatusCode = statusCode
+		return fault
+	}
+	idpErr := &IdpError{StatusCode: statusCode}
+	if message := htmlErrorText(body); message != "" {
+		idpErr.Message = message
+	} else if title := htmlTitle(body); title != "" {
+		idpErr.Message = title
+	}
+	return idpErr
+}
+
+func htmlTitle(body []byte) string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	title := findNode(doc, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "title"
+	})
+	if title == nil {
+		return ""
+	}
+	return textContent(title)
+}
+
+// htmlErrorText looks for the elements the common IdPs render their login
+// errors into: ADFS (#errorText), Keycloak (#input-error, .kc-feedback-text,
+// #kc-error-message) and Shibboleth (.output--failure).
+func htmlErrorText(body []byte) string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	return pageErrorText(doc)
+}
+
+func pageErrorText(doc *html.Node) string {
+	errorIDs := map[string]bool{"errorText": true, "input-error": true, "kc-error-message": true}
+	errorClasses := []string{"kc-feedback-text", "output--failure", "alert-error"}
+	node := findNode(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		if errorIDs[attr(n, "id")] {
+			return textContent(n) != ""
+		}
+		classes := strings.Fields(attr(n, "class"))
+		for _, class := range classes {
+			for _, errorClass := range errorClasses {
+				if class == errorClass {
+					return textContent(n) != ""
+				}
+			}
+		}
+		return false
+	})
+	if node == nil {
+		return ""
+	}
+	return textContent(node)
+}
+
+func findNode(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findNode(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+			b.WriteString(" ")
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
 
 	"otc-auth/common"
@@ -16,14 +17,19 @@ import (
 )
 
 type Authenticator struct {
-	client common.HTTPClient
-	parser CredentialParser
+	client  common.HTTPClient
+	parser  CredentialParser
+	browser *browserFlow
+	adapter IdpAdapter
+	// promptSecondFactor is nil when there is no terminal to ask on.
+	promptSecondFactor func(prompt string) (string, error)
 }
 
 func newAuthenticator(client common.HTTPClient, parser CredentialParser) *Authenticator {
 	return &Authenticator{
-		client: client,
-		parser: parser,
+		client:  client,
+		parser:  parser,
+		browser: newBrowserFlow(),
 	}
 }
 
@@ -33,6 +39,18 @@ func AuthenticateAndGetUnscopedToken(ctx context.Context,
 	client := common.NewHTTPClient(authInfo.SkipTLS)
 	parser := NewDefaultCredentialParser()
 	service := newAuthenticator(client, parser)
+	if authInfo.SamlBrowser {
+		return service.AuthenticateInBrowser(ctx, authInfo)
+	}
+
+	adapter, err := NewIdpAdapter(IdpType(authInfo.SamlIdpType))
+	if err != nil {
+		return nil, err
+	}
+	service.adapter = adapter
+	if common.IsInteractive() {
+		service.promptSecondFactor = common.PromptSecret
+	}
 	return service.Authenticate(ctx, authInfo)
 }
 
@@ -43,19 +61,17 @@ func (a *Authenticator) Authenticate(ctx context.Context, authInfo common.AuthIn
 	}
 	defer spInitiatedRequest.Body.Close()
 
-	bodyBytes, err := a.authenticateWithIdp(ctx, authInfo, spInitiatedRequest)
+	idpResult, err := a.authenticateWithIdp(ctx, authInfo, spInitiatedRequest)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't auth with idp: %w", err)
 	}
 
-	assertionResult := common.SamlAssertionResponse{}
-
-	err = xml.Unmarshal(bodyBytes, &assertionResult)
-	if err != nil {
-		return nil, fmt.Errorf("fatal: error deserializing xml.\ntrace: %w", err)
+	var response *http.Response
+	if idpResult.SamlResponse != "" {
+		response, err = a.exchangeSamlResponse(ctx, authInfo, idpResult.SamlResponse)
+	} else {
+		response, err = a.relayEnvelope(ctx, idpResult.Envelope)
 	}
-
-	response, err := a.validateAuthenticationWithServiceProvider(ctx, assertionResult, bodyBytes)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't validate auth with service provider: %w", err)
 	}
@@ -85,22 +101,32 @@ func (a *Authenticator) getServiceProviderInitiatedRequest(ctx context.Context,
 
 func (a *Authenticator) authenticateWithIdp(ctx context.Context, params common.AuthInfo,
 	samlResponse *http.Response,
-) ([]byte, error) {
-	request, err := common.NewRequest(ctx, http.MethodPost, params.IdpURL, samlResponse.Body)
+) (*IdpResult, error) {
+	ecpRequest, err := io.ReadAll(samlResponse.Body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("fatal: error reading sp request\ntrace: %w", err)
 	}
-	defer request.Body.Close()
 
-	request.Header.Add(headers.ContentType, headervalues.TextXML)
-	request.SetBasicAuth(params.Username, params.Password)
+	adapter := a.adapter
+	if adapter == nil {
+		adapter = &ecpBasicAuthAdapter{}
+	}
+	return adapter.Authenticate(ctx, IdpSession{
+		Client:             a.client,
+		AuthInfo:           params,
+		ECPRequest:         ecpRequest,
+		PromptSecondFactor: a.promptSecondFactor,
+	})
+}
 
-	response, err := a.client.MakeRequest(request)
-	if err != nil {
-		return nil, err
+// relayEnvelope hands the IdP's ECP envelope to the assertion consumer service
+// named in its header.
+func (a *Authenticator) relayEnvelope(ctx context.Context, envelope []byte) (*http.Response, error) {
+	assertionResult := common.SamlAssertionResponse{}
+	if err := xml.Unmarshal(envelope, &assertionResult); err != nil {
+		return nil, describeUnexpectedIdpResponse(envelope, err)
 	}
-	defer response.Body.Close()
-	return common.GetBodyBytesFromResponse(response)
+	return a.validateAuthenticationWithServiceProvider(ctx, assertionResult, envelope)
 }
 
 func (a *Authenticator) validateAuthenticationWithServiceProvider(ctx context.Context,
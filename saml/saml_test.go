@@ -312,8 +312,12 @@ func TestAuthenticator_authenticateWithIdp(t *testing.T) {
 				t.Errorf("authenticateWithIdp() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("authenticateWithIdp() got = %s, want %s", string(got), string(tt.want))
+			var gotEnvelope []byte
+			if got != nil {
+				gotEnvelope = got.Envelope
+			}
+			if !reflect.DeepEqual(gotEnvelope, tt.want) {
+				t.Errorf("authenticateWithIdp() got = %s, want %s", string(gotEnvelope), string(tt.want))
 			}
 		})
 	}